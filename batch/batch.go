@@ -0,0 +1,154 @@
+// Package batch runs an operation, or a pipeline of them, on every matrix of
+// a directory or an archive, a few at a time, and reports the result of each
+// in a manifest.
+package batch
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"league/main/controller"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// Pipeline runs its operations in order, each on the output of the previous
+// one.
+type Pipeline []controller.Operation
+
+// ParsePipeline returns the pipeline of the comma separated operation names,
+// such as "inverse,flatten". Only the operations taking a single matrix can
+// be part of a pipeline.
+func ParsePipeline(spec string, operations []controller.Operation) (Pipeline, error) {
+	var pipeline Pipeline
+	for _, name := range strings.Split(spec, ",") {
+		name = strings.TrimSpace(name)
+		i := slices.IndexFunc(operations, func(op controller.Operation) bool { return op.Name == name })
+		if i < 0 {
+			var names []string
+			for _, op := range operations {
+				if len(op.Inputs) == 1 {
+					names = append(names, op.Name)
+				}
+			}
+			return nil, fmt.Errorf("unknown operation %q, expected one of %s", name, strings.Join(names, ", "))
+		}
+		if op := operations[i]; len(op.Inputs) != 1 {
+			return nil, fmt.Errorf("operation %s takes %d matrices and cannot run in a pipeline", name, len(op.Inputs))
+		}
+		pipeline = append(pipeline, operations[i])
+	}
+	return pipeline, nil
+}
+
+// Names returns the operations of the pipeline.
+func (p Pipeline) Names() []string {
+	names := make([]string, len(p))
+	for i, op := range p {
+		names[i] = op.Name
+	}
+	return names
+}
+
+// run passes the matrix through every step with the same params and returns
+// the output of the last one, or the step that failed.
+func (p Pipeline) run(ctx context.Context, params url.Values, name string, r io.Reader) (string, string, error) {
+	var output bytes.Buffer
+	for _, op := range p {
+		output = bytes.Buffer{}
+		upload := controller.Upload{Field: op.Inputs[0], Name: name, Content: r}
+		if err := controller.Run(ctx, op, params, []controller.Upload{upload}, &output); err != nil {
+			return "", op.Name, err
+		}
+		r = bytes.NewReader(output.Bytes())
+	}
+	return output.String(), "", nil
+}
+
+// Input is a matrix of a batch.
+type Input struct {
+	// Name is the path of the matrix in its directory or archive.
+	Name string
+	Open func() (io.ReadCloser, error)
+}
+
+// Result is the outcome of the pipeline on one matrix: its output, or the
+// error of the step that failed.
+type Result struct {
+	Name   string `json:"name"`
+	Output string `json:"output,omitempty"`
+	Step   string `json:"step,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// Manifest reports a batch, with the result of every matrix in the order of
+// the inputs.
+type Manifest struct {
+	Pipeline  []string `json:"pipeline"`
+	Succeeded int      `json:"succeeded"`
+	Failed    int      `json:"failed"`
+	Results   []Result `json:"results"`
+}
+
+// Run runs the pipeline on every input, at most workers at once. A cancelled
+// context fails the inputs that did not finish; the manifest still reports
+// the others.
+func Run(ctx context.Context, pipeline Pipeline, params url.Values, inputs []Input, workers int) *Manifest {
+	manifest := &Manifest{Pipeline: pipeline.Names(), Results: make([]Result, len(inputs))}
+
+	var next atomic.Int64
+	var wg sync.WaitGroup
+	for range max(min(workers, len(inputs)), 1) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := int(next.Add(1) - 1); i < len(inputs); i = int(next.Add(1) - 1) {
+				manifest.Results[i] = runInput(ctx, pipeline, params, inputs[i])
+			}
+		}()
+	}
+	wg.Wait()
+
+	for _, result := range manifest.Results {
+		if result.Error == "" {
+			manifest.Succeeded++
+		} else {
+			manifest.Failed++
+		}
+	}
+	return manifest
+}
+
+func runInput(ctx context.Context, pipeline Pipeline, params url.Values, input Input) Result {
+	result := Result{Name: input.Name}
+	if err := ctx.Err(); err != nil {
+		result.Error = contextError(err)
+		return result
+	}
+
+	file, err := input.Open()
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	defer file.Close()
+
+	result.Output, result.Step, err = pipeline.run(ctx, params, input.Name, file)
+	if err != nil {
+		result.Error = err.Error()
+	}
+	return result
+}
+
+// contextError words the error of a done context like the operations do.
+func contextError(err error) string {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return "operation timed out"
+	}
+	return "request cancelled"
+}
@@ -0,0 +1,247 @@
+package batch
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"io"
+	"league/main/controller"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+var operations = controller.Operations()
+
+// files are the matrices of the test directory and archives, by path.
+var files = map[string]string{
+	"b.csv":          "1,2\n3,4\n",
+	"a.csv":          "1,2\n3\n",
+	"nested/c.CSV":   "5\n",
+	"notes.txt":      "not a matrix",
+	"nested/._c.csv": "macOS metadata",
+}
+
+func TestParsePipeline(t *testing.T) {
+	pipeline, err := ParsePipeline("invert, flatten", operations)
+	if err != nil || strings.Join(pipeline.Names(), ",") != "invert,flatten" {
+		t.Errorf("ParsePipeline() = %v, %v", pipeline.Names(), err)
+	}
+	if _, err := ParsePipeline("invert,power", operations); err == nil || !strings.Contains(err.Error(), `unknown operation "power", expected one of echo, invert`) {
+		t.Errorf("ParsePipeline() error = %v", err)
+	}
+	if _, err := ParsePipeline("matmul", operations); err == nil || err.Error() != "operation matmul takes 2 matrices and cannot run in a pipeline" {
+		t.Errorf("ParsePipeline() error = %v", err)
+	}
+	if _, err := ParsePipeline("", operations); err == nil {
+		t.Error("ParsePipeline() of no operation succeeded")
+	}
+}
+
+func TestRunDir(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range files {
+		os.MkdirAll(filepath.Join(dir, filepath.Dir(name)), 0o755)
+		os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644)
+	}
+
+	inputs, err := Dir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pipeline, _ := ParsePipeline("invert,flatten", operations)
+	manifest := Run(context.Background(), pipeline, url.Values{"order": {"column"}}, inputs, 2)
+
+	expected := &Manifest{
+		Pipeline:  []string{"invert", "flatten"},
+		Succeeded: 2,
+		Failed:    1,
+		Results: []Result{
+			{Name: "a.csv", Step: "invert", Error: "record on line 2: wrong number of fields"},
+			{Name: "b.csv", Output: "1,2,3,4\n"},
+			{Name: "nested/c.CSV", Output: "5\n"},
+		},
+	}
+	if got, want := mustJSON(t, manifest), mustJSON(t, expected); got != want {
+		t.Errorf("Run() = %s, want %s", got, want)
+	}
+}
+
+func TestRunBoundsWorkers(t *testing.T) {
+	var running, most atomic.Int64
+	slow := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := running.Add(1)
+		defer running.Add(-1)
+		for m := most.Load(); n > m && !most.CompareAndSwap(m, n); m = most.Load() {
+		}
+		time.Sleep(5 * time.Millisecond)
+		controller.SumHandler(w, r)
+	})
+
+	inputs := make([]Input, 20)
+	for i := range inputs {
+		inputs[i] = Input{Name: "m.csv", Open: func() (io.ReadCloser, error) { return io.NopCloser(strings.NewReader("1\n")), nil }}
+	}
+	manifest := Run(context.Background(), Pipeline{{Name: "slow", Inputs: []string{"file"}, Output: controller.OutputScalar, Handler: slow}}, nil, inputs, 3)
+	if manifest.Succeeded != 20 || most.Load() > 3 {
+		t.Errorf("%d succeeded with up to %d at once, want 20 with up to 3", manifest.Succeeded, most.Load())
+	}
+}
+
+func TestRunCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	inputs := []Input{{Name: "m.csv", Open: func() (io.ReadCloser, error) { return io.NopCloser(strings.NewReader("1\n")), nil }}}
+	pipeline, _ := ParsePipeline("sum", operations)
+	if manifest := Run(ctx, pipeline, nil, inputs, 1); manifest.Failed != 1 || manifest.Results[0].Error != "request cancelled" {
+		t.Errorf("Run() = %+v", manifest)
+	}
+}
+
+func TestOpenArchive(t *testing.T) {
+	tests := []struct {
+		name          string
+		archive       []byte
+		maxBytes      int64
+		expectedNames string
+		expectedError string
+	}{
+		{name: "Zip", archive: zipArchive(t), expectedNames: "a.csv,b.csv,nested/c.CSV"},
+		{name: "Tar", archive: tarArchive(t), expectedNames: "a.csv,b.csv,nested/c.CSV"},
+		{name: "Gzipped Tar", archive: gzipped(t, tarArchive(t)), expectedNames: "a.csv,b.csv,nested/c.CSV"},
+		{name: "Zip Too Large", archive: zipArchive(t), maxBytes: 10, expectedError: "archive expands to more than 10 bytes"},
+		{name: "Tar Too Large", archive: gzipped(t, tarArchive(t)), maxBytes: 10, expectedError: "archive expands to more than 10 bytes"},
+		{name: "Not An Archive", archive: []byte("1,2\n3,4\n"), expectedError: ErrUnknownArchive.Error()},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inputs, err := OpenArchive(bytes.NewReader(tt.archive), int64(len(tt.archive)), tt.maxBytes)
+			if tt.expectedError != "" {
+				if err == nil || err.Error() != tt.expectedError {
+					t.Errorf("OpenArchive() error = %v, want %s", err, tt.expectedError)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var names []string
+			for _, input := range inputs {
+				names = append(names, input.Name)
+				file, _ := input.Open()
+				content, _ := io.ReadAll(file)
+				file.Close()
+				if string(content) != files[input.Name] {
+					t.Errorf("%s = %q, want %q", input.Name, content, files[input.Name])
+				}
+			}
+			if strings.Join(names, ",") != tt.expectedNames {
+				t.Errorf("OpenArchive() = %v, want %s", names, tt.expectedNames)
+			}
+		})
+	}
+}
+
+func TestHandler(t *testing.T) {
+	handler := NewHandler(Options{Workers: 2}, operations)
+
+	tests := []struct {
+		name           string
+		method         string
+		url            string
+		archive        []byte
+		expectedStatus int
+		expectedBody   string
+	}{
+		{name: "Batch", method: "POST", url: "/batch?operation=inverse&domain=gf:7", archive: zipArchive(t), expectedStatus: http.StatusOK, expectedBody: `"succeeded":2,"failed":1`},
+		{name: "Unknown Operation", method: "POST", url: "/batch?operation=power", archive: zipArchive(t), expectedStatus: http.StatusBadRequest, expectedBody: `error unknown operation "power"`},
+		{name: "Missing Archive", method: "POST", url: "/batch?operation=sum", expectedStatus: http.StatusBadRequest, expectedBody: "error http: no such file"},
+		{name: "Not An Archive", method: "POST", url: "/batch?operation=sum", archive: []byte("1\n"), expectedStatus: http.StatusBadRequest, expectedBody: "error " + ErrUnknownArchive.Error()},
+		{name: "Wrong Method", method: "GET", url: "/batch?operation=sum", expectedStatus: http.StatusMethodNotAllowed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			form := new(bytes.Buffer)
+			writer := multipart.NewWriter(form)
+			if tt.archive != nil {
+				part, _ := writer.CreateFormFile("file", "matrices")
+				part.Write(tt.archive)
+			}
+			writer.Close()
+			req := httptest.NewRequest(tt.method, tt.url, form)
+			req.Header.Set("Content-Type", writer.FormDataContentType())
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+			if rr.Code != tt.expectedStatus {
+				t.Errorf("status = %d, want %d", rr.Code, tt.expectedStatus)
+			}
+			if !strings.Contains(rr.Body.String(), tt.expectedBody) {
+				t.Errorf("body = %q, want %q", rr.Body.String(), tt.expectedBody)
+			}
+		})
+	}
+}
+
+func mustJSON(t *testing.T, v any) string {
+	t.Helper()
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func zipArchive(t *testing.T) []byte {
+	t.Helper()
+	archive := new(bytes.Buffer)
+	writer := zip.NewWriter(archive)
+	writer.Create("nested/")
+	for name, content := range files {
+		file, _ := writer.Create(name)
+		file.Write([]byte(content))
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return archive.Bytes()
+}
+
+func tarArchive(t *testing.T) []byte {
+	t.Helper()
+	archive := new(bytes.Buffer)
+	writer := tar.NewWriter(archive)
+	writer.WriteHeader(&tar.Header{Name: "nested/", Typeflag: tar.TypeDir, Mode: 0o755})
+	for name, content := range files {
+		writer.WriteHeader(&tar.Header{Name: name, Typeflag: tar.TypeReg, Mode: 0o644, Size: int64(len(content))})
+		writer.Write([]byte(content))
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return archive.Bytes()
+}
+
+func gzipped(t *testing.T, data []byte) []byte {
+	t.Helper()
+	compressed := new(bytes.Buffer)
+	writer := gzip.NewWriter(compressed)
+	writer.Write(data)
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return compressed.Bytes()
+}
@@ -0,0 +1,70 @@
+package batch
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"league/main/controller"
+	"net/http"
+)
+
+// Options configure a Handler.
+type Options struct {
+	// Workers bounds the matrices of a batch processed at once.
+	Workers int
+	// MaxBytes bounds the size of the matrices of an archive once extracted,
+	// 0 for no limit.
+	MaxBytes int64
+}
+
+// Handler runs batches over HTTP: POST /batch?operation=inverse,flatten with
+// a zip, tar or tar.gz archive uploaded as file answers the manifest of the
+// pipeline on every CSV of the archive. The other parameters are given to
+// every operation.
+type Handler struct {
+	opts       Options
+	operations []controller.Operation
+}
+
+// NewHandler returns a Handler running pipelines of the given operations.
+func NewHandler(opts Options, operations []controller.Operation) *Handler {
+	return &Handler{opts: opts, operations: operations}
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "error method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	params := r.URL.Query()
+	pipeline, err := ParsePipeline(params.Get("operation"), h.operations)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("error %s", err.Error()), http.StatusBadRequest)
+		return
+	}
+	params.Del("operation")
+
+	file, header, err := r.FormFile("file")
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		http.Error(w, fmt.Sprintf("error request body larger than %d bytes", tooLarge.Limit), http.StatusRequestEntityTooLarge)
+		return
+	}
+	if err != nil {
+		http.Error(w, fmt.Sprintf("error %s", err.Error()), http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+
+	inputs, err := OpenArchive(file, header.Size, h.opts.MaxBytes)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("error %s", err.Error()), http.StatusBadRequest)
+		return
+	}
+
+	manifest := Run(r.Context(), pipeline, params, inputs, h.opts.Workers)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(manifest)
+}
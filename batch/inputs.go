@@ -0,0 +1,145 @@
+package batch
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// ErrUnknownArchive is returned for an archive that is neither a zip nor a
+// tar, gzipped or not.
+var ErrUnknownArchive = errors.New("expected a zip, tar or tar.gz archive")
+
+// isMatrix reports whether the file at name is a matrix of a batch: a .csv
+// file, not hidden, such as the ._ files macOS adds to archives.
+func isMatrix(name string) bool {
+	base := path.Base(name)
+	return strings.EqualFold(path.Ext(base), ".csv") && !strings.HasPrefix(base, ".")
+}
+
+func sortInputs(inputs []Input) {
+	slices.SortFunc(inputs, func(a, b Input) int { return strings.Compare(a.Name, b.Name) })
+}
+
+// Dir returns the matrices of dir and its subdirectories, sorted by path.
+func Dir(dir string) ([]Input, error) {
+	var inputs []Input
+	err := filepath.WalkDir(dir, func(file string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		name, _ := filepath.Rel(dir, file)
+		name = filepath.ToSlash(name)
+		if !entry.Type().IsRegular() || !isMatrix(name) {
+			return nil
+		}
+		inputs = append(inputs, Input{Name: name, Open: func() (io.ReadCloser, error) { return os.Open(file) }})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sortInputs(inputs)
+	return inputs, nil
+}
+
+// OpenArchive returns the matrices of a zip, tar or gzipped tar archive,
+// recognized by its content, sorted by path. The matrices may not expand to
+// more than maxBytes together, 0 for no limit. Those of a tar are read in
+// memory.
+func OpenArchive(r io.ReaderAt, size, maxBytes int64) ([]Input, error) {
+	head := make([]byte, 512)
+	n, err := r.ReadAt(head, 0)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	head = head[:n]
+
+	var inputs []Input
+	switch {
+	case bytes.HasPrefix(head, []byte("PK\x03\x04")), bytes.HasPrefix(head, []byte("PK\x05\x06")):
+		inputs, err = zipInputs(r, size, maxBytes)
+	case bytes.HasPrefix(head, []byte{0x1f, 0x8b}):
+		var archive *gzip.Reader
+		archive, err = gzip.NewReader(io.NewSectionReader(r, 0, size))
+		if err == nil {
+			inputs, err = tarInputs(archive, maxBytes)
+		}
+	case len(head) >= 262 && string(head[257:262]) == "ustar":
+		inputs, err = tarInputs(io.NewSectionReader(r, 0, size), maxBytes)
+	default:
+		err = ErrUnknownArchive
+	}
+	if err != nil {
+		return nil, err
+	}
+	sortInputs(inputs)
+	return inputs, nil
+}
+
+func zipInputs(r io.ReaderAt, size, maxBytes int64) ([]Input, error) {
+	archive, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, err
+	}
+
+	var inputs []Input
+	var total uint64
+	for _, file := range archive.File {
+		if !file.Mode().IsRegular() || !isMatrix(file.Name) {
+			continue
+		}
+		// zip checks the declared size while reading.
+		total += file.UncompressedSize64
+		if maxBytes > 0 && total > uint64(maxBytes) {
+			return nil, archiveTooLarge(maxBytes)
+		}
+		inputs = append(inputs, Input{Name: file.Name, Open: file.Open})
+	}
+	return inputs, nil
+}
+
+func tarInputs(r io.Reader, maxBytes int64) ([]Input, error) {
+	archive := tar.NewReader(r)
+
+	var inputs []Input
+	var total int64
+	for {
+		header, err := archive.Next()
+		if err == io.EOF {
+			return inputs, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if header.Typeflag != tar.TypeReg || !isMatrix(header.Name) {
+			continue
+		}
+
+		total += header.Size
+		if maxBytes > 0 && total > maxBytes {
+			return nil, archiveTooLarge(maxBytes)
+		}
+		content, err := io.ReadAll(archive)
+		if err != nil {
+			return nil, err
+		}
+		inputs = append(inputs, Input{Name: header.Name, Open: func() (io.ReadCloser, error) {
+			return io.NopCloser(bytes.NewReader(content)), nil
+		}})
+	}
+}
+
+func archiveTooLarge(maxBytes int64) error {
+	return fmt.Errorf("archive expands to more than %d bytes", maxBytes)
+}
@@ -0,0 +1,90 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"league/main/batch"
+	"league/main/controller"
+	"net/url"
+	"os"
+	"runtime"
+)
+
+// runBatch runs a pipeline on every matrix of a directory or archive and
+// prints the manifest. The status is exitFailure if any matrix failed.
+func runBatch(ctx context.Context, args []string, stdout, stderr io.Writer, operations []controller.Operation) int {
+	params := url.Values{}
+	flags := flag.NewFlagSet("batch", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "usage: league batch -operation <command>[,<command>...] [flags] <dir or archive>")
+		flags.PrintDefaults()
+	}
+	spec := flags.String("operation", "", "operation, or comma separated pipeline of operations, run on every matrix")
+	workers := flags.Int("workers", runtime.GOMAXPROCS(0), "matrices processed at once")
+	parameterFlags(flags, params, pipelineParams(operations))
+	if err := flags.Parse(args); errors.Is(err, flag.ErrHelp) {
+		return exitSuccess
+	} else if err != nil {
+		return exitUsage
+	}
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return exitUsage
+	}
+
+	pipeline, err := batch.ParsePipeline(*spec, operations)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return exitUsage
+	}
+	inputs, err := batchInputs(flags.Arg(0))
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return exitUsage
+	}
+
+	manifest := batch.Run(ctx, pipeline, params, inputs, *workers)
+	encoder := json.NewEncoder(stdout)
+	encoder.SetIndent("", "  ")
+	encoder.Encode(manifest)
+	if manifest.Failed > 0 {
+		return exitFailure
+	}
+	return exitSuccess
+}
+
+// pipelineParams returns the parameters of the operations that can run in
+// a pipeline, those taking a single matrix.
+func pipelineParams(operations []controller.Operation) []controller.Param {
+	var params []controller.Param
+	for _, op := range operations {
+		if len(op.Inputs) == 1 {
+			params = append(params, op.Params...)
+		}
+	}
+	return params
+}
+
+// batchInputs returns the matrices of a directory or of an archive file.
+func batchInputs(path string) ([]batch.Input, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	if info.IsDir() {
+		file.Close()
+		return batch.Dir(path)
+	}
+	// The matrices of a zip are read from the file while the batch runs.
+	return batch.OpenArchive(file, info.Size(), 0)
+}
@@ -0,0 +1,147 @@
+// Package cli runs the operations of the server from the command line, on
+// CSV matrices read from files or stdin, with the parameters of the HTTP API
+// as flags:
+//
+//	league sum matrix.csv
+//	league inverse -domain gf:7 < matrix.csv
+//	league matmul a.csv b.csv
+package cli
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"league/main/controller"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// Exit statuses of a command.
+const (
+	exitSuccess = 0
+	exitFailure = 1 // the operation failed
+	exitUsage   = 2 // invalid command, flags or files
+)
+
+// Run runs the operation named by the first argument and returns the exit
+// status. Every input of the operation is read from a file argument, in the
+// order of its inputs, at most one of them from stdin when its argument is -
+// or missing. The parameters of the operation are its flags.
+func Run(ctx context.Context, args []string, stdin io.Reader, stdout, stderr io.Writer, operations []controller.Operation) int {
+	if len(args) == 0 || args[0] == "help" {
+		usage(stderr, operations)
+		return exitUsage
+	}
+	name := args[0]
+	switch name {
+	case "batch":
+		return runBatch(ctx, args[1:], stdout, stderr, operations)
+	case "watch":
+		return runWatch(ctx, args[1:], stderr, operations)
+	}
+	i := slices.IndexFunc(operations, func(op controller.Operation) bool { return op.Name == name })
+	if i < 0 {
+		fmt.Fprintf(stderr, "unknown command %q\n", name)
+		usage(stderr, operations)
+		return exitUsage
+	}
+	op := operations[i]
+	fields := op.Inputs
+
+	params := url.Values{}
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		fmt.Fprintf(stderr, "usage: league %s [flags] [%s.csv ...]\nReads stdin for a missing or - file.\n", name, strings.Join(fields, ".csv "))
+		flags.PrintDefaults()
+	}
+	parameterFlags(flags, params, op.Params)
+	if err := flags.Parse(args[1:]); errors.Is(err, flag.ErrHelp) {
+		return exitSuccess
+	} else if err != nil {
+		return exitUsage
+	}
+
+	files := flags.Args()
+	if len(files) > len(fields) {
+		fmt.Fprintf(stderr, "%s takes at most %d files\n", name, len(fields))
+		return exitUsage
+	}
+	fromStdin := len(fields) - len(files)
+	for _, file := range files {
+		if file == "-" {
+			fromStdin++
+		}
+	}
+	if fromStdin > 1 {
+		fmt.Fprintf(stderr, "%s reads only one of its %d files from stdin\n", name, len(fields))
+		return exitUsage
+	}
+
+	inputs := make([]controller.Upload, len(fields))
+	for i, field := range fields {
+		inputs[i] = controller.Upload{Field: field, Name: "stdin.csv", Content: stdin}
+		if i >= len(files) || files[i] == "-" {
+			continue
+		}
+		file, err := os.Open(files[i])
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			return exitUsage
+		}
+		defer file.Close()
+		inputs[i].Name, inputs[i].Content = filepath.Base(files[i]), file
+	}
+
+	out := bufio.NewWriter(stdout)
+	err := controller.Run(ctx, op, params, inputs, out)
+	out.Flush()
+	if err != nil {
+		fmt.Fprintf(stderr, "%s: %s\n", name, err.Error())
+		return exitFailure
+	}
+	return exitSuccess
+}
+
+// parameterFlags defines the flags setting the parameters in params, once
+// for a parameter shared by several operations.
+func parameterFlags(flags *flag.FlagSet, params url.Values, parameters []controller.Param) {
+	for _, p := range parameters {
+		if flags.Lookup(p.Name) != nil {
+			continue
+		}
+		usage := p.Description
+		if len(p.Values) > 0 && !p.Boolean() {
+			usage += ": " + strings.Join(p.Values, " or ")
+		}
+		set := func(value string) error {
+			if len(p.Values) > 0 && !slices.Contains(p.Values, value) {
+				return fmt.Errorf("expected one of %s", strings.Join(p.Values, ", "))
+			}
+			params.Set(p.Name, value)
+			return nil
+		}
+		if p.Boolean() {
+			flags.BoolFunc(p.Name, usage, set)
+		} else {
+			flags.Func(p.Name, usage, set)
+		}
+	}
+}
+
+func usage(w io.Writer, operations []controller.Operation) {
+	fmt.Fprintln(w, "usage: league serve [flags]")
+	fmt.Fprintln(w, "       league <command> [flags] [file.csv ...]")
+	fmt.Fprintln(w, "       league batch -operation <command>[,<command>...] [flags] <dir or archive>")
+	fmt.Fprintln(w, "       league watch -operation <command>[,<command>...] -inbox <dir> -outbox <dir> -errors <dir> [flags]")
+	fmt.Fprintln(w, "commands:")
+	for _, op := range operations {
+		fmt.Fprintf(w, "  %-12s %s\n", op.Name, op.Summary)
+	}
+}
@@ -0,0 +1,94 @@
+package cli
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"league/main/controller"
+)
+
+var operations = controller.Operations()
+
+func TestRun(t *testing.T) {
+	dir := t.TempDir()
+	square := filepath.Join(dir, "square.csv")
+	os.WriteFile(square, []byte("1,2\n3,4\n"), 0o644)
+
+	tests := []struct {
+		name           string
+		args           []string
+		stdin          string
+		expectedStatus int
+		expectedOutput string
+		expectedError  string
+	}{
+		{name: "File", args: []string{"sum", square}, expectedStatus: exitSuccess, expectedOutput: "10\n"},
+		{name: "Stdin", args: []string{"sum"}, stdin: "1,2\n", expectedStatus: exitSuccess, expectedOutput: "3\n"},
+		{name: "Dash", args: []string{"sum", "-"}, stdin: "1,2\n", expectedStatus: exitSuccess, expectedOutput: "3\n"},
+		{name: "Flags", args: []string{"flatten", "-order", "column", square}, expectedStatus: exitSuccess, expectedOutput: "1,3,2,4\n"},
+		{name: "Boolean Flag", args: []string{"sum", "-stream", square}, expectedStatus: exitSuccess, expectedOutput: "10\n"},
+		{name: "Two Files", args: []string{"matmul", square, "-"}, stdin: "1\n1\n", expectedStatus: exitSuccess, expectedOutput: "3\n7\n"},
+		{name: "Output Like An Error", args: []string{"echo"}, stdin: "error x,1\n2,3\n", expectedStatus: exitSuccess, expectedOutput: "error x,1\n2,3\n"},
+		{name: "Operation Error", args: []string{"sum"}, stdin: "1,2\n3\n", expectedStatus: exitFailure, expectedError: "sum: record on line 2: wrong number of fields\n"},
+		{name: "No Command", args: nil, expectedStatus: exitUsage, expectedError: "usage: league serve"},
+		{name: "Unknown Command", args: []string{"power"}, expectedStatus: exitUsage, expectedError: `unknown command "power"`},
+		{name: "Invalid Value", args: []string{"flatten", "-order", "diagonal", square}, expectedStatus: exitUsage, expectedError: `invalid value "diagonal" for flag -order: expected one of row, column`},
+		{name: "Unknown Flag", args: []string{"sum", "-power", "2"}, expectedStatus: exitUsage, expectedError: "flag provided but not defined"},
+		{name: "Too Many Files", args: []string{"sum", square, square}, expectedStatus: exitUsage, expectedError: "at most 1 files"},
+		{name: "Stdin Twice", args: []string{"matmul", "-"}, expectedStatus: exitUsage, expectedError: "only one of its 2 files from stdin"},
+		{name: "Missing File", args: []string{"sum", filepath.Join(dir, "nope.csv")}, expectedStatus: exitUsage, expectedError: "no such file"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr strings.Builder
+			status := Run(context.Background(), tt.args, strings.NewReader(tt.stdin), &stdout, &stderr, operations)
+			if status != tt.expectedStatus {
+				t.Errorf("status = %d, want %d (stderr %q)", status, tt.expectedStatus, stderr.String())
+			}
+			if stdout.String() != tt.expectedOutput {
+				t.Errorf("stdout = %q, want %q", stdout.String(), tt.expectedOutput)
+			}
+			if !strings.Contains(stderr.String(), tt.expectedError) {
+				t.Errorf("stderr = %q, want %q", stderr.String(), tt.expectedError)
+			}
+		})
+	}
+}
+
+func TestRunBatch(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "a.csv"), []byte("1,2\n3,4\n"), 0o644)
+	os.WriteFile(filepath.Join(dir, "b.csv"), []byte("1,2\n3\n"), 0o644)
+
+	tests := []struct {
+		name           string
+		args           []string
+		expectedStatus int
+		expectedOutput string
+		expectedError  string
+	}{
+		{name: "Pipeline", args: []string{"batch", "-operation", "flatten,sum", "-workers", "1", dir}, expectedStatus: exitFailure, expectedOutput: `"output": "10\n"`},
+		{name: "Single Matrix Operations Only", args: []string{"batch", "-operation", "matmul", dir}, expectedStatus: exitUsage, expectedError: "matmul takes 2 matrices"},
+		{name: "Missing Dir", args: []string{"batch", "-operation", "sum"}, expectedStatus: exitUsage, expectedError: "usage: league batch"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr strings.Builder
+			status := Run(context.Background(), tt.args, strings.NewReader(""), &stdout, &stderr, operations)
+			if status != tt.expectedStatus {
+				t.Errorf("status = %d, want %d (stderr %q)", status, tt.expectedStatus, stderr.String())
+			}
+			if !strings.Contains(stdout.String(), tt.expectedOutput) {
+				t.Errorf("stdout = %q, want %q", stdout.String(), tt.expectedOutput)
+			}
+			if !strings.Contains(stderr.String(), tt.expectedError) {
+				t.Errorf("stderr = %q, want %q", stderr.String(), tt.expectedError)
+			}
+		})
+	}
+}
@@ -0,0 +1,61 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"league/main/batch"
+	"league/main/controller"
+	"league/main/hotfolder"
+	"net/url"
+	"runtime"
+	"time"
+)
+
+// runWatch processes the files dropped in an inbox until the context is
+// done.
+func runWatch(ctx context.Context, args []string, stderr io.Writer, operations []controller.Operation) int {
+	params := url.Values{}
+	opts := hotfolder.Options{}
+	flags := flag.NewFlagSet("watch", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "usage: league watch -operation <command>[,<command>...] -inbox <dir> -outbox <dir> -errors <dir> [flags]")
+		flags.PrintDefaults()
+	}
+	spec := flags.String("operation", "", "operation, or comma separated pipeline of operations, run on every file")
+	flags.StringVar(&opts.Inbox, "inbox", "", "directory polled for new CSV files")
+	flags.StringVar(&opts.Outbox, "outbox", "", "directory receiving the results, under the name of their file")
+	flags.StringVar(&opts.Errors, "errors", "", "directory receiving the failed files and their <name>.error.json reports")
+	flags.StringVar(&opts.State, "state", "", "file recording the hashes of the processed files and their pipeline (default <outbox>/.league-processed)")
+	flags.DurationVar(&opts.Interval, "interval", 5*time.Second, "time between two polls of the inbox")
+	flags.DurationVar(&opts.Settle, "settle", 2*time.Second, "how long a file must be left unmodified before it is processed")
+	flags.IntVar(&opts.Workers, "workers", runtime.GOMAXPROCS(0), "files processed at once")
+	parameterFlags(flags, params, pipelineParams(operations))
+	if err := flags.Parse(args); errors.Is(err, flag.ErrHelp) {
+		return exitSuccess
+	} else if err != nil {
+		return exitUsage
+	}
+	if flags.NArg() != 0 || opts.Inbox == "" || opts.Outbox == "" || opts.Errors == "" || opts.Interval <= 0 {
+		flags.Usage()
+		return exitUsage
+	}
+
+	pipeline, err := batch.ParsePipeline(*spec, operations)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return exitUsage
+	}
+	folder, err := hotfolder.New(opts, pipeline, params)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return exitUsage
+	}
+	defer folder.Close()
+
+	folder.Run(ctx)
+	return exitSuccess
+}
@@ -0,0 +1,420 @@
+// Package config loads the server configuration from defaults, a YAML file,
+// LEAGUE_* environment variables and command-line flags, each overriding the
+// previous ones.
+package config
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"maps"
+	"os"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// EnvPrefix is the prefix of the environment variables, LEAGUE_READ_TIMEOUT
+// sets read-timeout.
+const EnvPrefix = "LEAGUE_"
+
+// Config is the effective server configuration.
+type Config struct {
+	Addr         string        `yaml:"addr"`
+	ReadTimeout  time.Duration `yaml:"read_timeout"`
+	WriteTimeout time.Duration `yaml:"write_timeout"`
+	IdleTimeout  time.Duration `yaml:"idle_timeout"`
+	// ShutdownTimeout is how long in-flight requests may run after a SIGINT
+	// or SIGTERM before they are cancelled.
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout"`
+	// OperationTimeout is the deadline of every operation, OperationTimeouts
+	// overrides it per operation. 0 means no deadline.
+	OperationTimeout  time.Duration            `yaml:"operation_timeout"`
+	OperationTimeouts map[string]time.Duration `yaml:"operation_timeouts,omitempty"`
+	// MaxUploadSize is the largest request body accepted, in bytes.
+	MaxUploadSize int64 `yaml:"max_upload_size"`
+	// MaxRows, MaxCols, MaxCells and MaxCellDigits bound the matrices read in
+	// memory, MaxResultDigits the estimated size of a /multiply result. 0
+	// means no limit.
+	MaxRows         int `yaml:"max_rows"`
+	MaxCols         int `yaml:"max_cols"`
+	MaxCells        int `yaml:"max_cells"`
+	MaxCellDigits   int `yaml:"max_cell_digits"`
+	MaxResultDigits int `yaml:"max_result_digits"`
+	// Operations are the endpoints served, without the leading slash.
+	Operations []string   `yaml:"operations"`
+	LogLevel   slog.Level `yaml:"log_level"`
+	// Workers bounds the goroutines of the parallel matrix algorithms.
+	Workers int `yaml:"workers"`
+	// ExternalMemoryBudget and ExternalTempDir configure /invert?external=true,
+	// 0 and "" use the matrix package defaults.
+	ExternalMemoryBudget int64  `yaml:"external_memory_budget"`
+	ExternalTempDir      string `yaml:"external_temp_dir"`
+	// MatmulBlockSize is the side of the tiles of /matmul, and
+	// MatmulStrassenThreshold the dimension from which it uses the
+	// Strassen-Winograd algorithm over exact domains, 0 to never use it.
+	MatmulBlockSize         int `yaml:"matmul_block_size"`
+	MatmulStrassenThreshold int `yaml:"matmul_strassen_threshold"`
+	// JobWorkers bounds the jobs of /jobs running at once and JobQueueSize
+	// the jobs waiting for a worker. A finished job and its result are kept
+	// for JobTTL. JobTimeout is the deadline of a job, 0 means none.
+	JobWorkers   int           `yaml:"job_workers"`
+	JobQueueSize int           `yaml:"job_queue_size"`
+	JobTTL       time.Duration `yaml:"job_ttl"`
+	JobTimeout   time.Duration `yaml:"job_timeout"`
+	// BatchWorkers bounds the matrices of a /batch processed at once.
+	BatchWorkers int `yaml:"batch_workers"`
+	// IdempotencyWindow is how long the response to a POST with an
+	// Idempotency-Key header is replayed to retries, 0 disables the header.
+	IdempotencyWindow time.Duration `yaml:"idempotency_window"`
+	// IdempotencyMaxEntries and IdempotencyMaxBytes bound the responses kept
+	// for retries; larger bodies and responses are not kept. 0 means no bound.
+	IdempotencyMaxEntries int   `yaml:"idempotency_max_entries"`
+	IdempotencyMaxBytes   int64 `yaml:"idempotency_max_bytes"`
+	// CacheMaxEntries and CacheMaxBytes bound the results cached in memory,
+	// 0 disables the cache. CacheDir, if set, keeps up to CacheDiskMaxBytes
+	// of the results evicted from memory on disk.
+	CacheMaxEntries   int    `yaml:"cache_max_entries"`
+	CacheMaxBytes     int64  `yaml:"cache_max_bytes"`
+	CacheDir          string `yaml:"cache_dir"`
+	CacheDiskMaxBytes int64  `yaml:"cache_disk_max_bytes"`
+	// MatrixStore keeps the matrices uploaded to /matrices: "memory",
+	// "filesystem" in MatrixDir, or "none" to disable storage.
+	MatrixStore string `yaml:"matrix_store"`
+	MatrixDir   string `yaml:"matrix_dir"`
+}
+
+// Default returns the configuration used when nothing is set, serving the
+// given operations.
+func Default(operations []string) Config {
+	return Config{
+		Addr:                    ":8080",
+		ReadTimeout:             time.Minute,
+		WriteTimeout:            10 * time.Minute,
+		IdleTimeout:             2 * time.Minute,
+		ShutdownTimeout:         30 * time.Second,
+		OperationTimeout:        5 * time.Minute,
+		MaxUploadSize:           1 << 30,
+		MaxCells:                1 << 24,
+		MaxCellDigits:           10_000,
+		MaxResultDigits:         1_000_000,
+		Operations:              slices.Clone(operations),
+		LogLevel:                slog.LevelInfo,
+		Workers:                 runtime.GOMAXPROCS(0),
+		MatmulBlockSize:         64,
+		MatmulStrassenThreshold: 512,
+		JobWorkers:              2,
+		JobQueueSize:            100,
+		JobTTL:                  time.Hour,
+		JobTimeout:              time.Hour,
+		BatchWorkers:            4,
+		IdempotencyWindow:       time.Hour,
+		IdempotencyMaxEntries:   10000,
+		IdempotencyMaxBytes:     64 << 20,
+		CacheMaxEntries:         1000,
+		CacheMaxBytes:           64 << 20,
+		CacheDiskMaxBytes:       1 << 30,
+		MatrixStore:             "memory",
+	}
+}
+
+// setting is one configuration value that can be set by flag or environment
+// variable from its text.
+type setting struct {
+	name  string
+	usage string
+	set   func(c *Config, value string) error
+}
+
+var settings = []setting{
+	{"addr", "listen address", func(c *Config, value string) error {
+		c.Addr = value
+		return nil
+	}},
+	{"read-timeout", "maximum duration for reading a request", durationSetting(func(c *Config) *time.Duration { return &c.ReadTimeout })},
+	{"write-timeout", "maximum duration for writing a response", durationSetting(func(c *Config) *time.Duration { return &c.WriteTimeout })},
+	{"idle-timeout", "how long idle keep-alive connections are kept", durationSetting(func(c *Config) *time.Duration { return &c.IdleTimeout })},
+	{"shutdown-timeout", "how long in-flight requests may finish on shutdown", durationSetting(func(c *Config) *time.Duration { return &c.ShutdownTimeout })},
+	{"operation-timeout", "deadline of every operation, 0 for none", durationSetting(func(c *Config) *time.Duration { return &c.OperationTimeout })},
+	{"operation-timeouts", "per operation deadlines such as multiply=30s,matmul=2m", func(c *Config, value string) error {
+		c.OperationTimeouts = map[string]time.Duration{}
+		for _, entry := range strings.Split(value, ",") {
+			if entry = strings.TrimSpace(entry); entry == "" {
+				continue
+			}
+			op, timeout, ok := strings.Cut(entry, "=")
+			if !ok {
+				return fmt.Errorf("%q is not operation=duration", entry)
+			}
+			d, err := time.ParseDuration(timeout)
+			if err != nil {
+				return err
+			}
+			c.OperationTimeouts[strings.TrimSpace(op)] = d
+		}
+		return nil
+	}},
+	{"max-upload-size", "largest request body in bytes", int64Setting(func(c *Config) *int64 { return &c.MaxUploadSize })},
+	{"max-rows", "most rows in a matrix, 0 for no limit", intSetting(func(c *Config) *int { return &c.MaxRows })},
+	{"max-cols", "most columns in a matrix, 0 for no limit", intSetting(func(c *Config) *int { return &c.MaxCols })},
+	{"max-cells", "most cells in a matrix, 0 for no limit", intSetting(func(c *Config) *int { return &c.MaxCells })},
+	{"max-cell-digits", "most digits in a cell, 0 for no limit", intSetting(func(c *Config) *int { return &c.MaxCellDigits })},
+	{"max-result-digits", "most digits a product may have, 0 for no limit", intSetting(func(c *Config) *int { return &c.MaxResultDigits })},
+	{"operations", "comma-separated endpoints to serve", func(c *Config, value string) error {
+		c.Operations = nil
+		for _, op := range strings.Split(value, ",") {
+			if op = strings.TrimSpace(op); op != "" {
+				c.Operations = append(c.Operations, op)
+			}
+		}
+		return nil
+	}},
+	{"log-level", "debug, info, warn or error", func(c *Config, value string) error {
+		return c.LogLevel.UnmarshalText([]byte(value))
+	}},
+	{"workers", "goroutines used by the parallel matrix algorithms", intSetting(func(c *Config) *int { return &c.Workers })},
+	{"external-memory-budget", "memory in bytes for transposing matrices larger than memory", int64Setting(func(c *Config) *int64 { return &c.ExternalMemoryBudget })},
+	{"external-temp-dir", "directory for the blocks spilled by external transposes", func(c *Config, value string) error {
+		c.ExternalTempDir = value
+		return nil
+	}},
+	{"matmul-block-size", "side of the tiles of the matrix product", intSetting(func(c *Config) *int { return &c.MatmulBlockSize })},
+	{"matmul-strassen-threshold", "dimension from which exact matrix products use Strassen-Winograd, 0 never", intSetting(func(c *Config) *int { return &c.MatmulStrassenThreshold })},
+	{"job-workers", "jobs running at once", intSetting(func(c *Config) *int { return &c.JobWorkers })},
+	{"job-queue-size", "jobs waiting for a worker before submissions are refused", intSetting(func(c *Config) *int { return &c.JobQueueSize })},
+	{"job-ttl", "how long finished jobs and their results are kept", durationSetting(func(c *Config) *time.Duration { return &c.JobTTL })},
+	{"job-timeout", "deadline of every job, 0 for none", durationSetting(func(c *Config) *time.Duration { return &c.JobTimeout })},
+	{"batch-workers", "matrices of a batch processed at once", intSetting(func(c *Config) *int { return &c.BatchWorkers })},
+	{"idempotency-window", "how long responses are replayed for a repeated Idempotency-Key, 0 to ignore the header", durationSetting(func(c *Config) *time.Duration { return &c.IdempotencyWindow })},
+	{"idempotency-max-entries", "most responses kept for a repeated Idempotency-Key, 0 for no bound", intSetting(func(c *Config) *int { return &c.IdempotencyMaxEntries })},
+	{"idempotency-max-bytes", "most bytes of responses kept for a repeated Idempotency-Key, and of a body made idempotent, 0 for no bound", int64Setting(func(c *Config) *int64 { return &c.IdempotencyMaxBytes })},
+	{"cache-max-entries", "most results cached in memory, 0 disables the cache", intSetting(func(c *Config) *int { return &c.CacheMaxEntries })},
+	{"cache-max-bytes", "most bytes of results cached in memory, 0 disables the cache", int64Setting(func(c *Config) *int64 { return &c.CacheMaxBytes })},
+	{"cache-dir", "directory keeping the results evicted from memory, empty for none", func(c *Config, value string) error {
+		c.CacheDir = value
+		return nil
+	}},
+	{"cache-disk-max-bytes", "most bytes of results cached on disk", int64Setting(func(c *Config) *int64 { return &c.CacheDiskMaxBytes })},
+	{"matrix-store", "where uploaded matrices are kept: memory, filesystem or none", func(c *Config, value string) error {
+		c.MatrixStore = value
+		return nil
+	}},
+	{"matrix-dir", "directory of the filesystem matrix store", func(c *Config, value string) error {
+		c.MatrixDir = value
+		return nil
+	}},
+}
+
+func durationSetting(field func(c *Config) *time.Duration) func(c *Config, value string) error {
+	return func(c *Config, value string) error {
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return err
+		}
+		*field(c) = d
+		return nil
+	}
+}
+
+func intSetting(field func(c *Config) *int) func(c *Config, value string) error {
+	return func(c *Config, value string) error {
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return err
+		}
+		*field(c) = n
+		return nil
+	}
+}
+
+func int64Setting(field func(c *Config) *int64) func(c *Config, value string) error {
+	return func(c *Config, value string) error {
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return err
+		}
+		*field(c) = n
+		return nil
+	}
+}
+
+// envName returns the environment variable of a setting.
+func envName(name string) string {
+	return EnvPrefix + strings.ToUpper(strings.ReplaceAll(name, "-", "_"))
+}
+
+// Load builds the configuration from the command-line arguments and the
+// environment. The YAML file is named by the -config flag or LEAGUE_CONFIG.
+// operations are the endpoints the server knows, all enabled by default.
+func Load(args []string, operations []string) (Config, error) {
+	fs := flag.NewFlagSet("league", flag.ContinueOnError)
+	path := fs.String("config", os.Getenv(EnvPrefix+"CONFIG"), "YAML configuration file ("+EnvPrefix+"CONFIG)")
+
+	// Flags are only recorded while parsing and applied last, so that they
+	// override the file and the environment whatever their position.
+	type flagValue struct {
+		setting setting
+		value   string
+	}
+	var flags []flagValue
+	for _, s := range settings {
+		fs.Func(s.name, fmt.Sprintf("%s (%s)", s.usage, envName(s.name)), func(value string) error {
+			flags = append(flags, flagValue{s, value})
+			return nil
+		})
+	}
+	if err := fs.Parse(args); err != nil {
+		return Config{}, err
+	}
+	if fs.NArg() > 0 {
+		return Config{}, fmt.Errorf("unexpected argument %q", fs.Arg(0))
+	}
+
+	c := Default(operations)
+	if *path != "" {
+		if err := c.loadFile(*path); err != nil {
+			return Config{}, err
+		}
+	}
+
+	for _, s := range settings {
+		if value, ok := os.LookupEnv(envName(s.name)); ok {
+			if err := s.set(&c, value); err != nil {
+				return Config{}, fmt.Errorf("invalid %s: %w", envName(s.name), err)
+			}
+		}
+	}
+
+	for _, f := range flags {
+		if err := f.setting.set(&c, f.value); err != nil {
+			return Config{}, fmt.Errorf("invalid -%s: %w", f.setting.name, err)
+		}
+	}
+
+	if err := c.Validate(operations); err != nil {
+		return Config{}, err
+	}
+	return c, nil
+}
+
+// loadFile overrides c with the values set in the YAML file.
+func (c *Config) loadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(c); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("invalid config file %s: %w", path, err)
+	}
+	return nil
+}
+
+// Validate checks that the configuration is usable. operations are the
+// endpoints the server knows.
+func (c Config) Validate(operations []string) error {
+	var problems []string
+	if c.Addr == "" {
+		problems = append(problems, "addr must not be empty")
+	}
+	if c.ReadTimeout < 0 || c.WriteTimeout < 0 || c.IdleTimeout < 0 || c.ShutdownTimeout < 0 || c.OperationTimeout < 0 || c.JobTimeout < 0 || c.IdempotencyWindow < 0 {
+		problems = append(problems, "timeouts must not be negative")
+	}
+	if c.MaxUploadSize <= 0 {
+		problems = append(problems, "max_upload_size must be positive")
+	}
+	if c.MaxRows < 0 || c.MaxCols < 0 || c.MaxCells < 0 || c.MaxCellDigits < 0 || c.MaxResultDigits < 0 {
+		problems = append(problems, "matrix limits must not be negative")
+	}
+	if len(c.Operations) == 0 {
+		problems = append(problems, "at least one operation must be enabled")
+	}
+	for i, op := range c.Operations {
+		if !slices.Contains(operations, op) {
+			problems = append(problems, fmt.Sprintf("unknown operation %q, expected one of %s", op, strings.Join(operations, ", ")))
+		} else if slices.Contains(c.Operations[:i], op) {
+			problems = append(problems, fmt.Sprintf("operation %q is listed twice", op))
+		}
+	}
+	for _, op := range slices.Sorted(maps.Keys(c.OperationTimeouts)) {
+		if !slices.Contains(operations, op) {
+			problems = append(problems, fmt.Sprintf("unknown operation %q in operation_timeouts", op))
+		}
+		if c.OperationTimeouts[op] < 0 {
+			problems = append(problems, fmt.Sprintf("operation timeout of %s must not be negative", op))
+		}
+	}
+	if c.Workers < 1 {
+		problems = append(problems, "workers must be at least 1")
+	}
+	if c.ExternalMemoryBudget < 0 {
+		problems = append(problems, "external_memory_budget must not be negative")
+	}
+	if c.MatmulBlockSize < 1 {
+		problems = append(problems, "matmul_block_size must be at least 1")
+	}
+	if c.MatmulStrassenThreshold < 0 {
+		problems = append(problems, "matmul_strassen_threshold must not be negative")
+	}
+	if c.IdempotencyMaxEntries < 0 || c.IdempotencyMaxBytes < 0 {
+		problems = append(problems, "idempotency bounds must not be negative")
+	}
+	if c.CacheMaxEntries < 0 || c.CacheMaxBytes < 0 {
+		problems = append(problems, "cache bounds must not be negative")
+	}
+	if c.CacheDir != "" && c.CacheDiskMaxBytes <= 0 {
+		problems = append(problems, "cache_disk_max_bytes must be positive with a cache_dir")
+	}
+	switch c.MatrixStore {
+	case "memory", "none":
+	case "filesystem":
+		if c.MatrixDir == "" {
+			problems = append(problems, "matrix_dir must be set with the filesystem matrix_store")
+		}
+	default:
+		problems = append(problems, fmt.Sprintf("unknown matrix_store %q, expected memory, filesystem or none", c.MatrixStore))
+	}
+	if c.JobWorkers < 1 {
+		problems = append(problems, "job_workers must be at least 1")
+	}
+	if c.JobQueueSize < 0 {
+		problems = append(problems, "job_queue_size must not be negative")
+	}
+	if c.BatchWorkers < 1 {
+		problems = append(problems, "batch_workers must be at least 1")
+	}
+	if c.JobTTL <= 0 {
+		problems = append(problems, "job_ttl must be positive")
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("invalid configuration: %s", strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// Timeout returns the deadline of the operation, 0 for none.
+func (c Config) Timeout(operation string) time.Duration {
+	if timeout, ok := c.OperationTimeouts[operation]; ok {
+		return timeout
+	}
+	return c.OperationTimeout
+}
+
+// String returns the configuration as YAML, in the format of the config file.
+func (c Config) String() string {
+	data, err := yaml.Marshal(c)
+	if err != nil {
+		return err.Error()
+	}
+	return string(data)
+}
@@ -0,0 +1,141 @@
+package config
+
+import (
+	"log/slog"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+var testOperations = []string{"echo", "sum", "multiply"}
+
+func writeConfigFile(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "league.yaml")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	path := writeConfigFile(t, "addr: :9000\nread_timeout: 5s\nworkers: 2\nlog_level: warn\noperations: [sum]\n")
+	t.Setenv("LEAGUE_WORKERS", "3")
+	t.Setenv("LEAGUE_READ_TIMEOUT", "7s")
+
+	cfg, err := Load([]string{"-workers", "4", "-config", path}, testOperations)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	expected := Default(testOperations)
+	expected.Addr = ":9000"                // file
+	expected.LogLevel = slog.LevelWarn     // file
+	expected.Operations = []string{"sum"}  // file
+	expected.ReadTimeout = 7 * time.Second // environment over file
+	expected.Workers = 4                   // flag over environment and file
+	if !reflect.DeepEqual(cfg, expected) {
+		t.Errorf("Load() = %+v, want %+v", cfg, expected)
+	}
+}
+
+func TestLoadConfigFromEnvironment(t *testing.T) {
+	t.Setenv("LEAGUE_CONFIG", writeConfigFile(t, "addr: :9001\n"))
+
+	cfg, err := Load(nil, testOperations)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.Addr != ":9001" {
+		t.Errorf("Addr = %q, want %q", cfg.Addr, ":9001")
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		file     string
+		expected string
+	}{
+		{name: "Invalid Duration", args: []string{"-read-timeout", "soon"}, expected: "read-timeout"},
+		{name: "Invalid Number", args: []string{"-workers", "many"}, expected: "workers"},
+		{name: "Invalid Log Level", args: []string{"-log-level", "loud"}, expected: "log-level"},
+		{name: "Unknown Flag", args: []string{"-port", "80"}, expected: "port"},
+		{name: "Extra Argument", args: []string{"serve"}, expected: "unexpected argument"},
+		{name: "Unknown Operation", args: []string{"-operations", "sum,power"}, expected: `unknown operation "power"`},
+		{name: "Duplicate Operation", args: []string{"-operations", "sum,sum"}, expected: "listed twice"},
+		{name: "No Operation", args: []string{"-operations", ""}, expected: "at least one operation"},
+		{name: "Invalid Workers", args: []string{"-workers", "0"}, expected: "workers must be at least 1"},
+		{name: "Invalid Matmul Block Size", args: []string{"-matmul-block-size", "0"}, expected: "matmul_block_size must be at least 1"},
+		{name: "Invalid Strassen Threshold", args: []string{"-matmul-strassen-threshold", "-1"}, expected: "matmul_strassen_threshold must not be negative"},
+		{name: "Invalid Upload Size", args: []string{"-max-upload-size", "-1"}, expected: "max_upload_size"},
+		{name: "Invalid Operation Timeouts", args: []string{"-operation-timeouts", "sum"}, expected: "operation=duration"},
+		{name: "Unknown Operation Timeout", args: []string{"-operation-timeouts", "power=1s"}, expected: `unknown operation "power" in operation_timeouts`},
+		{name: "Invalid Limit", args: []string{"-max-cells", "-1"}, expected: "limits must not be negative"},
+		{name: "Invalid Idempotency Bound", args: []string{"-idempotency-max-entries", "-1"}, expected: "idempotency bounds must not be negative"},
+		{name: "Invalid Cache Bound", args: []string{"-cache-max-bytes", "-1"}, expected: "cache bounds must not be negative"},
+		{name: "Cache Dir Without Room", args: []string{"-cache-dir", "/tmp/league", "-cache-disk-max-bytes", "0"}, expected: "cache_disk_max_bytes must be positive"},
+		{name: "Unknown Matrix Store", args: []string{"-matrix-store", "s3"}, expected: `unknown matrix_store "s3"`},
+		{name: "Matrix Store Without Dir", args: []string{"-matrix-store", "filesystem"}, expected: "matrix_dir must be set"},
+		{name: "Invalid Job Workers", args: []string{"-job-workers", "0"}, expected: "job_workers must be at least 1"},
+		{name: "Invalid Batch Workers", args: []string{"-batch-workers", "0"}, expected: "batch_workers must be at least 1"},
+		{name: "Invalid Job TTL", args: []string{"-job-ttl", "0s"}, expected: "job_ttl must be positive"},
+		{name: "Negative Job Timeout", args: []string{"-job-timeout", "-1s"}, expected: "timeouts must not be negative"},
+		{name: "Unknown Field", file: "port: 80\n", expected: "port"},
+		{name: "Invalid Field", file: "idle_timeout: [1]\n", expected: "time.Duration"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args := tt.args
+			if tt.file != "" {
+				args = append(args, "-config", writeConfigFile(t, tt.file))
+			}
+
+			_, err := Load(args, testOperations)
+			if err == nil || !strings.Contains(err.Error(), tt.expected) {
+				t.Errorf("Load() error = %v, want it to mention %q", err, tt.expected)
+			}
+		})
+	}
+}
+
+func TestOperationTimeouts(t *testing.T) {
+	path := writeConfigFile(t, "operation_timeout: 1m\noperation_timeouts:\n  sum: 10s\n")
+
+	cfg, err := Load([]string{"-config", path}, testOperations)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.Timeout("sum") != 10*time.Second || cfg.Timeout("multiply") != time.Minute {
+		t.Errorf("Timeout() = %v, %v, want 10s, 1m", cfg.Timeout("sum"), cfg.Timeout("multiply"))
+	}
+
+	// A flag replaces the overrides of the file.
+	cfg, err = Load([]string{"-config", path, "-operation-timeouts", "multiply=0s, echo=2s"}, testOperations)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.Timeout("sum") != time.Minute || cfg.Timeout("multiply") != 0 || cfg.Timeout("echo") != 2*time.Second {
+		t.Errorf("Timeout() = %v, %v, %v, want 1m, 0s, 2s", cfg.Timeout("sum"), cfg.Timeout("multiply"), cfg.Timeout("echo"))
+	}
+}
+
+func TestStringRoundTrips(t *testing.T) {
+	cfg := Default(testOperations)
+	cfg.LogLevel = slog.LevelDebug
+	cfg.OperationTimeouts = map[string]time.Duration{"multiply": time.Hour}
+	cfg.ExternalTempDir = "/tmp/league"
+
+	loaded, err := Load([]string{"-config", writeConfigFile(t, cfg.String())}, testOperations)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if !reflect.DeepEqual(loaded, cfg) {
+		t.Errorf("Load(String()) = %+v, want %+v", loaded, cfg)
+	}
+}
@@ -0,0 +1,329 @@
+package controller
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"hash"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// CacheOptions configures a Cache.
+type CacheOptions struct {
+	// MaxEntries and MaxBytes bound the results kept in memory.
+	MaxEntries int
+	MaxBytes   int64
+	// Dir, if set, keeps the results evicted from memory on disk, up to
+	// DiskMaxBytes.
+	Dir          string
+	DiskMaxBytes int64
+}
+
+// CacheStats are the counters of a Cache, reported by its StatsHandler.
+type CacheStats struct {
+	Hits        int64   `json:"hits"`
+	MemoryHits  int64   `json:"memory_hits"`
+	DiskHits    int64   `json:"disk_hits"`
+	Misses      int64   `json:"misses"`
+	NotModified int64   `json:"not_modified"`
+	HitRatio    float64 `json:"hit_ratio"`
+	Stores      int64   `json:"stores"`
+	Evictions   int64   `json:"evictions"`
+	Entries     int     `json:"entries"`
+	Bytes       int64   `json:"bytes"`
+	DiskEntries int     `json:"disk_entries"`
+	DiskBytes   int64   `json:"disk_bytes"`
+}
+
+// Cache answers repeated computations from the results of earlier ones. A
+// result is addressed by a hash of the operation, its parameters and the
+// uploaded matrices, read as CSV so that formatting such as line endings or
+// quoting does not matter. The hash is sent as the ETag of the
+// result, and a request whose If-None-Match has it is answered 304 without
+// computing anything.
+//
+// Only successful results are kept, and a result computed from a stored
+// matrix is not answered once the matrix is deleted. Streamed requests (stream=true and
+// external=true) are never cached, their uploads do not fit in memory.
+type Cache struct {
+	operations []string
+
+	mu    sync.Mutex
+	lru   *lru
+	disk  *diskTier
+	stats CacheStats
+}
+
+// NewCache returns a Cache of the given operations, without the leading
+// slash. It fails if the disk tier cannot be opened.
+func NewCache(opts CacheOptions, operations []string) (*Cache, error) {
+	c := &Cache{operations: operations, lru: newLRU(opts.MaxEntries, opts.MaxBytes)}
+	if opts.Dir != "" {
+		disk, err := openDiskTier(opts.Dir, opts.DiskMaxBytes)
+		if err != nil {
+			return nil, err
+		}
+		c.disk = disk
+	}
+	return c, nil
+}
+
+// Handler caches the results of next for the POST requests to the cached
+// operations.
+func (c *Cache) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// The key holds only the IDs of stored matrices, a deleted one must
+		// not be answered from the cache.
+		if !c.cacheable(r) || !storedMatricesExist(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		key := cacheKey(r, body)
+		etag := `"` + key + `"`
+		w.Header().Set("ETag", etag)
+
+		if matchesETag(r.Header.Get("If-None-Match"), etag) {
+			c.count(func(s *CacheStats) { s.NotModified++ })
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+
+		if result, ok := c.get(key); ok {
+			w.Header().Set("X-Cache", "HIT")
+			if result.contentType != "" {
+				w.Header().Set("Content-Type", result.contentType)
+			}
+			w.Write(result.body)
+			return
+		}
+
+		w.Header().Set("X-Cache", "MISS")
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		response := newResponseRecorder()
+		next.ServeHTTP(response, r)
+		if response.failed() {
+			// Failures are not addressed by the request, the ETag does
+			// not apply to them.
+			w.Header().Del("ETag")
+		} else {
+			c.put(key, &cachedResult{contentType: response.header.Get("Content-Type"), body: response.body.Bytes()})
+		}
+		response.replay(w)
+	})
+}
+
+// StatsHandler reports the CacheStats as JSON.
+func (c *Cache) StatsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(c.Stats())
+}
+
+// Stats returns the current counters.
+func (c *Cache) Stats() CacheStats {
+	c.mu.Lock()
+	stats := c.stats
+	stats.Entries, stats.Bytes = c.lru.order.Len(), c.lru.bytes
+	c.mu.Unlock()
+
+	if lookups := stats.Hits + stats.Misses; lookups > 0 {
+		stats.HitRatio = float64(stats.Hits) / float64(lookups)
+	}
+	if c.disk != nil {
+		stats.DiskEntries, stats.DiskBytes = c.disk.stats()
+	}
+	return stats
+}
+
+func (c *Cache) cacheable(r *http.Request) bool {
+	query := r.URL.Query()
+	return r.Method == http.MethodPost &&
+		slices.Contains(c.operations, strings.TrimPrefix(r.URL.Path, "/")) &&
+		query.Get("stream") != "true" && query.Get("external") != "true"
+}
+
+func (c *Cache) count(update func(s *CacheStats)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	update(&c.stats)
+}
+
+// get looks the result up in memory, then on disk. A result found on disk
+// moves back to memory.
+func (c *Cache) get(key string) (*cachedResult, bool) {
+	c.mu.Lock()
+	result, ok := c.lru.get(key)
+	if ok {
+		c.stats.Hits++
+		c.stats.MemoryHits++
+	}
+	c.mu.Unlock()
+	if ok {
+		return result, true
+	}
+
+	if c.disk != nil {
+		if result, ok := c.disk.get(key); ok {
+			c.count(func(s *CacheStats) {
+				s.Hits++
+				s.DiskHits++
+			})
+			c.store(key, result)
+			return result, true
+		}
+	}
+
+	c.count(func(s *CacheStats) { s.Misses++ })
+	return nil, false
+}
+
+func (c *Cache) put(key string, result *cachedResult) {
+	c.count(func(s *CacheStats) { s.Stores++ })
+	c.store(key, result)
+}
+
+// store keeps the result in memory and moves the evicted ones to disk.
+func (c *Cache) store(key string, result *cachedResult) {
+	c.mu.Lock()
+	evicted := c.lru.add(key, result)
+	c.stats.Evictions += int64(len(evicted))
+	c.mu.Unlock()
+
+	if c.disk == nil {
+		return
+	}
+	for _, entry := range evicted {
+		c.disk.put(entry.key, entry.result)
+	}
+	// A result too large for memory goes straight to disk.
+	if result.size() > c.lru.maxBytes {
+		c.disk.put(key, result)
+	}
+}
+
+// storedMatricesExist reports whether the stored matrices the request
+// references in place of its uploads all still exist.
+func storedMatricesExist(r *http.Request) bool {
+	for param, ids := range r.URL.Query() {
+		if param != storedMatrixParam("file") && !strings.HasPrefix(param, storedMatrixParam("")) {
+			continue
+		}
+		for _, id := range ids {
+			if Matrices == nil {
+				return false
+			}
+			file, _, err := Matrices.Open(r.Context(), id)
+			if err != nil {
+				return false
+			}
+			file.Close()
+		}
+	}
+	return true
+}
+
+// matchesETag reports whether an If-None-Match header lists etag, or is *.
+func matchesETag(header, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == etag || candidate == "*" {
+			return true
+		}
+	}
+	return false
+}
+
+// cacheKey hashes the operation, the parameters and the uploaded matrices of
+// a request. Form values count as parameters whether sent in the query or the
+// body, a registered schema counts with its current definition, and files
+// count by their CSV records.
+func cacheKey(r *http.Request, body []byte) string {
+	h := sha256.New()
+	writeField(h, r.URL.Path)
+
+	params := r.URL.Query()
+	var files [][2]string
+	mediaType, mediaParams, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err == nil && mediaType == "multipart/form-data" {
+		parts := multipart.NewReader(bytes.NewReader(body), mediaParams["boundary"])
+		for {
+			part, err := parts.NextPart()
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			if err != nil {
+				// Not a valid form, the raw body is all we have.
+				params, files = r.URL.Query(), [][2]string{{"", string(body)}}
+				break
+			}
+			content, _ := io.ReadAll(part)
+			if part.FileName() == "" {
+				params.Add(part.FormName(), string(content))
+				continue
+			}
+			files = append(files, [2]string{part.FormName(), string(content)})
+		}
+	} else {
+		files = [][2]string{{"", string(body)}}
+	}
+
+	if schema := params.Get("schema"); schema != "" {
+		params.Set("schema", resolveSchema(schema))
+	}
+	writeField(h, params.Encode())
+
+	slices.SortStableFunc(files, func(a, b [2]string) int { return strings.Compare(a[0], b[0]) })
+	for _, file := range files {
+		writeField(h, file[0])
+		writeRecords(h, file[1])
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// resolveSchema returns the definition of a registered schema, or spec itself
+// if it is an inline spec or unknown.
+func resolveSchema(spec string) string {
+	schemas.RLock()
+	defer schemas.RUnlock()
+	if schema, ok := schemas.byName[spec]; ok {
+		if definition, err := json.Marshal(schema); err == nil {
+			return string(definition)
+		}
+	}
+	return spec
+}
+
+// writeRecords hashes content by its CSV records, read exactly as the
+// handlers read them, or as is when they would fail to read it. Only what
+// encoding/csv normalizes, such as line endings, quoting and blank lines,
+// makes two contents the same: a cell with spaces around it is another cell.
+func writeRecords(h hash.Hash, content string) {
+	records, err := csv.NewReader(strings.NewReader(content)).ReadAll()
+	if err != nil {
+		writeField(h, "raw")
+		writeField(h, content)
+		return
+	}
+
+	writeField(h, "csv")
+	for _, row := range records {
+		writeField(h, "row")
+		for _, cell := range row {
+			writeField(h, cell)
+		}
+	}
+}
@@ -0,0 +1,337 @@
+package controller_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync/atomic"
+	"testing"
+
+	"league/main/controller"
+	"league/main/matrix"
+)
+
+// countingOperation counts the requests reaching handler.
+func countingOperation(calls *atomic.Int64, handler http.HandlerFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		handler(w, r)
+	})
+}
+
+// formRequest uploads the files, keyed by field name, with the form values.
+func formRequest(url string, values map[string]string, files ...[2]string) *http.Request {
+	form := new(bytes.Buffer)
+	writer := multipart.NewWriter(form)
+	for name, value := range values {
+		writer.WriteField(name, value)
+	}
+	for _, file := range files {
+		fileWriter, _ := writer.CreateFormFile(file[0], "test.csv")
+		fileWriter.Write([]byte(file[1]))
+	}
+	writer.Close()
+
+	req := httptest.NewRequest("POST", url, form)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	return req
+}
+
+func newCache(t *testing.T, opts controller.CacheOptions) *controller.Cache {
+	t.Helper()
+	if opts.MaxEntries == 0 {
+		opts.MaxEntries = 10
+	}
+	if opts.MaxBytes == 0 {
+		opts.MaxBytes = 1 << 20
+	}
+	cache, err := controller.NewCache(opts, []string{"sum", "echo", "matmul", "invert"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cache
+}
+
+func TestCache(t *testing.T) {
+	file := func(content string) [2]string { return [2]string{"file", content} }
+
+	tests := []struct {
+		name          string
+		first, second *http.Request
+		expectedCache string
+		expectedBody  string
+		expectedCalls int64
+	}{
+		{
+			name:          "Same Matrix",
+			first:         formRequest("/sum", nil, file("1,2\n3,4\n")),
+			second:        formRequest("/sum", nil, file("1,2\n3,4\n")),
+			expectedCache: "HIT",
+			expectedBody:  "10\n",
+			expectedCalls: 1,
+		},
+		{
+			name:          "Same Matrix Formatted Differently",
+			first:         formRequest("/sum", nil, file("1,2\n3,4\n")),
+			second:        formRequest("/sum", nil, file("1,\"2\"\r\n\r\n3,4")),
+			expectedCache: "HIT",
+			expectedBody:  "10\n",
+			expectedCalls: 1,
+		},
+		{
+			name:          "Spaces Around Cells",
+			first:         formRequest("/sum", nil, file("1,2\n")),
+			second:        formRequest("/sum", nil, file("1, 2\n")),
+			expectedCache: "MISS",
+			expectedBody:  "error invalid number at position [0,1]",
+			expectedCalls: 2,
+		},
+		{
+			name:          "Spaces Kept In The Result",
+			first:         formRequest("/echo", nil, file("a, b\n")),
+			second:        formRequest("/echo", nil, file("a,b\n")),
+			expectedCache: "MISS",
+			expectedBody:  "a,b\n",
+			expectedCalls: 2,
+		},
+		{
+			name:          "Parameter In Query Or Form",
+			first:         formRequest("/sum?domain=bigint", nil, file("1,2\n")),
+			second:        formRequest("/sum", map[string]string{"domain": "bigint"}, file("1,2\n")),
+			expectedCache: "HIT",
+			expectedBody:  "3\n",
+			expectedCalls: 1,
+		},
+		{
+			name:          "Different Matrix",
+			first:         formRequest("/sum", nil, file("1,2\n")),
+			second:        formRequest("/sum", nil, file("1,3\n")),
+			expectedCache: "MISS",
+			expectedBody:  "4\n",
+			expectedCalls: 2,
+		},
+		{
+			name:          "Different Parameters",
+			first:         formRequest("/sum?domain=bigint", nil, file("1,2\n")),
+			second:        formRequest("/sum?domain=rational", nil, file("1,2\n")),
+			expectedCache: "MISS",
+			expectedBody:  "3\n",
+			expectedCalls: 2,
+		},
+		{
+			name:          "Different Operation",
+			first:         formRequest("/sum", nil, file("1,2\n")),
+			second:        formRequest("/invert", nil, file("1,2\n")),
+			expectedCache: "MISS",
+			expectedBody:  "1\n2\n",
+			expectedCalls: 2,
+		},
+		{
+			name:          "Swapped Operands",
+			first:         formRequest("/matmul", nil, [2]string{"a", "1,2\n"}, [2]string{"b", "3\n4\n"}),
+			second:        formRequest("/matmul", nil, [2]string{"a", "3\n4\n"}, [2]string{"b", "1,2\n"}),
+			expectedCache: "MISS",
+			expectedBody:  "3,6\n4,8\n",
+			expectedCalls: 2,
+		},
+		{
+			name:          "Errors Are Not Cached",
+			first:         formRequest("/sum", nil, file("1,a\n")),
+			second:        formRequest("/sum", nil, file("1,a\n")),
+			expectedCache: "MISS",
+			expectedBody:  "error invalid number at position [0,1]",
+			expectedCalls: 2,
+		},
+		{
+			name:          "Results Like An Error Are Cached",
+			first:         formRequest("/echo", nil, file("error x,1\n")),
+			second:        formRequest("/echo", nil, file("error x,1\n")),
+			expectedCache: "HIT",
+			expectedBody:  "error x,1\n",
+			expectedCalls: 1,
+		},
+		{
+			name:          "Streams Are Not Cached",
+			first:         formRequest("/sum?stream=true", nil, file("1,2\n")),
+			second:        formRequest("/sum?stream=true", nil, file("1,2\n")),
+			expectedBody:  "3\n",
+			expectedCalls: 2,
+		},
+		{
+			name:          "Operation Not Cached",
+			first:         formRequest("/multiply", nil, file("1,2\n")),
+			second:        formRequest("/multiply", nil, file("1,2\n")),
+			expectedBody:  "2\n",
+			expectedCalls: 2,
+		},
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/sum", controller.SumHandler)
+	mux.HandleFunc("/echo", controller.EchoHandler)
+	mux.HandleFunc("/multiply", controller.MultiplyHandler)
+	mux.HandleFunc("/matmul", controller.MatMulHandler)
+	mux.HandleFunc("/invert", controller.InvertHandler)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls atomic.Int64
+			handler := newCache(t, controller.CacheOptions{}).Handler(countingOperation(&calls, mux.ServeHTTP))
+
+			handler.ServeHTTP(httptest.NewRecorder(), tt.first)
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, tt.second)
+
+			if cache := rr.Header().Get("X-Cache"); cache != tt.expectedCache {
+				t.Errorf("X-Cache = %q, want %q", cache, tt.expectedCache)
+			}
+			if rr.Body.String() != tt.expectedBody {
+				t.Errorf("expected %q; got %q", tt.expectedBody, rr.Body.String())
+			}
+			if calls.Load() != tt.expectedCalls {
+				t.Errorf("operation ran %d times, want %d", calls.Load(), tt.expectedCalls)
+			}
+		})
+	}
+}
+
+func TestCacheETag(t *testing.T) {
+	var calls atomic.Int64
+	handler := newCache(t, controller.CacheOptions{}).Handler(countingOperation(&calls, controller.SumHandler))
+
+	first := httptest.NewRecorder()
+	handler.ServeHTTP(first, formRequest("/sum", nil, [2]string{"file", "1,2\n"}))
+	etag := first.Header().Get("ETag")
+	if etag == "" {
+		t.Fatal("no ETag")
+	}
+
+	for _, header := range []string{etag, `"other", ` + etag, "W/" + etag, "*"} {
+		req := formRequest("/sum", nil, [2]string{"file", "1,2\n"})
+		req.Header.Set("If-None-Match", header)
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		if rr.Code != http.StatusNotModified || rr.Body.Len() != 0 || rr.Header().Get("ETag") != etag {
+			t.Errorf("If-None-Match %s = %d %q, want %d with ETag %s", header, rr.Code, rr.Body.String(), http.StatusNotModified, etag)
+		}
+	}
+
+	req := formRequest("/sum", nil, [2]string{"file", "1,3\n"})
+	req.Header.Set("If-None-Match", etag)
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK || rr.Header().Get("ETag") == etag {
+		t.Errorf("other matrix = %d with ETag %s, want %d with another ETag", rr.Code, rr.Header().Get("ETag"), http.StatusOK)
+	}
+
+	if calls.Load() != 2 {
+		t.Errorf("operation ran %d times, want 2", calls.Load())
+	}
+}
+
+func TestCacheDeletedMatrix(t *testing.T) {
+	useMatrices(t)
+	id := putMatrix(t, "1,2\n3,4\n")
+	var calls atomic.Int64
+	handler := newCache(t, controller.CacheOptions{}).Handler(countingOperation(&calls, controller.SumHandler))
+
+	first := httptest.NewRecorder()
+	handler.ServeHTTP(first, formRequest("/sum?matrix="+id, nil))
+	if first.Body.String() != "10\n" {
+		t.Fatalf("first = %q, want %q", first.Body.String(), "10\n")
+	}
+
+	rr := httptest.NewRecorder()
+	controller.MatricesHandler(rr, httptest.NewRequest("DELETE", "/matrices/"+id, nil))
+	if rr.Code != http.StatusNoContent {
+		t.Fatalf("DELETE status = %d", rr.Code)
+	}
+
+	for _, etag := range []string{"", first.Header().Get("ETag")} {
+		req := formRequest("/sum?matrix="+id, nil)
+		req.Header.Set("If-None-Match", etag)
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		if rr.Code != http.StatusNotFound || rr.Header().Get("X-Cache") != "" {
+			t.Errorf("If-None-Match %q after the delete = %d %s %q, want %d", etag, rr.Code, rr.Header().Get("X-Cache"), rr.Body.String(), http.StatusNotFound)
+		}
+	}
+	if calls.Load() != 3 {
+		t.Errorf("operation ran %d times, want 3", calls.Load())
+	}
+}
+
+func TestCacheSchemaChanges(t *testing.T) {
+	var calls atomic.Int64
+	handler := newCache(t, controller.CacheOptions{}).Handler(countingOperation(&calls, controller.SumHandler))
+	sum := func() *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, formRequest("/sum?schema=cached", nil, [2]string{"file", "1,2\n"}))
+		return rr
+	}
+
+	controller.RegisterSchema("cached", &matrix.Schema{Cols: &matrix.Range{Min: intPtr(2)}})
+	sum()
+	controller.RegisterSchema("cached", &matrix.Schema{Cols: &matrix.Range{Min: intPtr(3)}})
+	if rr := sum(); rr.Code != http.StatusUnprocessableEntity {
+		t.Errorf("after the schema changed: %d %q, want %d", rr.Code, rr.Body.String(), http.StatusUnprocessableEntity)
+	}
+}
+
+func intPtr(i int) *int {
+	return &i
+}
+
+func TestCacheEviction(t *testing.T) {
+	var calls atomic.Int64
+	dir := t.TempDir()
+	cache := newCache(t, controller.CacheOptions{MaxEntries: 2, Dir: dir, DiskMaxBytes: 1 << 20})
+	handler := cache.Handler(countingOperation(&calls, controller.SumHandler))
+	sum := func(content string) string {
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, formRequest("/sum", nil, [2]string{"file", content}))
+		return rr.Header().Get("X-Cache")
+	}
+
+	for _, content := range []string{"1\n", "2\n", "3\n"} {
+		sum(content)
+	}
+	// 1 was evicted from memory to disk, 2 and 3 are in memory.
+	// In order: bringing 1 back to memory evicts the least recently used.
+	for _, tt := range [][2]string{{"3\n", "HIT"}, {"2\n", "HIT"}, {"1\n", "HIT"}, {"4\n", "MISS"}} {
+		if cache := sum(tt[0]); cache != tt[1] {
+			t.Errorf("sum of %q: X-Cache = %s, want %s", tt[0], cache, tt[1])
+		}
+	}
+
+	stats := cache.Stats()
+	expected := controller.CacheStats{
+		Hits: 3, MemoryHits: 2, DiskHits: 1, Misses: 4, HitRatio: 3.0 / 7, Stores: 4, Evictions: 3,
+		Entries: 2, Bytes: stats.Bytes, DiskEntries: 3, DiskBytes: stats.DiskBytes,
+	}
+	if stats != expected {
+		t.Errorf("Stats() = %+v, want %+v", stats, expected)
+	}
+
+	// The disk tier outlives the cache.
+	files, _ := os.ReadDir(dir)
+	reopened := newCache(t, controller.CacheOptions{Dir: dir, DiskMaxBytes: 1 << 20})
+	if stats := reopened.Stats(); stats.DiskEntries != len(files) {
+		t.Errorf("reopened disk tier has %d entries, want %d", stats.DiskEntries, len(files))
+	}
+	rr := httptest.NewRecorder()
+	reopened.Handler(http.HandlerFunc(controller.SumHandler)).ServeHTTP(rr, formRequest("/sum", nil, [2]string{"file", "1\n"}))
+	if rr.Header().Get("X-Cache") != "HIT" || rr.Body.String() != "1\n" {
+		t.Errorf("reopened cache = %s %q, want HIT %q", rr.Header().Get("X-Cache"), rr.Body.String(), "1\n")
+	}
+
+	rr = httptest.NewRecorder()
+	cache.StatsHandler(rr, httptest.NewRequest("GET", "/cache/stats", nil))
+	var reported controller.CacheStats
+	if err := json.Unmarshal(rr.Body.Bytes(), &reported); err != nil || reported != cache.Stats() {
+		t.Errorf("StatsHandler() = %s, want %+v", rr.Body.String(), cache.Stats())
+	}
+}
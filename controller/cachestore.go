@@ -0,0 +1,248 @@
+package controller
+
+import (
+	"bufio"
+	"container/list"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// cachedResult is a successful response kept by the Cache.
+type cachedResult struct {
+	contentType string
+	body        []byte
+}
+
+func (cr *cachedResult) size() int64 {
+	return int64(len(cr.contentType) + len(cr.body))
+}
+
+// lru is the memory tier of the Cache: at most maxEntries results of at most
+// maxBytes together, the least recently used evicted first.
+type lru struct {
+	maxEntries int
+	maxBytes   int64
+
+	order   *list.List // of *lruEntry, the most recently used first
+	entries map[string]*list.Element
+	bytes   int64
+}
+
+type lruEntry struct {
+	key    string
+	result *cachedResult
+}
+
+func newLRU(maxEntries int, maxBytes int64) *lru {
+	return &lru{maxEntries: maxEntries, maxBytes: maxBytes, order: list.New(), entries: map[string]*list.Element{}}
+}
+
+func (l *lru) get(key string) (*cachedResult, bool) {
+	element, ok := l.entries[key]
+	if !ok {
+		return nil, false
+	}
+	l.order.MoveToFront(element)
+	return element.Value.(*lruEntry).result, true
+}
+
+// add stores the result and returns the entries evicted to make room for it.
+// A result larger than maxBytes is not stored.
+func (l *lru) add(key string, result *cachedResult) []*lruEntry {
+	if result.size() > l.maxBytes {
+		return nil
+	}
+	if element, ok := l.entries[key]; ok {
+		l.bytes -= element.Value.(*lruEntry).result.size()
+		element.Value.(*lruEntry).result = result
+		l.bytes += result.size()
+		l.order.MoveToFront(element)
+	} else {
+		l.entries[key] = l.order.PushFront(&lruEntry{key: key, result: result})
+		l.bytes += result.size()
+	}
+
+	var evicted []*lruEntry
+	for l.order.Len() > l.maxEntries || l.bytes > l.maxBytes {
+		entry := l.order.Remove(l.order.Back()).(*lruEntry)
+		delete(l.entries, entry.key)
+		l.bytes -= entry.result.size()
+		evicted = append(evicted, entry)
+	}
+	return evicted
+}
+
+// diskTier keeps the results evicted from memory as files named by their key
+// in dir, at most maxBytes of them, the least recently used removed first.
+// The files of a previous run are reused.
+type diskTier struct {
+	dir      string
+	maxBytes int64
+
+	mu    sync.Mutex
+	order *list.List // of *diskEntry, the most recently used first
+	files map[string]*list.Element
+	bytes int64
+}
+
+type diskEntry struct {
+	key  string
+	size int64
+}
+
+func openDiskTier(dir string, maxBytes int64) (*diskTier, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	type file struct {
+		key      string
+		size     int64
+		modified time.Time
+	}
+	var files []file
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil || !entry.Type().IsRegular() || !isCacheKey(entry.Name()) {
+			continue
+		}
+		files = append(files, file{key: entry.Name(), size: info.Size(), modified: info.ModTime()})
+	}
+	slices.SortFunc(files, func(a, b file) int { return b.modified.Compare(a.modified) })
+
+	d := &diskTier{dir: dir, maxBytes: maxBytes, order: list.New(), files: map[string]*list.Element{}}
+	for _, f := range files {
+		d.files[f.key] = d.order.PushBack(&diskEntry{key: f.key, size: f.size})
+		d.bytes += f.size
+	}
+	d.mu.Lock()
+	d.evict()
+	d.mu.Unlock()
+	return d, nil
+}
+
+// isCacheKey reports whether name is a hex SHA-256, so files that are not
+// ours are left alone.
+func isCacheKey(name string) bool {
+	return len(name) == 64 && strings.Trim(name, "0123456789abcdef") == ""
+}
+
+func (d *diskTier) get(key string) (*cachedResult, bool) {
+	d.mu.Lock()
+	element, ok := d.files[key]
+	if ok {
+		d.order.MoveToFront(element)
+	}
+	d.mu.Unlock()
+	if !ok {
+		return nil, false
+	}
+
+	result, err := readCachedResult(filepath.Join(d.dir, key))
+	if err != nil {
+		slog.Warn("reading cached result", "key", key, "error", err)
+		d.remove(key)
+		return nil, false
+	}
+	return result, true
+}
+
+// put writes the result, replacing a previous file atomically.
+func (d *diskTier) put(key string, result *cachedResult) {
+	if result.size() >= d.maxBytes {
+		return
+	}
+	if err := writeCachedResult(d.dir, key, result); err != nil {
+		slog.Warn("writing cached result", "key", key, "error", err)
+		return
+	}
+
+	// The file has a newline after the content type.
+	size := result.size() + 1
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if element, ok := d.files[key]; ok {
+		d.bytes -= element.Value.(*diskEntry).size
+		d.order.Remove(element)
+	}
+	d.files[key] = d.order.PushFront(&diskEntry{key: key, size: size})
+	d.bytes += size
+	d.evict()
+}
+
+func (d *diskTier) remove(key string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if element, ok := d.files[key]; ok {
+		d.bytes -= element.Value.(*diskEntry).size
+		d.order.Remove(element)
+		delete(d.files, key)
+	}
+	os.Remove(filepath.Join(d.dir, key))
+}
+
+// evict removes the least recently used files until the tier fits maxBytes.
+// d.mu must be held.
+func (d *diskTier) evict() {
+	for d.bytes > d.maxBytes {
+		entry := d.order.Remove(d.order.Back()).(*diskEntry)
+		delete(d.files, entry.key)
+		d.bytes -= entry.size
+		os.Remove(filepath.Join(d.dir, entry.key))
+	}
+}
+
+func (d *diskTier) stats() (entries int, bytes int64) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.order.Len(), d.bytes
+}
+
+// A cached result file holds the content type on its first line followed by
+// the body.
+func writeCachedResult(dir, key string, result *cachedResult) error {
+	f, err := os.CreateTemp(dir, ".tmp-"+key)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	_, err = fmt.Fprintf(f, "%s\n%s", result.contentType, result.body)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), filepath.Join(dir, key))
+}
+
+func readCachedResult(path string) (*cachedResult, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	r := bufio.NewReader(f)
+	contentType, err := r.ReadString('\n')
+	if err != nil {
+		return nil, fmt.Errorf("missing content type: %w", err)
+	}
+	body, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	return &cachedResult{contentType: strings.TrimSuffix(contentType, "\n"), body: body}, nil
+}
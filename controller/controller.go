@@ -1,109 +1,366 @@
 package controller
 
 import (
+	"context"
 	"encoding/csv"
+	"encoding/json"
 	"fmt"
+	"io"
 	"league/main/matrix"
 	"net/http"
-	"strings"
+	"net/url"
 )
 
 func EchoHandler(w http.ResponseWriter, r *http.Request) {
+	serveRegistered(w, r, "echo")
+}
+
+// serveRegistered serves a built-in operation declared with a Func.
+func serveRegistered(w http.ResponseWriter, r *http.Request, name string) {
+	op, _ := LookupOperation(name)
+	op.serveFunc(w, r)
+}
+
+// ExternalTranspose configures the memory budget and temp directory of
+// /invert?external=true, which transposes matrices larger than memory.
+var ExternalTranspose matrix.ExternalOptions
+
+// Limits bounds the uploaded matrices, read in memory or streamed, and the
+// estimated size of a /multiply result.
+var Limits matrix.Limits
+
+func InvertHandler(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Query().Get("external") == "true" {
+		file, hasError := openStream(r, w)
+		if hasError {
+			return
+		}
+		defer file.Close()
+
+		opts := ExternalTranspose
+		opts.Limits = Limits
+		sw := newStreamWriter(w)
+		if err := matrix.TransposeCSVContext(r.Context(), file, sw, opts); err != nil {
+			sw.Error(err)
+		}
+		sw.Flush()
+		return
+	}
+
 	records, hasError := readFile(r, w)
 	if hasError {
 		return
 	}
 
-	var response string
-	for _, row := range records {
-		response = fmt.Sprintf("%s%s\n", response, strings.Join(row, ","))
+	// The cells are validated, then written as they were uploaded.
+	if err := matrix.ValidateContext(r.Context(), records); err != nil {
+		writeError(w, err)
+		return
 	}
 
-	fmt.Fprint(w, response)
+	sw := newStreamWriter(w)
+	matrix.WriteCSV(sw, matrix.Cells(records).T())
+	sw.Flush()
 }
 
-func InvertHandler(w http.ResponseWriter, r *http.Request) {
+func FlattenHandler(w http.ResponseWriter, r *http.Request) {
+	if isStream(r) {
+		// A streamed upload is flattened as it is read, row by row.
+		if r.URL.Query().Get("order") == "column" {
+			httpError(w, "order=column is not supported with stream=true", http.StatusBadRequest)
+			return
+		}
+		file, hasError := openStream(r, w)
+		if hasError {
+			return
+		}
+		defer file.Close()
+
+		sw := newStreamWriter(w)
+		if err := Limits.FlattenCSV(file, sw); err != nil {
+			sw.Error(err)
+		}
+		sw.Flush()
+		return
+	}
+
 	records, hasError := readFile(r, w)
 	if hasError {
 		return
 	}
 
-	invertedMatrix, err := matrix.InvertMatrix(records)
-
-	if err != nil {
-		w.Write([]byte(fmt.Sprintf("error %s", err.Error())))
+	// The cells are validated, then written as they were uploaded.
+	if err := matrix.ValidateContext(r.Context(), records); err != nil {
+		writeError(w, err)
 		return
 	}
 
-	var response string
-	for _, row := range invertedMatrix {
-		response = fmt.Sprintf("%s%s\n", response, strings.Join(row, ","))
+	// order=column flattens the transposed view, without copying the cells.
+	// Like stream, order is read from the query only, where it is validated.
+	var view matrix.View = matrix.Cells(records)
+	if r.URL.Query().Get("order") == "column" {
+		view = matrix.Cells(records).T()
 	}
 
-	fmt.Fprint(w, response)
+	sw := newStreamWriter(w)
+	matrix.WriteFlat(sw, view)
+	sw.Flush()
 }
 
-func FlattenHandler(w http.ResponseWriter, r *http.Request) {
+func SumHandler(w http.ResponseWriter, r *http.Request) {
+	if isStream(r) {
+		streamScalar(w, r, Limits.SumCSV)
+		return
+	}
+
 	records, hasError := readFile(r, w)
 	if hasError {
 		return
 	}
 
-	flattenedMatrix, err := matrix.FlattenMatrix(records)
+	var result string
+	var err error
+	if name := r.FormValue("domain"); name != "" {
+		result, err = inDomain(name, func(d matrix.Domain) (string, error) { return d.Sum(r.Context(), records) })
+	} else {
+		result, err = matrix.SumMatrixContext(r.Context(), records)
+	}
 
 	if err != nil {
-		w.Write([]byte(fmt.Sprintf("error %s", err.Error())))
+		writeError(w, err)
 		return
 	}
 
-	fmt.Fprint(w, flattenedMatrix)
+	fmt.Fprint(w, result, "\n")
 }
 
-func SumHandler(w http.ResponseWriter, r *http.Request) {
+func MultiplyHandler(w http.ResponseWriter, r *http.Request) {
+	if isStream(r) {
+		streamScalar(w, r, Limits.ProductCSV)
+		return
+	}
+
 	records, hasError := readFile(r, w)
 	if hasError {
 		return
 	}
 
-	result, err := matrix.SumMatrix(records)
+	if err := Limits.CheckProduct(records); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	var result string
+	var err error
+	if name := r.FormValue("domain"); name != "" {
+		result, err = inDomain(name, func(d matrix.Domain) (string, error) { return d.Product(r.Context(), records) })
+	} else {
+		result, err = matrix.MultiplyMatrixContext(r.Context(), records)
+	}
 
 	if err != nil {
-		w.Write([]byte(fmt.Sprintf("error %s", err.Error())))
+		writeError(w, err)
 		return
 	}
 
 	fmt.Fprint(w, result, "\n")
 }
 
-func MultiplyHandler(w http.ResponseWriter, r *http.Request) {
-	records, hasError := readFile(r, w)
+// MatMulHandler multiplies the matrices uploaded as a and b.
+func MatMulHandler(w http.ResponseWriter, r *http.Request) {
+	serveRegistered(w, r, "matmul")
+}
+
+func matMul(ctx context.Context, inputs [][][]string, params url.Values) (any, error) {
+	domain, err := matrix.LookupDomain(params.Get("domain"))
+	if err != nil {
+		return nil, err
+	}
+	return domain.MatMul(ctx, inputs[0], inputs[1])
+}
+
+func DeterminantHandler(w http.ResponseWriter, r *http.Request) {
+	serveRegistered(w, r, "determinant")
+}
+
+func determinant(ctx context.Context, inputs [][][]string, params url.Values) (any, error) {
+	return inDomain(params.Get("domain"), func(d matrix.Domain) (string, error) { return d.Determinant(ctx, inputs[0]) })
+}
+
+// InverseHandler inverts a square matrix. Inversion needs a field, so the
+// domain defaults to rational instead of bigint.
+func InverseHandler(w http.ResponseWriter, r *http.Request) {
+	serveRegistered(w, r, "inverse")
+}
+
+func inverse(ctx context.Context, inputs [][][]string, params url.Values) (any, error) {
+	name := params.Get("domain")
+	if name == "" {
+		name = "rational"
+	}
+
+	domain, err := matrix.LookupDomain(name)
+	if err != nil {
+		return nil, err
+	}
+	return domain.Inverse(ctx, inputs[0])
+}
+
+// inDomain looks up the named domain and runs fn with it.
+func inDomain(name string, fn func(matrix.Domain) (string, error)) (string, error) {
+	domain, err := matrix.LookupDomain(name)
+	if err != nil {
+		return "", err
+	}
+	return fn(domain)
+}
+
+// StatsHandler reports the count, sum, minimum, maximum and mean of the cells.
+func StatsHandler(w http.ResponseWriter, r *http.Request) {
+	var stats *matrix.Stats
+	var err error
+	if isStream(r) {
+		file, hasError := openStream(r, w)
+		if hasError {
+			return
+		}
+		defer file.Close()
+		stats, err = Limits.StatsCSV(file)
+	} else {
+		records, hasError := readFile(r, w)
+		if hasError {
+			return
+		}
+		stats, err = matrix.StatsMatrixContext(r.Context(), records)
+	}
+
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(stats)
+}
+
+// ValidateHandler always streams the upload since the report does not need
+// the whole matrix in memory.
+func ValidateHandler(w http.ResponseWriter, r *http.Request) {
+	file, hasError := openStream(r, w)
+	if hasError {
+		return
+	}
+	defer file.Close()
+
+	report, err := matrix.ValidateCSV(file)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
+
+// isStream reports whether the client asked for the streaming variant with
+// stream=true. Only the query string is consulted so the multipart body is
+// not parsed ahead of time.
+func isStream(r *http.Request) bool {
+	return r.URL.Query().Get("stream") == "true"
+}
+
+// openStream returns the file part of a multipart request, or the stored
+// matrix referenced by matrix=<id>, without buffering it in memory or on disk.
+// Reading it fails once the request context is done. Schemas need the whole
+// matrix and are not supported for streamed requests.
+func openStream(r *http.Request, w http.ResponseWriter) (io.ReadCloser, bool) {
+	if r.URL.Query().Get("schema") != "" {
+		httpError(w, "schema is not supported with stream=true", http.StatusOK)
+		return nil, true
+	}
+
+	if file, referenced, hasError := openStoredMatrix(r, w, "file"); referenced {
+		return file, hasError
+	}
+
+	reader, err := r.MultipartReader()
+	if err != nil {
+		writeError(w, err)
+		return nil, true
+	}
+
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			err = http.ErrMissingFile
+		}
+		if err != nil {
+			writeError(w, err)
+			return nil, true
+		}
+		if part.FormName() == "file" {
+			return io.NopCloser(matrix.ContextReader(r.Context(), part)), false
+		}
+	}
+}
+
+// streamScalar answers with the result of a streaming operation that reduces
+// the upload to a single number.
+func streamScalar(w http.ResponseWriter, r *http.Request, operation func(io.Reader) (string, error)) {
+	file, hasError := openStream(r, w)
 	if hasError {
 		return
 	}
+	defer file.Close()
 
-	result, err := matrix.MultiplyMatrix(records)
+	result, err := operation(file)
 
 	if err != nil {
-		w.Write([]byte(fmt.Sprintf("error %s", err.Error())))
+		writeError(w, err)
 		return
 	}
 
 	fmt.Fprint(w, result, "\n")
 }
 
-func readFile(r *http.Request, w http.ResponseWriter) ([][]string, bool) {
-	file, _, err := r.FormFile("file")
+// openFormFile returns the uploaded file field, or the stored matrix
+// referenced in its place.
+func openFormFile(r *http.Request, w http.ResponseWriter, field string) (io.ReadCloser, bool) {
+	if file, referenced, hasError := openStoredMatrix(r, w, field); referenced {
+		return file, hasError
+	}
+
+	file, _, err := r.FormFile(field)
 
 	if err != nil {
-		w.Write([]byte(fmt.Sprintf("error %s", err.Error())))
+		writeError(w, err)
+		return nil, true
+	}
+	return file, false
+}
+
+func readFile(r *http.Request, w http.ResponseWriter) ([][]string, bool) {
+	return readFormFile(r, w, "file")
+}
+
+func readFormFile(r *http.Request, w http.ResponseWriter, field string) ([][]string, bool) {
+	file, hasError := openFormFile(r, w, field)
+	if hasError {
 		return nil, true
 	}
 
 	defer file.Close()
 
 	records, err := csv.NewReader(file).ReadAll()
+	if err == nil {
+		err = Limits.Check(records)
+	}
 	if err != nil {
-		w.Write([]byte(fmt.Sprintf("error %s", err.Error())))
+		writeError(w, err)
+		return nil, true
+	}
+
+	if checkSchema(r, w, records) {
 		return nil, true
 	}
 	return records, false
@@ -2,6 +2,7 @@ package controller_test
 
 import (
 	"bytes"
+	"context"
 	"io"
 	"mime/multipart"
 	"net/http"
@@ -10,6 +11,7 @@ import (
 	"testing"
 
 	"league/main/controller"
+	"league/main/matrix"
 )
 
 func TestEchoHandler(t *testing.T) {
@@ -145,6 +147,11 @@ func TestInvertHandler(t *testing.T) {
 			fileContent: "1,2,3\n4,5,6\n",
 			expected:    "1,4\n2,5\n3,6\n",
 		},
+		{
+			name:        "Cells Kept As Written",
+			fileContent: "004,+5\n1,-0\n",
+			expected:    "004,1\n+5,-0\n",
+		},
 		{
 			name:        "Invalid Matrix",
 			fileContent: "invalid,data\n",
@@ -186,6 +193,7 @@ func TestInvertHandler(t *testing.T) {
 func TestFlattenHandler(t *testing.T) {
 	tests := []struct {
 		name        string
+		url         string
 		fileContent string
 		expected    string
 	}{
@@ -199,11 +207,32 @@ func TestFlattenHandler(t *testing.T) {
 			fileContent: "invalid,data\n",
 			expected:    "error invalid number at position [0,0]", // Adjust based on actual error handling
 		},
+		{
+			name:        "Column Order",
+			url:         "/flatten?order=column",
+			fileContent: "1,2,3\n4,5,6\n",
+			expected:    "1,4,2,5,3,6\n",
+		},
+		{
+			name:        "Cells Kept As Written",
+			url:         "/flatten?order=column",
+			fileContent: "004,+5\n1,-0\n",
+			expected:    "004,1,+5,-0\n",
+		},
+		{
+			name:        "Streamed Cells Kept As Written",
+			url:         "/flatten?stream=true",
+			fileContent: "004,+5\n1,-0\n",
+			expected:    "004,+5,1,-0\n",
+		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			req := httptest.NewRequest("POST", "/flatten", bytes.NewBufferString(tt.fileContent))
+			if tt.url == "" {
+				tt.url = "/flatten"
+			}
+			req := httptest.NewRequest("POST", tt.url, bytes.NewBufferString(tt.fileContent))
 			req.Header.Set("Content-Type", "multipart/form-data")
 
 			// Create a form file to simulate file upload
@@ -329,3 +358,326 @@ func TestMultiplyHandler(t *testing.T) {
 		})
 	}
 }
+
+func TestValidateHandler(t *testing.T) {
+	tests := []struct {
+		name        string
+		fileContent string
+		expected    []string
+	}{
+		{
+			name:        "Valid Matrix",
+			fileContent: "1,2,3\n4,5,6\n",
+			expected:    []string{`"valid":true`, `"rows":2`, `"cols":3`},
+		},
+		{
+			name:        "Every Problem Reported",
+			fileContent: "1,x\n2\n3,y\n",
+			expected: []string{
+				`"valid":false`,
+				`invalid number at position [0,1]`,
+				`row 1 has 1 fields, expected 2`,
+				`invalid number at position [2,1]`,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			form := new(bytes.Buffer)
+			writer := multipart.NewWriter(form)
+			fileWriter, _ := writer.CreateFormFile("file", "test.csv")
+			fileWriter.Write([]byte(tt.fileContent))
+			writer.Close()
+
+			req := httptest.NewRequest("POST", "/validate", form)
+			req.Header.Set("Content-Type", writer.FormDataContentType())
+
+			rr := httptest.NewRecorder()
+			controller.ValidateHandler(rr, req)
+
+			if rr.Code != http.StatusOK {
+				t.Errorf("expected status OK; got %v", rr.Code)
+			}
+			for _, expected := range tt.expected {
+				if !strings.Contains(rr.Body.String(), expected) {
+					t.Errorf("expected body to contain %q; got %q", expected, rr.Body.String())
+				}
+			}
+		})
+	}
+}
+
+func TestDomainHandlers(t *testing.T) {
+	tests := []struct {
+		name     string
+		handler  http.HandlerFunc
+		url      string
+		files    map[string]string
+		expected string
+	}{
+		{
+			name:     "Sum in prime field",
+			handler:  controller.SumHandler,
+			url:      "/sum?domain=gf:7",
+			files:    map[string]string{"file": "1,2,3\n4,5,6\n"},
+			expected: "0\n",
+		},
+		{
+			name:     "Multiply overflows int64",
+			handler:  controller.MultiplyHandler,
+			url:      "/multiply?domain=int64",
+			files:    map[string]string{"file": "9223372036854775807,2\n"},
+			expected: "error integer overflow",
+		},
+		{
+			name:     "Unknown domain",
+			handler:  controller.SumHandler,
+			url:      "/sum?domain=octonion",
+			files:    map[string]string{"file": "1\n"},
+			expected: `error unknown domain "octonion"`,
+		},
+		{
+			name:     "Matrix multiplication",
+			handler:  controller.MatMulHandler,
+			url:      "/matmul",
+			files:    map[string]string{"a": "1,2\n3,4\n", "b": "0,1\n1,0\n"},
+			expected: "2,1\n4,3\n",
+		},
+		{
+			name:     "Matrix multiplication missing operand",
+			handler:  controller.MatMulHandler,
+			url:      "/matmul",
+			files:    map[string]string{"a": "1,2\n3,4\n"},
+			expected: "error http: no such file",
+		},
+		{
+			name:     "Determinant",
+			handler:  controller.DeterminantHandler,
+			url:      "/determinant",
+			files:    map[string]string{"file": "1,2\n3,4\n"},
+			expected: "-2\n",
+		},
+		{
+			name:     "Inverse defaults to rational",
+			handler:  controller.InverseHandler,
+			url:      "/inverse",
+			files:    map[string]string{"file": "1,2\n3,4\n"},
+			expected: "-2,1\n3/2,-1/2\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			form := new(bytes.Buffer)
+			writer := multipart.NewWriter(form)
+			for field, content := range tt.files {
+				fileWriter, _ := writer.CreateFormFile(field, "test.csv")
+				fileWriter.Write([]byte(content))
+			}
+			writer.Close()
+
+			req := httptest.NewRequest("POST", tt.url, form)
+			req.Header.Set("Content-Type", writer.FormDataContentType())
+
+			rr := httptest.NewRecorder()
+			tt.handler(rr, req)
+
+			if !strings.HasPrefix(rr.Body.String(), tt.expected) {
+				t.Errorf("expected body %q; got %q", tt.expected, rr.Body.String())
+			}
+		})
+	}
+}
+
+func TestStreamingHandlers(t *testing.T) {
+	tests := []struct {
+		name     string
+		handler  http.HandlerFunc
+		url      string
+		content  string
+		expected string
+	}{
+		{
+			name:     "Streamed sum",
+			handler:  controller.SumHandler,
+			url:      "/sum?stream=true",
+			content:  "1,2,3\n4,5,6\n",
+			expected: "21\n",
+		},
+		{
+			name:     "Streamed multiply",
+			handler:  controller.MultiplyHandler,
+			url:      "/multiply?stream=true",
+			content:  "1,2,3\n4,5,6\n",
+			expected: "720\n",
+		},
+		{
+			name:     "Streamed flatten",
+			handler:  controller.FlattenHandler,
+			url:      "/flatten?stream=true",
+			content:  "1,2,3\n4,5,6\n",
+			expected: "1,2,3,4,5,6\n",
+		},
+		{
+			name:     "Streamed flatten with invalid cell",
+			handler:  controller.FlattenHandler,
+			url:      "/flatten?stream=true",
+			content:  "1,2\n3,x\n",
+			expected: "1,2,3\nerror invalid number at position [1,1]",
+		},
+		{
+			name:     "Streamed flatten in column order",
+			handler:  controller.FlattenHandler,
+			url:      "/flatten?stream=true&order=column",
+			content:  "1,2\n3,4\n",
+			expected: "error order=column is not supported with stream=true\n",
+		},
+		{
+			name:     "Streamed stats",
+			handler:  controller.StatsHandler,
+			url:      "/stats?stream=true",
+			content:  "1,2\n3,4\n",
+			expected: `{"rows":2,"cols":2,"count":4,"sum":"10","min":"1","max":"4","mean":"2.5"}` + "\n",
+		},
+		{
+			name:     "Stats",
+			handler:  controller.StatsHandler,
+			url:      "/stats",
+			content:  "1,2\n3,4\n",
+			expected: `{"rows":2,"cols":2,"count":4,"sum":"10","min":"1","max":"4","mean":"2.5"}` + "\n",
+		},
+		{
+			name:     "Streamed sum with schema",
+			handler:  controller.SumHandler,
+			url:      "/sum?stream=true&schema=positive",
+			content:  "1\n",
+			expected: "error schema is not supported with stream=true",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			form := new(bytes.Buffer)
+			writer := multipart.NewWriter(form)
+			fileWriter, _ := writer.CreateFormFile("file", "test.csv")
+			fileWriter.Write([]byte(tt.content))
+			writer.Close()
+
+			req := httptest.NewRequest("POST", tt.url, form)
+			req.Header.Set("Content-Type", writer.FormDataContentType())
+
+			rr := httptest.NewRecorder()
+			tt.handler(rr, req)
+
+			if rr.Body.String() != tt.expected {
+				t.Errorf("expected body %q; got %q", tt.expected, rr.Body.String())
+			}
+		})
+	}
+}
+
+func TestStreamingMissingFile(t *testing.T) {
+	form := new(bytes.Buffer)
+	writer := multipart.NewWriter(form)
+	writer.WriteField("other", "1,2")
+	writer.Close()
+
+	req := httptest.NewRequest("POST", "/sum?stream=true", form)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+
+	rr := httptest.NewRecorder()
+	controller.SumHandler(rr, req)
+
+	if rr.Body.String() != "error http: no such file" {
+		t.Errorf("expected missing file error; got %q", rr.Body.String())
+	}
+}
+
+func TestInvertHandlerExternal(t *testing.T) {
+	controller.ExternalTranspose.MemoryBudget = 1
+	controller.ExternalTranspose.TempDir = t.TempDir()
+	defer func() { controller.ExternalTranspose = matrix.ExternalOptions{} }()
+
+	form := new(bytes.Buffer)
+	writer := multipart.NewWriter(form)
+	fileWriter, _ := writer.CreateFormFile("file", "test.csv")
+	fileWriter.Write([]byte("1,2,3\n4,5,6\n"))
+	writer.Close()
+
+	req := httptest.NewRequest("POST", "/invert?external=true", form)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+
+	rr := httptest.NewRecorder()
+	controller.InvertHandler(rr, req)
+
+	if rr.Body.String() != "1,4\n2,5\n3,6\n" {
+		t.Errorf("expected transposed matrix; got %q", rr.Body.String())
+	}
+}
+
+func TestMultiplyHandlerCancelled(t *testing.T) {
+	form := new(bytes.Buffer)
+	writer := multipart.NewWriter(form)
+	fileWriter, _ := writer.CreateFormFile("file", "test.csv")
+	fileWriter.Write([]byte("1,2\n3,4\n"))
+	writer.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	req := httptest.NewRequestWithContext(ctx, "POST", "/multiply", form)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+
+	rr := httptest.NewRecorder()
+	controller.MultiplyHandler(rr, req)
+
+	if rr.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected status %d; got %d", http.StatusServiceUnavailable, rr.Code)
+	}
+}
+
+func TestHandlersTimeOut(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		url     string
+		fields  []string
+	}{
+		{name: "Invert", handler: controller.InvertHandler, url: "/invert", fields: []string{"file"}},
+		{name: "Invert External", handler: controller.InvertHandler, url: "/invert?external=true", fields: []string{"file"}},
+		{name: "Flatten", handler: controller.FlattenHandler, url: "/flatten", fields: []string{"file"}},
+		{name: "Sum", handler: controller.SumHandler, url: "/sum", fields: []string{"file"}},
+		{name: "Sum Streamed", handler: controller.SumHandler, url: "/sum?stream=true", fields: []string{"file"}},
+		{name: "Multiply", handler: controller.MultiplyHandler, url: "/multiply", fields: []string{"file"}},
+		{name: "Multiply In Domain", handler: controller.MultiplyHandler, url: "/multiply?domain=rational", fields: []string{"file"}},
+		{name: "MatMul", handler: controller.MatMulHandler, url: "/matmul", fields: []string{"a", "b"}},
+		{name: "Determinant", handler: controller.DeterminantHandler, url: "/determinant", fields: []string{"file"}},
+		{name: "Inverse", handler: controller.InverseHandler, url: "/inverse", fields: []string{"file"}},
+		{name: "Stats", handler: controller.StatsHandler, url: "/stats", fields: []string{"file"}},
+		{name: "Validate", handler: controller.ValidateHandler, url: "/validate", fields: []string{"file"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			form := new(bytes.Buffer)
+			writer := multipart.NewWriter(form)
+			for _, field := range tt.fields {
+				fileWriter, _ := writer.CreateFormFile(field, "test.csv")
+				fileWriter.Write([]byte("1,2\n3,4\n"))
+			}
+			writer.Close()
+
+			ctx, cancel := context.WithTimeout(context.Background(), 0)
+			defer cancel()
+			req := httptest.NewRequestWithContext(ctx, "POST", tt.url, form)
+			req.Header.Set("Content-Type", writer.FormDataContentType())
+
+			rr := httptest.NewRecorder()
+			tt.handler(rr, req)
+
+			if rr.Code != http.StatusGatewayTimeout {
+				t.Errorf("expected status %d; got %d %q", http.StatusGatewayTimeout, rr.Code, rr.Body.String())
+			}
+		})
+	}
+}
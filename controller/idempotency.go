@@ -0,0 +1,311 @@
+package controller
+
+import (
+	"bytes"
+	"container/list"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"log/slog"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// IdempotencyKeyHeader names the header by which clients make retries of a
+// POST safe.
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// Idempotency answers a POST repeated with the same Idempotency-Key header
+// from the response stored for the first one, so a client retrying after a
+// timeout does not start the same computation twice. A repeated key must come
+// with the same request, path, query and uploaded content, otherwise it is
+// rejected with 422. A duplicate arriving while the first request is still
+// running waits for it.
+//
+// Responses are buffered and kept in memory for the window, the oldest
+// forgotten first once MaxEntries or MaxBytes is reached. A first request
+// cancelled by its client, that panicked, that failed with a 5xx such as a
+// passed deadline, or whose response is larger than MaxBytes, is not stored
+// and the next duplicate runs again. A body larger than MaxBytes is passed on
+// as it streams, without the header applying to it.
+type Idempotency struct {
+	opts IdempotencyOptions
+
+	mu        sync.Mutex
+	requests  map[string]*idempotentRequest
+	stored    *list.List
+	bytes     int64
+	lastSweep time.Time
+}
+
+// IdempotencyOptions configures an Idempotency.
+type IdempotencyOptions struct {
+	// Window is how long a response is replayed.
+	Window time.Duration
+	// MaxEntries and MaxBytes bound the responses kept, 0 for no bound.
+	// MaxBytes bounds the request bodies buffered to be fingerprinted too.
+	MaxEntries int
+	MaxBytes   int64
+}
+
+// idempotentRequest is the first request seen with a key. done is closed
+// once response is set, or once the request is discarded.
+type idempotentRequest struct {
+	key         string
+	fingerprint [sha256.Size]byte
+	done        chan struct{}
+	response    *responseRecorder
+	expires     time.Time
+	element     *list.Element
+}
+
+// NewIdempotency returns an Idempotency keeping responses as opts says.
+func NewIdempotency(opts IdempotencyOptions) *Idempotency {
+	return &Idempotency{opts: opts, requests: map[string]*idempotentRequest{}, stored: list.New()}
+}
+
+// Handler applies the Idempotency-Key header to the POST requests of next.
+func (id *Idempotency) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(IdempotencyKeyHeader)
+		if r.Method != http.MethodPost || key == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		body, err := readBody(r.Body, id.opts.MaxBytes)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		if id.opts.MaxBytes > 0 && int64(len(body)) > id.opts.MaxBytes {
+			slog.Debug("request too large to be idempotent", "key", key, "path", r.URL.Path)
+			r.Body = readCloser{io.MultiReader(bytes.NewReader(body), r.Body), r.Body}
+			next.ServeHTTP(w, r)
+			return
+		}
+		fingerprint := requestFingerprint(r, body)
+
+		for {
+			first, running := id.claim(key, fingerprint)
+			if first == nil {
+				httpError(w, fmt.Sprintf("%s %q was used for a different request", IdempotencyKeyHeader, key), http.StatusUnprocessableEntity)
+				return
+			}
+
+			if !running {
+				r.Body = io.NopCloser(bytes.NewReader(body))
+				id.run(first, key, next, w, r)
+				return
+			}
+
+			select {
+			case <-first.done:
+			case <-r.Context().Done():
+				writeError(w, r.Context().Err())
+				return
+			}
+			if first.response != nil {
+				w.Header().Set("Idempotent-Replayed", "true")
+				first.response.replay(w)
+				return
+			}
+			// The first request was cancelled, try to run this one.
+		}
+	})
+}
+
+// claim returns the request already registered for key and true, or registers
+// a new one and returns it with false. It returns nil when the key belongs to
+// a different request.
+func (id *Idempotency) claim(key string, fingerprint [sha256.Size]byte) (*idempotentRequest, bool) {
+	id.mu.Lock()
+	defer id.mu.Unlock()
+
+	now := time.Now()
+	id.sweep(now)
+	if first, ok := id.requests[key]; ok {
+		if first.expires.IsZero() || now.Before(first.expires) {
+			if first.fingerprint != fingerprint {
+				return nil, false
+			}
+			return first, true
+		}
+		id.forget(first)
+	}
+
+	first := &idempotentRequest{key: key, fingerprint: fingerprint, done: make(chan struct{})}
+	id.requests[key] = first
+	return first, false
+}
+
+// run serves the first request with a key and stores its response for the
+// duplicates.
+func (id *Idempotency) run(first *idempotentRequest, key string, next http.Handler, w http.ResponseWriter, r *http.Request) {
+	response := &cappedRecorder{responseRecorder: newResponseRecorder(), w: w, max: id.opts.MaxBytes}
+	completed := false
+	defer func() {
+		id.mu.Lock()
+		defer id.mu.Unlock()
+		if !completed || r.Context().Err() != nil || response.passedOn || response.status() >= http.StatusInternalServerError {
+			delete(id.requests, key)
+		} else {
+			first.response = response.responseRecorder
+			first.expires = time.Now().Add(id.opts.Window)
+			id.store(first)
+		}
+		close(first.done)
+	}()
+
+	next.ServeHTTP(response, r)
+	completed = true
+	if response.passedOn {
+		slog.Debug("idempotent response too large to be stored", "key", key, "path", r.URL.Path)
+		return
+	}
+	response.replay(w)
+	slog.Debug("idempotent request stored", "key", key, "path", r.URL.Path, "status", response.status())
+}
+
+// store keeps the response of first, forgetting the oldest ones beyond the
+// bounds. The caller holds id.mu.
+func (id *Idempotency) store(first *idempotentRequest) {
+	first.element = id.stored.PushBack(first)
+	id.bytes += int64(first.response.body.Len())
+	for id.stored.Len() > 0 && (id.opts.MaxEntries > 0 && id.stored.Len() > id.opts.MaxEntries || id.opts.MaxBytes > 0 && id.bytes > id.opts.MaxBytes) {
+		id.forget(id.stored.Front().Value.(*idempotentRequest))
+	}
+}
+
+// forget removes a stored response. The caller holds id.mu.
+func (id *Idempotency) forget(first *idempotentRequest) {
+	id.stored.Remove(first.element)
+	id.bytes -= int64(first.response.body.Len())
+	delete(id.requests, first.key)
+}
+
+// sweep forgets the expired responses, at most four times per window. They
+// are stored in the order they expire.
+func (id *Idempotency) sweep(now time.Time) {
+	if now.Sub(id.lastSweep) < id.opts.Window/4 {
+		return
+	}
+	id.lastSweep = now
+	for id.stored.Len() > 0 {
+		first := id.stored.Front().Value.(*idempotentRequest)
+		if now.Before(first.expires) {
+			break
+		}
+		id.forget(first)
+	}
+}
+
+// readBody reads body up to one byte more than max, all of it when max is 0.
+func readBody(body io.Reader, max int64) ([]byte, error) {
+	if max <= 0 {
+		return io.ReadAll(body)
+	}
+	return io.ReadAll(io.LimitReader(body, max+1))
+}
+
+// cappedRecorder records a response until it grows larger than max, then
+// passes it on to w as it is written, so that a large result is neither
+// kept in memory nor delayed.
+type cappedRecorder struct {
+	*responseRecorder
+	w        http.ResponseWriter
+	max      int64
+	passedOn bool
+}
+
+func (cr *cappedRecorder) Write(p []byte) (int, error) {
+	if cr.passedOn {
+		return cr.w.Write(p)
+	}
+	if cr.max > 0 && int64(cr.body.Len()+len(p)) > cr.max {
+		cr.passedOn = true
+		cr.replay(cr.w)
+		return cr.w.Write(p)
+	}
+	return cr.responseRecorder.Write(p)
+}
+
+// Fail records the failure, and tells w of it once the response is passed
+// on.
+func (cr *cappedRecorder) Fail(err error) {
+	cr.responseRecorder.Fail(err)
+	if cr.passedOn {
+		fail(cr.w, err)
+	}
+}
+
+// FlushError flushes w once the response is passed on, and does nothing
+// while it is recorded.
+func (cr *cappedRecorder) FlushError() error {
+	if !cr.passedOn {
+		return nil
+	}
+	return http.NewResponseController(cr.w).Flush()
+}
+
+// requestFingerprint hashes what makes two requests the same: the path, the
+// query and the content. Multipart bodies are hashed part by part, so the
+// random boundary a client picks for each attempt does not matter.
+func requestFingerprint(r *http.Request, body []byte) [sha256.Size]byte {
+	h := sha256.New()
+	writeField(h, r.URL.Path)
+	writeField(h, r.URL.Query().Encode())
+
+	if !hashMultipart(h, r.Header.Get("Content-Type"), body) {
+		writeField(h, r.Header.Get("Content-Type"))
+		writeField(h, string(body))
+	}
+
+	var fingerprint [sha256.Size]byte
+	h.Sum(fingerprint[:0])
+	return fingerprint
+}
+
+// hashMultipart hashes the name, file name and content of every part of a
+// multipart body. It reports false if the body is not multipart.
+func hashMultipart(h hash.Hash, contentType string, body []byte) bool {
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil || mediaType != "multipart/form-data" || params["boundary"] == "" {
+		return false
+	}
+
+	parts := multipart.NewReader(bytes.NewReader(body), params["boundary"])
+	scratch := sha256.New()
+	scratch.Write([]byte("multipart"))
+	for {
+		part, err := parts.NextRawPart()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return false
+		}
+		content, err := io.ReadAll(part)
+		if err != nil {
+			return false
+		}
+		writeField(scratch, part.FormName())
+		writeField(scratch, part.FileName())
+		writeField(scratch, string(content))
+	}
+	h.Write(scratch.Sum(nil))
+	return true
+}
+
+// writeField writes s to h prefixed with its length, so fields cannot run
+// into each other.
+func writeField(h hash.Hash, s string) {
+	binary.Write(h, binary.BigEndian, uint64(len(s)))
+	io.WriteString(h, s)
+}
@@ -0,0 +1,314 @@
+package controller_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"league/main/controller"
+)
+
+// countingHandler answers how many times it ran, after waiting for release
+// when it is not nil.
+func countingHandler(calls *atomic.Int64, release chan struct{}) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		call := calls.Add(1)
+		if release != nil {
+			select {
+			case <-release:
+			case <-r.Context().Done():
+				http.Error(w, "error request cancelled", http.StatusServiceUnavailable)
+				return
+			}
+		}
+		fmt.Fprintf(w, "call %d\n", call)
+	})
+}
+
+// idempotentRequest uploads content with a fresh multipart boundary.
+func idempotentRequest(url, key, content string) *http.Request {
+	form := new(bytes.Buffer)
+	writer := multipart.NewWriter(form)
+	fileWriter, _ := writer.CreateFormFile("file", "test.csv")
+	fileWriter.Write([]byte(content))
+	writer.Close()
+
+	req := httptest.NewRequest("POST", url, form)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	if key != "" {
+		req.Header.Set(controller.IdempotencyKeyHeader, key)
+	}
+	return req
+}
+
+func TestIdempotency(t *testing.T) {
+	tests := []struct {
+		name           string
+		first, second  *http.Request
+		expectedStatus int
+		expectedBody   string
+		expectedCalls  int64
+	}{
+		{
+			name:           "Same Request Replayed",
+			first:          idempotentRequest("/sum", "a", "1,2\n"),
+			second:         idempotentRequest("/sum", "a", "1,2\n"),
+			expectedStatus: http.StatusOK,
+			expectedBody:   "call 1\n",
+			expectedCalls:  1,
+		},
+		{
+			name:           "Different Content",
+			first:          idempotentRequest("/sum", "a", "1,2\n"),
+			second:         idempotentRequest("/sum", "a", "1,3\n"),
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedBody:   "error Idempotency-Key \"a\" was used for a different request\n",
+			expectedCalls:  1,
+		},
+		{
+			name:           "Different Operation",
+			first:          idempotentRequest("/sum", "a", "1,2\n"),
+			second:         idempotentRequest("/multiply", "a", "1,2\n"),
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedBody:   "error Idempotency-Key \"a\" was used for a different request\n",
+			expectedCalls:  1,
+		},
+		{
+			name:           "Different Parameters",
+			first:          idempotentRequest("/sum?domain=bigint", "a", "1,2\n"),
+			second:         idempotentRequest("/sum?domain=rational", "a", "1,2\n"),
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedBody:   "error Idempotency-Key \"a\" was used for a different request\n",
+			expectedCalls:  1,
+		},
+		{
+			name:           "Different Keys",
+			first:          idempotentRequest("/sum", "a", "1,2\n"),
+			second:         idempotentRequest("/sum", "b", "1,2\n"),
+			expectedStatus: http.StatusOK,
+			expectedBody:   "call 2\n",
+			expectedCalls:  2,
+		},
+		{
+			name:           "No Key",
+			first:          idempotentRequest("/sum", "", "1,2\n"),
+			second:         idempotentRequest("/sum", "", "1,2\n"),
+			expectedStatus: http.StatusOK,
+			expectedBody:   "call 2\n",
+			expectedCalls:  2,
+		},
+		{
+			name:           "Not A POST",
+			first:          httptest.NewRequest("GET", "/jobs/1", nil),
+			second:         httptest.NewRequest("GET", "/jobs/1", nil),
+			expectedStatus: http.StatusOK,
+			expectedBody:   "call 2\n",
+			expectedCalls:  2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls atomic.Int64
+			handler := controller.NewIdempotency(controller.IdempotencyOptions{Window: time.Hour}).Handler(countingHandler(&calls, nil))
+
+			handler.ServeHTTP(httptest.NewRecorder(), tt.first)
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, tt.second)
+
+			if rr.Code != tt.expectedStatus {
+				t.Errorf("expected status %d; got %d", tt.expectedStatus, rr.Code)
+			}
+			if rr.Body.String() != tt.expectedBody {
+				t.Errorf("expected %q; got %q", tt.expectedBody, rr.Body.String())
+			}
+			if calls.Load() != tt.expectedCalls {
+				t.Errorf("handler ran %d times, want %d", calls.Load(), tt.expectedCalls)
+			}
+		})
+	}
+}
+
+func TestIdempotencyConcurrentDuplicates(t *testing.T) {
+	var calls atomic.Int64
+	release := make(chan struct{})
+	handler := controller.NewIdempotency(controller.IdempotencyOptions{Window: time.Hour}).Handler(countingHandler(&calls, release))
+
+	var wg sync.WaitGroup
+	responses := make([]*httptest.ResponseRecorder, 5)
+	for i := range responses {
+		responses[i] = httptest.NewRecorder()
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			handler.ServeHTTP(responses[i], idempotentRequest("/sum", "a", "1,2\n"))
+		}()
+	}
+	for calls.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if calls.Load() != 1 {
+		t.Errorf("handler ran %d times, want 1", calls.Load())
+	}
+	replayed := 0
+	for _, rr := range responses {
+		if rr.Code != http.StatusOK || rr.Body.String() != "call 1\n" {
+			t.Errorf("response = %d %q, want %d %q", rr.Code, rr.Body.String(), http.StatusOK, "call 1\n")
+		}
+		if rr.Header().Get("Idempotent-Replayed") == "true" {
+			replayed++
+		}
+	}
+	if replayed != len(responses)-1 {
+		t.Errorf("%d responses replayed, want %d", replayed, len(responses)-1)
+	}
+}
+
+func TestIdempotencyCancelledRequestIsNotStored(t *testing.T) {
+	var calls atomic.Int64
+	release := make(chan struct{})
+	handler := controller.NewIdempotency(controller.IdempotencyOptions{Window: time.Hour}).Handler(countingHandler(&calls, release))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	first := httptest.NewRecorder()
+	handler.ServeHTTP(first, idempotentRequest("/sum", "a", "1,2\n").WithContext(ctx))
+	if first.Code != http.StatusServiceUnavailable {
+		t.Errorf("cancelled request answered %d, want %d", first.Code, http.StatusServiceUnavailable)
+	}
+
+	close(release)
+	retry := httptest.NewRecorder()
+	handler.ServeHTTP(retry, idempotentRequest("/sum", "a", "1,2\n"))
+	if retry.Code != http.StatusOK || retry.Body.String() != "call 2\n" {
+		t.Errorf("retry = %d %q, want %d %q", retry.Code, retry.Body.String(), http.StatusOK, "call 2\n")
+	}
+}
+
+func TestIdempotencyWindow(t *testing.T) {
+	var calls atomic.Int64
+	handler := controller.NewIdempotency(controller.IdempotencyOptions{Window: 10 * time.Millisecond}).Handler(countingHandler(&calls, nil))
+
+	handler.ServeHTTP(httptest.NewRecorder(), idempotentRequest("/sum", "a", "1,2\n"))
+	time.Sleep(20 * time.Millisecond)
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, idempotentRequest("/sum", "a", "1,3\n"))
+
+	if rr.Code != http.StatusOK || rr.Body.String() != "call 2\n" {
+		t.Errorf("response after the window = %d %q, want %d %q", rr.Code, rr.Body.String(), http.StatusOK, "call 2\n")
+	}
+}
+
+func TestIdempotentJobSubmission(t *testing.T) {
+	q := newJobQueue(t, controller.JobOptions{}, map[string]http.HandlerFunc{"sum": controller.SumHandler})
+	handler := controller.NewIdempotency(controller.IdempotencyOptions{Window: time.Hour}).Handler(q)
+
+	first := httptest.NewRecorder()
+	handler.ServeHTTP(first, idempotentRequest("/jobs?operation=sum", "a", "1,2\n"))
+	retry := httptest.NewRecorder()
+	handler.ServeHTTP(retry, idempotentRequest("/jobs?operation=sum", "a", "1,2\n"))
+
+	if first.Code != http.StatusAccepted || retry.Header().Get("Location") != first.Header().Get("Location") {
+		t.Errorf("retry submitted job %q, want %q", retry.Header().Get("Location"), first.Header().Get("Location"))
+	}
+}
+
+func TestIdempotencyServerErrorIsNotStored(t *testing.T) {
+	var calls atomic.Int64
+	handler := controller.NewIdempotency(controller.IdempotencyOptions{Window: time.Hour}).Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) == 1 {
+			http.Error(w, "error operation timed out", http.StatusGatewayTimeout)
+			return
+		}
+		fmt.Fprintln(w, "3")
+	}))
+
+	handler.ServeHTTP(httptest.NewRecorder(), idempotentRequest("/sum", "a", "1,2\n"))
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, idempotentRequest("/sum", "a", "1,2\n"))
+
+	if rr.Code != http.StatusOK || rr.Body.String() != "3\n" || calls.Load() != 2 {
+		t.Errorf("retry after a timeout = %d %q after %d calls, want it run again", rr.Code, rr.Body.String(), calls.Load())
+	}
+}
+
+func TestIdempotencyBounds(t *testing.T) {
+	large := strings.Repeat("1,2\n", 500)
+	tests := []struct {
+		name          string
+		opts          controller.IdempotencyOptions
+		handler       http.Handler
+		first, second *http.Request
+		expectedBody  string
+		expectedCalls int64
+	}{
+		{
+			name: "Oldest Forgotten Beyond MaxEntries",
+			opts: controller.IdempotencyOptions{Window: time.Hour, MaxEntries: 2},
+			// b and c push a out before it is retried.
+			first:         idempotentRequest("/sum", "a", "1,2\n"),
+			second:        idempotentRequest("/sum", "a", "1,2\n"),
+			expectedBody:  "call 4\n",
+			expectedCalls: 4,
+		},
+		{
+			name:          "Large Response Not Stored",
+			opts:          controller.IdempotencyOptions{Window: time.Hour, MaxBytes: 1000},
+			handler:       http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { io.Copy(w, strings.NewReader(large)) }),
+			first:         idempotentRequest("/invert", "a", "1,2\n"),
+			second:        idempotentRequest("/invert", "a", "1,2\n"),
+			expectedBody:  large,
+			expectedCalls: 2,
+		},
+		{
+			name:          "Large Body Passed On",
+			opts:          controller.IdempotencyOptions{Window: time.Hour, MaxBytes: 1000},
+			first:         idempotentRequest("/sum", "a", large),
+			second:        idempotentRequest("/sum", "a", large+"3,4\n"),
+			expectedBody:  "call 2\n",
+			expectedCalls: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls atomic.Int64
+			next := countingHandler(&calls, nil)
+			if tt.handler != nil {
+				next = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					calls.Add(1)
+					tt.handler.ServeHTTP(w, r)
+				})
+			}
+			handler := controller.NewIdempotency(tt.opts).Handler(next)
+
+			handler.ServeHTTP(httptest.NewRecorder(), tt.first)
+			if tt.opts.MaxEntries > 0 {
+				handler.ServeHTTP(httptest.NewRecorder(), idempotentRequest("/sum", "b", "1,2\n"))
+				handler.ServeHTTP(httptest.NewRecorder(), idempotentRequest("/sum", "c", "1,2\n"))
+			}
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, tt.second)
+
+			if rr.Body.String() != tt.expectedBody {
+				t.Errorf("expected %q; got %q", tt.expectedBody, rr.Body.String())
+			}
+			if calls.Load() != tt.expectedCalls {
+				t.Errorf("handler ran %d times, want %d", calls.Load(), tt.expectedCalls)
+			}
+		})
+	}
+}
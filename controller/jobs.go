@@ -0,0 +1,451 @@
+package controller
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"league/main/matrix"
+	"log/slog"
+	"maps"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// JobOptions configures a JobQueue.
+type JobOptions struct {
+	// Workers bounds the jobs running at once.
+	Workers int
+	// QueueSize bounds the jobs waiting for a worker, submissions beyond it
+	// answer 503.
+	QueueSize int
+	// TTL is how long a finished job and its result are kept.
+	TTL time.Duration
+	// Timeout is the deadline of every job, 0 for none.
+	Timeout time.Duration
+}
+
+// JobStatus is the state of a job.
+type JobStatus string
+
+const (
+	JobQueued    JobStatus = "queued"
+	JobRunning   JobStatus = "running"
+	JobSucceeded JobStatus = "succeeded"
+	JobFailed    JobStatus = "failed"
+	JobCancelled JobStatus = "cancelled"
+)
+
+// JobProgress is the last progress reported by a running job: done out of
+// total units of the stage it is in, such as "parse" or "matmul".
+type JobProgress struct {
+	Stage string `json:"stage"`
+	Done  int    `json:"done"`
+	Total int    `json:"total"`
+}
+
+// JobInfo is the status of a job as reported by /jobs.
+type JobInfo struct {
+	ID        string       `json:"id"`
+	Operation string       `json:"operation"`
+	Status    JobStatus    `json:"status"`
+	Progress  *JobProgress `json:"progress,omitempty"`
+	Error     string       `json:"error,omitempty"`
+	Created   time.Time    `json:"created"`
+	Started   *time.Time   `json:"started,omitempty"`
+	Finished  *time.Time   `json:"finished,omitempty"`
+	Expires   *time.Time   `json:"expires,omitempty"`
+}
+
+// JobQueue runs operations asynchronously for clients that cannot wait for a
+// long computation on one connection. POST /jobs?operation=<name> takes the
+// same body and parameters as the operation itself and answers 202 with the
+// job, GET /jobs/{id} reports its status and progress, GET /jobs/{id}/result
+// answers what the operation would have answered and DELETE /jobs/{id}
+// cancels the job, or forgets it once finished.
+//
+// Jobs run the operation handlers on a copy of the submitted request, so they
+// behave exactly like the synchronous endpoints. Uploads and results are kept
+// in memory until the job expires.
+type JobQueue struct {
+	opts       JobOptions
+	operations map[string]http.HandlerFunc
+	routes     *http.ServeMux
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	queue  chan *job
+	wg     sync.WaitGroup
+
+	mu   sync.Mutex
+	jobs map[string]*job
+	// pending counts the jobs queued or running.
+	pending int
+}
+
+// NewJobQueue starts the workers of a queue running the given operations,
+// keyed by name. Close stops them.
+func NewJobQueue(opts JobOptions, operations map[string]http.HandlerFunc) *JobQueue {
+	ctx, cancel := context.WithCancel(context.Background())
+	q := &JobQueue{
+		opts:       opts,
+		operations: operations,
+		ctx:        ctx,
+		cancel:     cancel,
+		queue:      make(chan *job, opts.Workers+opts.QueueSize),
+		jobs:       map[string]*job{},
+	}
+
+	q.routes = http.NewServeMux()
+	q.routes.HandleFunc("POST /jobs", q.submit)
+	q.routes.HandleFunc("GET /jobs/{id}", q.status)
+	q.routes.HandleFunc("GET /jobs/{id}/result", q.result)
+	q.routes.HandleFunc("DELETE /jobs/{id}", q.delete)
+
+	q.wg.Add(opts.Workers + 1)
+	for range opts.Workers {
+		go q.work()
+	}
+	go q.expire()
+	return q
+}
+
+func (q *JobQueue) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	q.routes.ServeHTTP(w, r)
+}
+
+// Close cancels the running jobs and waits for the workers to stop. Jobs
+// still queued are never run.
+func (q *JobQueue) Close() {
+	q.cancel()
+	q.wg.Wait()
+}
+
+// job is one submitted operation. The request fields are set on submission,
+// the others are guarded by mu.
+type job struct {
+	id        string
+	operation string
+	handler   http.HandlerFunc
+	query     url.Values
+	header    http.Header
+	body      []byte
+	ctx       context.Context
+	cancel    context.CancelFunc
+
+	mu       sync.Mutex
+	status   JobStatus
+	progress *JobProgress
+	err      string
+	created  time.Time
+	started  time.Time
+	finished time.Time
+	response *responseRecorder
+}
+
+func (q *JobQueue) submit(w http.ResponseWriter, r *http.Request) {
+	name := r.URL.Query().Get("operation")
+	handler, ok := q.operations[name]
+	if !ok {
+		names := slices.Sorted(maps.Keys(q.operations))
+		httpError(w, fmt.Sprintf("unknown operation %q, expected one of %s", name, strings.Join(names, ", ")), http.StatusBadRequest)
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	id, err := newJobID()
+	if err != nil {
+		httpError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	query := r.URL.Query()
+	query.Del("operation")
+	ctx, cancel := context.WithCancel(q.ctx)
+	j := &job{
+		id:        id,
+		operation: name,
+		handler:   handler,
+		query:     query,
+		header:    r.Header.Clone(),
+		body:      body,
+		ctx:       ctx,
+		cancel:    cancel,
+		status:    JobQueued,
+		created:   time.Now(),
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.ctx.Err() != nil {
+		cancel()
+		httpError(w, "the job queue is shutting down", http.StatusServiceUnavailable)
+		return
+	}
+	if q.pending == cap(q.queue) {
+		cancel()
+		httpError(w, "the job queue is full", http.StatusServiceUnavailable)
+		return
+	}
+	q.pending++
+	q.queue <- j
+	q.jobs[j.id] = j
+	slog.Debug("job queued", "id", j.id, "operation", name)
+
+	w.Header().Set("Location", basePath(r)+"/jobs/"+j.id)
+	writeJob(w, http.StatusAccepted, j.info(q.opts.TTL))
+}
+
+func (q *JobQueue) status(w http.ResponseWriter, r *http.Request) {
+	j, ok := q.lookup(w, r)
+	if !ok {
+		return
+	}
+	writeJob(w, http.StatusOK, j.info(q.opts.TTL))
+}
+
+// result answers the response of the operation once the job finished, even
+// when it failed, so clients see the error the operation reported.
+func (q *JobQueue) result(w http.ResponseWriter, r *http.Request) {
+	j, ok := q.lookup(w, r)
+	if !ok {
+		return
+	}
+
+	j.mu.Lock()
+	status, response := j.status, j.response
+	j.mu.Unlock()
+	if status != JobSucceeded && status != JobFailed {
+		httpError(w, fmt.Sprintf("job %s is %s", j.id, status), http.StatusConflict)
+		return
+	}
+
+	response.replay(w)
+}
+
+// delete cancels a queued or running job, and forgets a finished one.
+func (q *JobQueue) delete(w http.ResponseWriter, r *http.Request) {
+	j, ok := q.lookup(w, r)
+	if !ok {
+		return
+	}
+
+	j.mu.Lock()
+	finished := j.status != JobQueued && j.status != JobRunning
+	if !finished {
+		j.status = JobCancelled
+		j.finished = time.Now()
+		j.progress = nil
+		j.body = nil
+	}
+	j.mu.Unlock()
+	j.cancel()
+
+	if finished {
+		q.mu.Lock()
+		delete(q.jobs, j.id)
+		q.mu.Unlock()
+	}
+
+	writeJob(w, http.StatusOK, j.info(q.opts.TTL))
+}
+
+// lookup returns the job named in the path, answering 404 if there is none
+// or it expired.
+func (q *JobQueue) lookup(w http.ResponseWriter, r *http.Request) (*job, bool) {
+	id := r.PathValue("id")
+	q.mu.Lock()
+	j, ok := q.jobs[id]
+	q.mu.Unlock()
+	if !ok || j.expired(time.Now(), q.opts.TTL) {
+		httpError(w, fmt.Sprintf("unknown job %q", id), http.StatusNotFound)
+		return nil, false
+	}
+	return j, true
+}
+
+func (q *JobQueue) work() {
+	defer q.wg.Done()
+	for {
+		select {
+		case <-q.ctx.Done():
+			return
+		case j := <-q.queue:
+			q.run(j)
+			q.mu.Lock()
+			q.pending--
+			q.mu.Unlock()
+		}
+	}
+}
+
+// run runs the operation of j on a copy of the submitted request.
+func (q *JobQueue) run(j *job) {
+	defer j.cancel()
+
+	j.mu.Lock()
+	if j.status != JobQueued {
+		j.mu.Unlock()
+		return
+	}
+	j.status = JobRunning
+	j.started = time.Now()
+	body := j.body
+	j.mu.Unlock()
+
+	ctx := matrix.WithProgress(j.ctx, j.report)
+	if q.opts.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, q.opts.Timeout)
+		defer cancel()
+	}
+
+	target := "/" + j.operation
+	if len(j.query) > 0 {
+		target += "?" + j.query.Encode()
+	}
+	response := newResponseRecorder()
+	r, err := http.NewRequestWithContext(ctx, http.MethodPost, target, bytes.NewReader(body))
+	if err == nil {
+		r.Header = j.header
+		err = serveJob(j.handler, response, r)
+	}
+	if err != nil {
+		slog.Error("job failed", "id", j.id, "operation", j.operation, "error", err)
+		response = newResponseRecorder()
+		httpError(response, err.Error(), http.StatusInternalServerError)
+	}
+	j.finish(response, response.failure())
+	slog.Debug("job finished", "id", j.id, "operation", j.operation, "status", j.status)
+}
+
+// serveJob runs handler, turning a panic into an error as net/http does for
+// the synchronous endpoints.
+func serveJob(handler http.HandlerFunc, w http.ResponseWriter, r *http.Request) (err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("internal error: %v", p)
+		}
+	}()
+	handler(w, r)
+	return nil
+}
+
+// report records the progress of the running job.
+func (j *job) report(stage string, done, total int) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.status == JobRunning {
+		j.progress = &JobProgress{Stage: stage, Done: done, Total: total}
+	}
+}
+
+// finish records the response of the operation and the error it reported,
+// unless the job was cancelled meanwhile.
+func (j *job) finish(response *responseRecorder, failure string) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.status != JobRunning {
+		return
+	}
+
+	j.status = JobSucceeded
+	if failure != "" {
+		j.status = JobFailed
+		j.err = failure
+	}
+	j.response = response
+	j.progress = nil
+	j.finished = time.Now()
+	j.body = nil
+}
+
+func (j *job) expired(now time.Time, ttl time.Duration) bool {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return !j.finished.IsZero() && now.Sub(j.finished) > ttl
+}
+
+func (j *job) info(ttl time.Duration) JobInfo {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	info := JobInfo{
+		ID:        j.id,
+		Operation: j.operation,
+		Status:    j.status,
+		Progress:  j.progress,
+		Error:     j.err,
+		Created:   j.created,
+	}
+	if started := j.started; !started.IsZero() {
+		info.Started = &started
+	}
+	if finished := j.finished; !finished.IsZero() {
+		expires := finished.Add(ttl)
+		info.Finished = &finished
+		info.Expires = &expires
+	}
+	return info
+}
+
+// expire forgets the jobs finished for longer than the TTL.
+func (q *JobQueue) expire() {
+	defer q.wg.Done()
+	ticker := time.NewTicker(max(q.opts.TTL/4, time.Second))
+	defer ticker.Stop()
+	for {
+		select {
+		case <-q.ctx.Done():
+			return
+		case now := <-ticker.C:
+			q.mu.Lock()
+			for id, j := range q.jobs {
+				if j.expired(now, q.opts.TTL) {
+					delete(q.jobs, id)
+				}
+			}
+			q.mu.Unlock()
+		}
+	}
+}
+
+func newJobID() (string, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return "", fmt.Errorf("generating a job id: %w", err)
+	}
+	return hex.EncodeToString(id), nil
+}
+
+func writeJob(w http.ResponseWriter, status int, info JobInfo) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(info)
+}
+
+// failure returns the error the operation reported, "" if it succeeded. Like
+// the synchronous endpoints, an operation can fail with a 200 status, so the
+// body is not enough to tell.
+func (rr *responseRecorder) failure() string {
+	switch {
+	case rr.err != nil:
+		return rr.err.Error()
+	case rr.status() >= http.StatusBadRequest:
+		// An error status tells the failure, the body is only its message.
+		return strings.TrimPrefix(strings.TrimSpace(rr.body.String()), "error ")
+	}
+	return ""
+}
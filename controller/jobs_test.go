@@ -0,0 +1,343 @@
+package controller_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"league/main/controller"
+	"league/main/matrix"
+)
+
+// blockingOperation answers "done" once release is closed, or the error of
+// its context.
+func blockingOperation(release chan struct{}) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+			fmt.Fprint(w, "done")
+		case <-r.Context().Done():
+			http.Error(w, "error "+r.Context().Err().Error(), http.StatusServiceUnavailable)
+		}
+	}
+}
+
+func newJobQueue(t *testing.T, opts controller.JobOptions, operations map[string]http.HandlerFunc) *controller.JobQueue {
+	t.Helper()
+	if opts.Workers == 0 {
+		opts.Workers = 2
+	}
+	if opts.TTL == 0 {
+		opts.TTL = time.Hour
+	}
+	q := controller.NewJobQueue(opts, operations)
+	t.Cleanup(q.Close)
+	return q
+}
+
+func submitJob(t *testing.T, q *controller.JobQueue, query, content string) *httptest.ResponseRecorder {
+	t.Helper()
+	form := new(bytes.Buffer)
+	writer := multipart.NewWriter(form)
+	fileWriter, _ := writer.CreateFormFile("file", "test.csv")
+	fileWriter.Write([]byte(content))
+	writer.Close()
+
+	req := httptest.NewRequest("POST", "/jobs?"+query, form)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	rr := httptest.NewRecorder()
+	q.ServeHTTP(rr, req)
+	return rr
+}
+
+func submittedJob(t *testing.T, q *controller.JobQueue, query, content string) controller.JobInfo {
+	t.Helper()
+	rr := submitJob(t, q, query, content)
+	if rr.Code != http.StatusAccepted {
+		t.Fatalf("POST /jobs status = %d, want %d: %s", rr.Code, http.StatusAccepted, rr.Body.String())
+	}
+	var info controller.JobInfo
+	if err := json.Unmarshal(rr.Body.Bytes(), &info); err != nil {
+		t.Fatal(err)
+	}
+	if location := rr.Header().Get("Location"); location != "/jobs/"+info.ID {
+		t.Errorf("Location = %q, want %q", location, "/jobs/"+info.ID)
+	}
+	return info
+}
+
+func jobRequest(q *controller.JobQueue, method, path string) *httptest.ResponseRecorder {
+	rr := httptest.NewRecorder()
+	q.ServeHTTP(rr, httptest.NewRequest(method, path, nil))
+	return rr
+}
+
+// waitForJob polls the job until done returns true for its status.
+func waitForJob(t *testing.T, q *controller.JobQueue, id string, done func(controller.JobInfo) bool) controller.JobInfo {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		rr := jobRequest(q, "GET", "/jobs/"+id)
+		var info controller.JobInfo
+		if rr.Code == http.StatusOK {
+			json.Unmarshal(rr.Body.Bytes(), &info)
+			if done(info) {
+				return info
+			}
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("job %s: last status %d %s", id, rr.Code, rr.Body.String())
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func hasStatus(status controller.JobStatus) func(controller.JobInfo) bool {
+	return func(info controller.JobInfo) bool { return info.Status == status }
+}
+
+func TestJobResults(t *testing.T) {
+	q := newJobQueue(t, controller.JobOptions{}, map[string]http.HandlerFunc{
+		"sum":      controller.SumHandler,
+		"invert":   controller.InvertHandler,
+		"multiply": controller.MultiplyHandler,
+		"echo":     controller.EchoHandler,
+	})
+
+	tests := []struct {
+		name           string
+		query          string
+		content        string
+		expectedStatus controller.JobStatus
+		expectedError  string
+		expectedCode   int
+		expectedBody   string
+	}{
+		{
+			name:           "Sum",
+			query:          "operation=sum",
+			content:        "1,2,3\n4,5,6\n",
+			expectedStatus: controller.JobSucceeded,
+			expectedCode:   http.StatusOK,
+			expectedBody:   "21\n",
+		},
+		{
+			name:           "Parameters Are Passed On",
+			query:          "operation=multiply&domain=gf:7",
+			content:        "3,5\n",
+			expectedStatus: controller.JobSucceeded,
+			expectedCode:   http.StatusOK,
+			expectedBody:   "1\n",
+		},
+		{
+			name:           "Invert",
+			query:          "operation=invert",
+			content:        "1,2\n3,4\n",
+			expectedStatus: controller.JobSucceeded,
+			expectedCode:   http.StatusOK,
+			expectedBody:   "1,3\n2,4\n",
+		},
+		{
+			name:           "Result Like An Error",
+			query:          "operation=echo",
+			content:        "error x,1\n2,3\n",
+			expectedStatus: controller.JobSucceeded,
+			expectedCode:   http.StatusOK,
+			expectedBody:   "error x,1\n2,3\n",
+		},
+		{
+			name:           "Invalid Matrix",
+			query:          "operation=sum",
+			content:        "1,a\n",
+			expectedStatus: controller.JobFailed,
+			expectedError:  "invalid number at position [0,1]",
+			expectedCode:   http.StatusOK,
+			expectedBody:   "error invalid number at position [0,1]",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			submitted := submittedJob(t, q, tt.query, tt.content)
+			info := waitForJob(t, q, submitted.ID, func(info controller.JobInfo) bool { return info.Finished != nil })
+
+			if info.Status != tt.expectedStatus || info.Error != tt.expectedError {
+				t.Errorf("job = %s %q, want %s %q", info.Status, info.Error, tt.expectedStatus, tt.expectedError)
+			}
+			if info.Started == nil || info.Expires == nil || info.Progress != nil {
+				t.Errorf("job = %+v, want started, expiring and without progress", info)
+			}
+
+			rr := jobRequest(q, "GET", "/jobs/"+info.ID+"/result")
+			if rr.Code != tt.expectedCode {
+				t.Errorf("expected status %d; got %d", tt.expectedCode, rr.Code)
+			}
+			if rr.Body.String() != tt.expectedBody {
+				t.Errorf("expected %q; got %q", tt.expectedBody, rr.Body.String())
+			}
+		})
+	}
+}
+
+func TestJobRequests(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	q := newJobQueue(t, controller.JobOptions{}, map[string]http.HandlerFunc{
+		"sum":   controller.SumHandler,
+		"block": blockingOperation(release),
+	})
+	running := submittedJob(t, q, "operation=block", "1\n")
+	waitForJob(t, q, running.ID, hasStatus(controller.JobRunning))
+
+	tests := []struct {
+		name           string
+		method         string
+		path           string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "Unknown Job",
+			method:         "GET",
+			path:           "/jobs/nope",
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   "error unknown job \"nope\"\n",
+		},
+		{
+			name:           "Result Of Running Job",
+			method:         "GET",
+			path:           "/jobs/" + running.ID + "/result",
+			expectedStatus: http.StatusConflict,
+			expectedBody:   "error job " + running.ID + " is running\n",
+		},
+		{
+			name:           "Unknown Operation",
+			method:         "POST",
+			path:           "/jobs?operation=power",
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   "error unknown operation \"power\", expected one of block, sum\n",
+		},
+		{
+			name:           "Method Not Allowed",
+			method:         "PUT",
+			path:           "/jobs/" + running.ID,
+			expectedStatus: http.StatusMethodNotAllowed,
+			expectedBody:   "Method Not Allowed\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := jobRequest(q, tt.method, tt.path)
+			if rr.Code != tt.expectedStatus {
+				t.Errorf("expected status %d; got %d", tt.expectedStatus, rr.Code)
+			}
+			if rr.Body.String() != tt.expectedBody {
+				t.Errorf("expected %q; got %q", tt.expectedBody, rr.Body.String())
+			}
+		})
+	}
+}
+
+func TestJobCancel(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	q := newJobQueue(t, controller.JobOptions{Workers: 1, QueueSize: 1}, map[string]http.HandlerFunc{
+		"block": blockingOperation(release),
+	})
+
+	running := submittedJob(t, q, "operation=block", "1\n")
+	waitForJob(t, q, running.ID, hasStatus(controller.JobRunning))
+	queued := submittedJob(t, q, "operation=block", "1\n")
+	if queued.Status != controller.JobQueued {
+		t.Errorf("second job is %s, want %s", queued.Status, controller.JobQueued)
+	}
+	if rr := submitJob(t, q, "operation=block", "1\n"); rr.Code != http.StatusServiceUnavailable {
+		t.Errorf("submitting to a full queue answered %d, want %d", rr.Code, http.StatusServiceUnavailable)
+	}
+
+	for _, id := range []string{running.ID, queued.ID} {
+		rr := jobRequest(q, "DELETE", "/jobs/"+id)
+		var info controller.JobInfo
+		json.Unmarshal(rr.Body.Bytes(), &info)
+		if rr.Code != http.StatusOK || info.Status != controller.JobCancelled {
+			t.Errorf("DELETE /jobs/%s = %d %s, want %d %s", id, rr.Code, info.Status, http.StatusOK, controller.JobCancelled)
+		}
+		if rr := jobRequest(q, "GET", "/jobs/"+id+"/result"); rr.Code != http.StatusConflict {
+			t.Errorf("result of cancelled job answered %d, want %d", rr.Code, http.StatusConflict)
+		}
+	}
+
+	// The queue frees up once the running job noticed the cancellation.
+	deadline := time.Now().Add(5 * time.Second)
+	for submitJob(t, q, "operation=block", "1\n").Code != http.StatusAccepted {
+		if time.Now().After(deadline) {
+			t.Fatal("cancelled jobs still fill the queue")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if info := waitForJob(t, q, queued.ID, func(controller.JobInfo) bool { return true }); info.Status != controller.JobCancelled {
+		t.Errorf("cancelled queued job is %s", info.Status)
+	}
+}
+
+func TestJobProgress(t *testing.T) {
+	release := make(chan struct{})
+	q := newJobQueue(t, controller.JobOptions{}, map[string]http.HandlerFunc{
+		"parse": func(w http.ResponseWriter, r *http.Request) {
+			records := [][]string{{"1", "2"}, {"3", "4"}, {"5", "6"}}
+			if _, err := matrix.ParseContext(r.Context(), records); err != nil {
+				t.Error(err)
+			}
+			blockingOperation(release)(w, r)
+		},
+	})
+
+	job := submittedJob(t, q, "operation=parse", "1\n")
+	info := waitForJob(t, q, job.ID, func(info controller.JobInfo) bool { return info.Progress != nil })
+	if expected := (controller.JobProgress{Stage: "parse", Done: 3, Total: 3}); *info.Progress != expected {
+		t.Errorf("progress = %+v, want %+v", *info.Progress, expected)
+	}
+
+	close(release)
+	waitForJob(t, q, job.ID, hasStatus(controller.JobSucceeded))
+}
+
+func TestJobTimeout(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	q := newJobQueue(t, controller.JobOptions{Timeout: 20 * time.Millisecond}, map[string]http.HandlerFunc{
+		"block": blockingOperation(release),
+	})
+
+	job := submittedJob(t, q, "operation=block", "1\n")
+	info := waitForJob(t, q, job.ID, func(info controller.JobInfo) bool { return info.Finished != nil })
+	if info.Status != controller.JobFailed || info.Error != "context deadline exceeded" {
+		t.Errorf("job = %s %q, want %s %q", info.Status, info.Error, controller.JobFailed, "context deadline exceeded")
+	}
+}
+
+func TestJobExpires(t *testing.T) {
+	q := newJobQueue(t, controller.JobOptions{TTL: 20 * time.Millisecond}, map[string]http.HandlerFunc{
+		"sum": controller.SumHandler,
+	})
+
+	job := submittedJob(t, q, "operation=sum", "1\n")
+	waitForJob(t, q, job.ID, hasStatus(controller.JobSucceeded))
+
+	deadline := time.Now().Add(5 * time.Second)
+	for jobRequest(q, "GET", "/jobs/"+job.ID).Code != http.StatusNotFound {
+		if time.Now().After(deadline) {
+			t.Fatal("job did not expire")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if rr := jobRequest(q, "GET", "/jobs/"+job.ID+"/result"); rr.Code != http.StatusNotFound {
+		t.Errorf("result of expired job answered %d, want %d", rr.Code, http.StatusNotFound)
+	}
+}
@@ -0,0 +1,169 @@
+package controller_test
+
+import (
+	"bytes"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"league/main/controller"
+	"league/main/matrix"
+)
+
+func TestLimits(t *testing.T) {
+	controller.Limits = matrix.Limits{MaxRows: 3, MaxCols: 3, MaxDigits: 5, MaxResultDigits: 10}
+	defer func() { controller.Limits = matrix.Limits{} }()
+
+	tests := []struct {
+		name           string
+		handler        http.HandlerFunc
+		url            string
+		content        string
+		maxBytes       int64
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "Within Limits",
+			handler:        controller.SumHandler,
+			url:            "/sum",
+			content:        "1,2,3\n4,5,6\n",
+			expectedStatus: http.StatusOK,
+			expectedBody:   "21\n",
+		},
+		{
+			name:           "Too Many Rows",
+			handler:        controller.EchoHandler,
+			url:            "/echo",
+			content:        "1\n2\n3\n4\n",
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedBody:   "error too many rows: 4, the limit is 3\n",
+		},
+		{
+			name:           "Too Many Columns",
+			handler:        controller.SumHandler,
+			url:            "/sum",
+			content:        "1,2,3,4\n",
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedBody:   "error too many columns: 4, the limit is 3\n",
+		},
+		{
+			name:           "Too Many Digits",
+			handler:        controller.SumHandler,
+			url:            "/sum",
+			content:        "1,-123456\n",
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedBody:   "error too many digits at position [0,1]: 6, the limit is 5\n",
+		},
+		{
+			name:           "Product Too Large",
+			handler:        controller.MultiplyHandler,
+			url:            "/multiply",
+			content:        "12345,12345\n12345,1\n",
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedBody:   "error too many digits in the product: 16, the limit is 10\n",
+		},
+		{
+			name:           "Sum Not Bound By Product Limit",
+			handler:        controller.SumHandler,
+			url:            "/sum",
+			content:        "12345,12345\n12345,1\n",
+			expectedStatus: http.StatusOK,
+			expectedBody:   "37036\n",
+		},
+		{
+			name:           "Product With Zero",
+			handler:        controller.MultiplyHandler,
+			url:            "/multiply",
+			content:        "12345,12345\n12345,0\n",
+			expectedStatus: http.StatusOK,
+			expectedBody:   "0\n",
+		},
+		{
+			name:           "Body Too Large",
+			handler:        controller.SumHandler,
+			url:            "/sum",
+			content:        strings.Repeat("1,2\n", 1000),
+			maxBytes:       1000,
+			expectedStatus: http.StatusRequestEntityTooLarge,
+			expectedBody:   "error request body larger than 1000 bytes\n",
+		},
+		{
+			name:           "Streamed Body Too Large",
+			handler:        controller.SumHandler,
+			url:            "/sum?stream=true",
+			content:        "1,2\n3,4\n",
+			maxBytes:       100,
+			expectedStatus: http.StatusRequestEntityTooLarge,
+			expectedBody:   "error request body larger than 100 bytes\n",
+		},
+		{
+			name:           "Streamed Too Many Digits",
+			handler:        controller.MultiplyHandler,
+			url:            "/multiply?stream=true",
+			content:        "1,2\n3," + strings.Repeat("9", 1000) + "\n",
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedBody:   "error too many digits at position [1,1]: 1000, the limit is 5\n",
+		},
+		{
+			name:           "Streamed Product Too Large",
+			handler:        controller.MultiplyHandler,
+			url:            "/multiply?stream=true",
+			content:        "12345,12345\n12345,1\n",
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedBody:   "error too many digits in the product: 15, the limit is 10\n",
+		},
+		{
+			name:           "Streamed Too Many Rows",
+			handler:        controller.StatsHandler,
+			url:            "/stats?stream=true",
+			content:        "1\n2\n3\n4\n",
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedBody:   "error too many rows: 4, the limit is 3\n",
+		},
+		{
+			name:           "External Too Many Columns",
+			handler:        controller.InvertHandler,
+			url:            "/invert?external=true",
+			content:        "1,2,3,4\n",
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedBody:   "error too many columns: 4, the limit is 3\n",
+		},
+		{
+			name:           "Streamed Flatten Too Many Digits",
+			handler:        controller.FlattenHandler,
+			url:            "/flatten?stream=true",
+			content:        "123456\n",
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedBody:   "error too many digits at position [0,0]: 6, the limit is 5\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			form := new(bytes.Buffer)
+			writer := multipart.NewWriter(form)
+			fileWriter, _ := writer.CreateFormFile("file", "test.csv")
+			fileWriter.Write([]byte(tt.content))
+			writer.Close()
+
+			req := httptest.NewRequest("POST", tt.url, form)
+			req.Header.Set("Content-Type", writer.FormDataContentType())
+
+			rr := httptest.NewRecorder()
+			if tt.maxBytes > 0 {
+				req.Body = http.MaxBytesReader(rr, req.Body, tt.maxBytes)
+			}
+			tt.handler(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Errorf("expected status %d; got %d", tt.expectedStatus, rr.Code)
+			}
+			if rr.Body.String() != tt.expectedBody {
+				t.Errorf("expected %q; got %q", tt.expectedBody, rr.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,146 @@
+package controller
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"league/main/matrix"
+	"league/main/store"
+	"net/http"
+)
+
+// Matrices keeps the matrices uploaded to /matrices. Operations then take
+// matrix=<id> in place of the file upload, and matrix_a=<id> or
+// matrix_b=<id> in place of the a and b uploads of /matmul. nil disables
+// storage.
+var Matrices store.Store
+
+var matrixRoutes = func() *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /matrices", putMatrix)
+	mux.HandleFunc("GET /matrices", listMatrices)
+	mux.HandleFunc("GET /matrices/{id}", getMatrix)
+	mux.HandleFunc("DELETE /matrices/{id}", deleteMatrix)
+	return mux
+}()
+
+// MatricesHandler stores the matrix uploaded as file on POST /matrices and
+// answers its ID, lists the stored matrices on GET /matrices, and returns or
+// deletes one on GET and DELETE /matrices/{id}.
+func MatricesHandler(w http.ResponseWriter, r *http.Request) {
+	if Matrices == nil {
+		httpError(w, "matrix storage is disabled", http.StatusNotFound)
+		return
+	}
+	matrixRoutes.ServeHTTP(w, r)
+}
+
+// putMatrix streams the upload to the store without buffering it.
+func putMatrix(w http.ResponseWriter, r *http.Request) {
+	reader, err := r.MultipartReader()
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			err = http.ErrMissingFile
+		}
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		if part.FormName() != "file" {
+			continue
+		}
+
+		info, err := Matrices.Put(r.Context(), part.FileName(), part)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		w.Header().Set("Location", basePath(r)+"/matrices/"+info.ID)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(info)
+		return
+	}
+}
+
+func listMatrices(w http.ResponseWriter, r *http.Request) {
+	infos, err := Matrices.List(r.Context())
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string][]store.Info{"matrices": infos})
+}
+
+func getMatrix(w http.ResponseWriter, r *http.Request) {
+	file, _, err := Matrices.Open(r.Context(), r.PathValue("id"))
+	if err != nil {
+		writeStoreError(w, r.PathValue("id"), err)
+		return
+	}
+	defer file.Close()
+
+	w.Header().Set("Content-Type", "text/csv")
+	io.Copy(w, file)
+}
+
+func deleteMatrix(w http.ResponseWriter, r *http.Request) {
+	if err := Matrices.Delete(r.Context(), r.PathValue("id")); err != nil {
+		writeStoreError(w, r.PathValue("id"), err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// writeStoreError answers 404 for an unknown matrix and reports any other
+// error like writeError.
+func writeStoreError(w http.ResponseWriter, id string, err error) {
+	if errors.Is(err, store.ErrNotFound) {
+		httpError(w, fmt.Sprintf("unknown matrix %q", id), http.StatusNotFound)
+		return
+	}
+	writeError(w, err)
+}
+
+// storedMatrixParam is the query parameter referencing a stored matrix in
+// place of the upload field.
+func storedMatrixParam(field string) string {
+	if field == "file" {
+		return "matrix"
+	}
+	return "matrix_" + field
+}
+
+// openStoredMatrix opens the stored matrix the request references in place
+// of the upload field, and reports whether it references one at all.
+func openStoredMatrix(r *http.Request, w http.ResponseWriter, field string) (file io.ReadCloser, referenced, hasError bool) {
+	id := r.URL.Query().Get(storedMatrixParam(field))
+	if id == "" {
+		return nil, false, false
+	}
+	if Matrices == nil {
+		httpError(w, "matrix storage is disabled", http.StatusBadRequest)
+		return nil, true, true
+	}
+
+	file, _, err := Matrices.Open(r.Context(), id)
+	if err != nil {
+		writeStoreError(w, id, err)
+		return nil, true, true
+	}
+	return readCloser{matrix.ContextReader(r.Context(), file), file}, true, false
+}
+
+// readCloser reads from one reader and closes another.
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
@@ -0,0 +1,142 @@
+package controller_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"league/main/controller"
+	"league/main/store"
+)
+
+// useMatrices stores matrices in memory for the duration of the test.
+func useMatrices(t *testing.T) {
+	t.Helper()
+	controller.Matrices = store.NewMemory()
+	t.Cleanup(func() { controller.Matrices = nil })
+}
+
+// putMatrix uploads content to /matrices and returns its ID.
+func putMatrix(t *testing.T, content string) string {
+	t.Helper()
+	rr := httptest.NewRecorder()
+	controller.MatricesHandler(rr, formRequest("/matrices", nil, [2]string{"file", content}))
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("upload status = %d, body %q", rr.Code, rr.Body.String())
+	}
+
+	var info store.Info
+	if err := json.NewDecoder(rr.Body).Decode(&info); err != nil {
+		t.Fatal(err)
+	}
+	if location := rr.Header().Get("Location"); location != "/matrices/"+info.ID {
+		t.Errorf("Location = %q, want /matrices/%s", location, info.ID)
+	}
+	return info.ID
+}
+
+func TestMatrices(t *testing.T) {
+	useMatrices(t)
+	id := putMatrix(t, "1,2\n3,4\n")
+
+	rr := httptest.NewRecorder()
+	controller.MatricesHandler(rr, httptest.NewRequest("GET", "/matrices", nil))
+	var list struct{ Matrices []store.Info }
+	json.NewDecoder(rr.Body).Decode(&list)
+	if len(list.Matrices) != 1 || list.Matrices[0].ID != id || list.Matrices[0].Name != "test.csv" {
+		t.Errorf("list = %+v, want only %s", list.Matrices, id)
+	}
+
+	tests := []struct {
+		name           string
+		method         string
+		url            string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{name: "Get", method: "GET", url: "/matrices/" + id, expectedStatus: http.StatusOK, expectedBody: "1,2\n3,4\n"},
+		{name: "Unknown", method: "GET", url: "/matrices/nope", expectedStatus: http.StatusNotFound, expectedBody: "error unknown matrix \"nope\"\n"},
+		{name: "Wrong Method", method: "PUT", url: "/matrices/" + id, expectedStatus: http.StatusMethodNotAllowed},
+		{name: "Delete", method: "DELETE", url: "/matrices/" + id, expectedStatus: http.StatusNoContent},
+		{name: "Deleted", method: "GET", url: "/matrices/" + id, expectedStatus: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			controller.MatricesHandler(rr, httptest.NewRequest(tt.method, tt.url, nil))
+			if rr.Code != tt.expectedStatus {
+				t.Errorf("status = %d, want %d", rr.Code, tt.expectedStatus)
+			}
+			if tt.expectedBody != "" && rr.Body.String() != tt.expectedBody {
+				t.Errorf("body = %q, want %q", rr.Body.String(), tt.expectedBody)
+			}
+		})
+	}
+}
+
+func TestStoredMatrixOperations(t *testing.T) {
+	useMatrices(t)
+	id := putMatrix(t, "1,2\n3,4\n")
+
+	// Every result must match the one of the same matrices uploaded.
+	tests := []struct {
+		name      string
+		handler   http.HandlerFunc
+		url       string
+		uploadURL string
+		files     [][2]string
+	}{
+		{name: "Sum", handler: controller.SumHandler, url: "/sum?matrix=" + id, uploadURL: "/sum", files: [][2]string{{"file", "1,2\n3,4\n"}}},
+		{name: "Streamed Sum", handler: controller.SumHandler, url: "/sum?stream=true&matrix=" + id, uploadURL: "/sum?stream=true", files: [][2]string{{"file", "1,2\n3,4\n"}}},
+		{name: "Inverse", handler: controller.InverseHandler, url: "/inverse?domain=rational&matrix=" + id, uploadURL: "/inverse?domain=rational", files: [][2]string{{"file", "1,2\n3,4\n"}}},
+		{name: "MatMul", handler: controller.MatMulHandler, url: "/matmul?matrix_a=" + id + "&matrix_b=" + id, uploadURL: "/matmul", files: [][2]string{{"a", "1,2\n3,4\n"}, {"b", "1,2\n3,4\n"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			uploaded := httptest.NewRecorder()
+			tt.handler(uploaded, formRequest(tt.uploadURL, nil, tt.files...))
+
+			stored := httptest.NewRecorder()
+			tt.handler(stored, httptest.NewRequest("POST", tt.url, nil))
+			if stored.Code != http.StatusOK || stored.Body.String() != uploaded.Body.String() {
+				t.Errorf("got %d %q, want %q", stored.Code, stored.Body.String(), uploaded.Body.String())
+			}
+			if strings.HasPrefix(stored.Body.String(), "error") {
+				t.Errorf("body = %q", stored.Body.String())
+			}
+		})
+	}
+
+	errorTests := []struct {
+		name           string
+		url            string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{name: "Unknown Matrix", url: "/sum?matrix=nope", expectedStatus: http.StatusNotFound, expectedBody: "error unknown matrix \"nope\"\n"},
+		{name: "Unknown Streamed Matrix", url: "/sum?stream=true&matrix=nope", expectedStatus: http.StatusNotFound, expectedBody: "error unknown matrix \"nope\"\n"},
+	}
+
+	for _, tt := range errorTests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			controller.SumHandler(rr, httptest.NewRequest("POST", tt.url, nil))
+			if rr.Code != tt.expectedStatus || rr.Body.String() != tt.expectedBody {
+				t.Errorf("got %d %q, want %d %q", rr.Code, rr.Body.String(), tt.expectedStatus, tt.expectedBody)
+			}
+		})
+	}
+
+	t.Run("Storage Disabled", func(t *testing.T) {
+		controller.Matrices = nil
+		rr := httptest.NewRecorder()
+		controller.SumHandler(rr, httptest.NewRequest("POST", "/sum?matrix="+id, nil))
+		if rr.Code != http.StatusBadRequest || rr.Body.String() != "error matrix storage is disabled\n" {
+			t.Errorf("got %d %q", rr.Code, rr.Body.String())
+		}
+	})
+}
@@ -0,0 +1,90 @@
+package controller
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// mediaTypes are the content types of the output kinds.
+var mediaTypes = map[OutputKind]string{
+	OutputMatrix: "text/csv",
+	OutputScalar: "text/plain",
+	OutputJSON:   "application/json",
+}
+
+// OpenAPI returns the OpenAPI 3 description of the operations.
+func OpenAPI(operations []Operation) map[string]any {
+	paths := map[string]any{}
+	for _, op := range operations {
+		success := "the " + string(op.Output) + " result, or an error message starting with \"error \""
+		var parameters []any
+		for _, param := range op.Params {
+			if param.Name == "stream" || param.Name == "external" {
+				success = "the " + string(op.Output) + " result, or an error message starting with \"error \"; " +
+					"a streamed result failing midway ends with the error as a last line starting with \"error \""
+			}
+			schema := map[string]any{"type": "string"}
+			if len(param.Values) > 0 {
+				schema["enum"] = param.Values
+			}
+			parameters = append(parameters, map[string]any{
+				"name": param.Name, "in": "query", "description": param.Description, "schema": schema,
+			})
+		}
+
+		uploads := map[string]any{}
+		for _, field := range op.Inputs {
+			uploads[field] = map[string]any{"type": "string", "format": "binary", "description": "CSV matrix"}
+			parameters = append(parameters, map[string]any{
+				"name": storedMatrixParam(field), "in": "query", "schema": map[string]any{"type": "string"},
+				"description": "ID of a matrix stored with POST " + APIVersion + "/matrices, uploaded as " + field + " otherwise",
+			})
+		}
+
+		paths[APIVersion+"/"+op.Name] = map[string]any{"post": map[string]any{
+			"operationId": op.Name,
+			"summary":     op.Summary,
+			"parameters":  parameters,
+			"requestBody": map[string]any{"content": map[string]any{"multipart/form-data": map[string]any{
+				"schema": map[string]any{"type": "object", "properties": uploads},
+			}}},
+			"responses": map[string]any{
+				"200": map[string]any{
+					"description": success,
+					"content":     map[string]any{mediaTypes[op.Output]: map[string]any{"schema": map[string]any{"type": "string"}}},
+				},
+				"400": errorResponse("invalid parameter"),
+				"404": errorResponse("unknown stored matrix"),
+				"413": errorResponse("upload larger than max_upload_size"),
+				"422": errorResponse("matrix over the limits or not conforming to its schema"),
+				"503": errorResponse("request cancelled"),
+				"504": errorResponse("operation timed out"),
+			},
+		}}
+	}
+
+	return map[string]any{
+		"openapi": "3.0.3",
+		"info":    map[string]any{"title": "League matrix operations", "version": "1"},
+		"paths":   paths,
+	}
+}
+
+func errorResponse(description string) map[string]any {
+	return map[string]any{
+		"description": description,
+		"content":     map[string]any{"text/plain": map[string]any{"schema": map[string]any{"type": "string"}}},
+	}
+}
+
+// OpenAPIHandler answers the OpenAPI description of the operations.
+func OpenAPIHandler(operations []Operation) http.HandlerFunc {
+	document, err := json.Marshal(OpenAPI(operations))
+	if err != nil {
+		panic(err)
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(document)
+	}
+}
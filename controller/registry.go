@@ -0,0 +1,258 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// OutputKind is what an operation answers.
+type OutputKind string
+
+const (
+	// OutputMatrix is a matrix as CSV rows.
+	OutputMatrix OutputKind = "matrix"
+	// OutputScalar is a single value on its own line.
+	OutputScalar OutputKind = "scalar"
+	// OutputJSON is a JSON document.
+	OutputJSON OutputKind = "json"
+)
+
+// Param is a parameter of an operation, read from the query string or a
+// form field.
+type Param struct {
+	Name        string
+	Description string
+	// Values are the accepted values, any when empty. A request with another
+	// value is rejected with 400.
+	Values []string
+}
+
+// Boolean reports whether the parameter is a flag, true or false.
+func (p Param) Boolean() bool {
+	return slices.Equal(p.Values, []string{"true", "false"})
+}
+
+// Func computes an operation on its inputs, read as CSV records in the order
+// of Operation.Inputs, and returns a [][]string for OutputMatrix, a string for
+// OutputScalar or any value encoded for OutputJSON.
+type Func func(ctx context.Context, inputs [][][]string, params url.Values) (any, error)
+
+// Operation declares everything the server, the command line and the
+// pipelines need to know about an operation.
+type Operation struct {
+	Name    string
+	Summary string
+	// Inputs are the form fields of the uploaded matrices, file for most
+	// operations. Each can be replaced by a stored matrix, see Matrices.
+	Inputs []string
+	Params []Param
+	Output OutputKind
+	// Func computes the operation, its handler reading the inputs within
+	// Limits and the schema parameter. Operations streaming their inputs
+	// provide their Handler instead.
+	Func    Func
+	Handler http.HandlerFunc
+}
+
+// Common parameters of the operations.
+var (
+	domainParam = Param{Name: "domain", Description: "number domain: integer (the default, promoted to bigint on overflow), bigint, int64, rational, float64, complex128 or gf:<prime>"}
+	schemaParam = Param{Name: "schema", Description: "name of a registered schema, or inline JSON schema, the matrix must conform to"}
+	streamParam = Param{Name: "stream", Description: "stream the upload instead of reading it in memory", Values: []string{"true", "false"}}
+)
+
+// fileInput is the single upload of most operations.
+var fileInput = []string{"file"}
+
+var registry = struct {
+	sync.RWMutex
+	operations []Operation
+}{operations: []Operation{
+	{
+		Name: "echo", Summary: "Returns the matrix as it was read.",
+		Inputs: fileInput, Params: []Param{schemaParam}, Output: OutputMatrix,
+		Func: func(ctx context.Context, inputs [][][]string, params url.Values) (any, error) { return inputs[0], nil },
+	},
+	{
+		Name: "invert", Summary: "Returns the transposed matrix.",
+		Inputs: fileInput, Output: OutputMatrix, Handler: InvertHandler,
+		Params: []Param{schemaParam, {Name: "external", Description: "transpose a matrix larger than memory through temporary files", Values: []string{"true", "false"}}},
+	},
+	{
+		Name: "flatten", Summary: "Returns the cells of the matrix on one line.",
+		Inputs: fileInput, Output: OutputMatrix, Handler: FlattenHandler,
+		Params: []Param{schemaParam, streamParam, {Name: "order", Description: "order the cells are read in, column is not supported with stream", Values: []string{"row", "column"}}},
+	},
+	{
+		Name: "sum", Summary: "Returns the sum of the cells.",
+		Inputs: fileInput, Params: []Param{schemaParam, streamParam, domainParam}, Output: OutputScalar, Handler: SumHandler,
+	},
+	{
+		Name: "multiply", Summary: "Returns the product of the cells.",
+		Inputs: fileInput, Params: []Param{schemaParam, streamParam, domainParam}, Output: OutputScalar, Handler: MultiplyHandler,
+	},
+	{
+		Name: "matmul", Summary: "Returns the matrix product of a and b.",
+		Inputs: []string{"a", "b"}, Params: []Param{schemaParam, domainParam}, Output: OutputMatrix, Func: matMul,
+	},
+	{
+		Name: "determinant", Summary: "Returns the determinant of a square matrix.",
+		Inputs: fileInput, Params: []Param{schemaParam, domainParam}, Output: OutputScalar, Func: determinant,
+	},
+	{
+		Name: "inverse", Summary: "Returns the inverse of a square matrix, in the rational domain by default.",
+		Inputs: fileInput, Params: []Param{schemaParam, domainParam}, Output: OutputMatrix, Func: inverse,
+	},
+	{
+		Name: "stats", Summary: "Returns the count, sum, minimum, maximum and mean of the cells.",
+		Inputs: fileInput, Params: []Param{schemaParam, streamParam}, Output: OutputJSON, Handler: StatsHandler,
+	},
+	{
+		Name: "validate", Summary: "Reports the shape of the matrix and every cell that is not a number, streaming the upload.",
+		Inputs: fileInput, Output: OutputJSON, Handler: ValidateHandler,
+	},
+}}
+
+// Register adds an operation to the registry, which gives it a route, a
+// command, pipeline support and documentation. It is meant to be called
+// before the server starts, and panics on an invalid or duplicate operation
+// as http.Handle does.
+func Register(op Operation) {
+	if err := op.check(); err != nil {
+		panic(err)
+	}
+	registry.Lock()
+	defer registry.Unlock()
+	if _, ok := lookupOperation(op.Name); ok {
+		panic(fmt.Sprintf("controller: operation %q is registered twice", op.Name))
+	}
+	registry.operations = append(registry.operations, op)
+}
+
+func (op Operation) check() error {
+	switch {
+	case op.Name == "" || strings.ContainsAny(op.Name, "/,? "):
+		return fmt.Errorf("controller: invalid operation name %q", op.Name)
+	case len(op.Inputs) == 0:
+		return fmt.Errorf("controller: operation %s has no input", op.Name)
+	case (op.Func == nil) == (op.Handler == nil):
+		return fmt.Errorf("controller: operation %s needs either a Func or a Handler", op.Name)
+	case op.Output != OutputMatrix && op.Output != OutputScalar && op.Output != OutputJSON:
+		return fmt.Errorf("controller: operation %s has an unknown output kind %q", op.Name, op.Output)
+	}
+	return nil
+}
+
+// Operations returns the registered operations, in the order they were
+// registered.
+func Operations() []Operation {
+	registry.RLock()
+	defer registry.RUnlock()
+	return slices.Clone(registry.operations)
+}
+
+// LookupOperation returns the registered operation of that name.
+func LookupOperation(name string) (Operation, bool) {
+	registry.RLock()
+	defer registry.RUnlock()
+	return lookupOperation(name)
+}
+
+func lookupOperation(name string) (Operation, bool) {
+	i := slices.IndexFunc(registry.operations, func(op Operation) bool { return op.Name == name })
+	if i < 0 {
+		return Operation{}, false
+	}
+	return registry.operations[i], true
+}
+
+// ServeHTTP rejects a parameter outside of its accepted values, then runs
+// the operation. The query string is checked first so that a streamed upload
+// is not parsed ahead of time: a Handler reads the parameters with accepted
+// values from the query only, while a Func, given the form fields as well,
+// has them checked once the form is parsed.
+func (op Operation) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !op.checkParams(w, r.URL.Query()) {
+		return
+	}
+
+	if op.Handler != nil {
+		op.Handler(w, r)
+		return
+	}
+	op.serveFunc(w, r)
+}
+
+// serveFunc reads the inputs, runs Func and answers its result.
+func (op Operation) serveFunc(w http.ResponseWriter, r *http.Request) {
+	inputs := make([][][]string, len(op.Inputs))
+	for i, field := range op.Inputs {
+		records, hasError := readFormFile(r, w, field)
+		if hasError {
+			return
+		}
+		inputs[i] = records
+	}
+	// The parameters may be form fields as well, parsed with the uploads
+	// unless the inputs are stored matrices.
+	if r.Form == nil {
+		r.ParseMultipartForm(defaultMaxMemory)
+	}
+	if !op.checkParams(w, r.Form) {
+		return
+	}
+
+	result, err := op.Func(r.Context(), inputs, r.Form)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	switch op.Output {
+	case OutputMatrix:
+		rows, ok := result.([][]string)
+		if !ok {
+			op.wrongResult(w, result)
+			return
+		}
+		writeRows(w, rows)
+	case OutputScalar:
+		scalar, ok := result.(string)
+		if !ok {
+			op.wrongResult(w, result)
+			return
+		}
+		fmt.Fprint(w, scalar, "\n")
+	case OutputJSON:
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(result)
+	}
+}
+
+// checkParams answers 400 and returns false for a parameter outside of its
+// accepted values.
+func (op Operation) checkParams(w http.ResponseWriter, values url.Values) bool {
+	for _, param := range op.Params {
+		if value := values.Get(param.Name); value != "" && len(param.Values) > 0 && !slices.Contains(param.Values, value) {
+			httpError(w, fmt.Sprintf("invalid %s %q, expected one of %s", param.Name, value, strings.Join(param.Values, ", ")), http.StatusBadRequest)
+			return false
+		}
+	}
+	return true
+}
+
+// wrongResult answers 500 for a Func returning a result of the wrong type
+// for its Output, [][]string for a matrix and string for a scalar.
+func (op Operation) wrongResult(w http.ResponseWriter, result any) {
+	httpError(w, fmt.Sprintf("operation %s returned a %T for a %s output", op.Name, result, op.Output), http.StatusInternalServerError)
+}
+
+// defaultMaxMemory is the part of a multipart form kept in memory, the rest
+// spilling to temporary files, as http.Request.FormFile does.
+const defaultMaxMemory = 32 << 20
@@ -0,0 +1,135 @@
+package controller_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"testing"
+
+	"league/main/controller"
+)
+
+// diagonal is registered once for the whole package, the registry having no
+// way to remove an operation.
+var diagonal = controller.Operation{
+	Name: "diagonal", Summary: "Returns the diagonal of a square matrix.",
+	Inputs: []string{"file"}, Output: controller.OutputMatrix,
+	Params: []controller.Param{{Name: "skip", Description: "skip the first cell", Values: []string{"true", "false"}}},
+	Func: func(ctx context.Context, inputs [][][]string, params url.Values) (any, error) {
+		var diagonal []string
+		for i, row := range inputs[0] {
+			if i > 0 || params.Get("skip") != "true" {
+				diagonal = append(diagonal, row[i])
+			}
+		}
+		return [][]string{diagonal}, nil
+	},
+}
+
+// mistyped returns a scalar for a matrix output.
+var mistyped = controller.Operation{
+	Name: "mistyped", Summary: "Returns a scalar for a matrix.",
+	Inputs: []string{"file"}, Output: controller.OutputMatrix,
+	Func: func(ctx context.Context, inputs [][][]string, params url.Values) (any, error) { return "1", nil },
+}
+
+func init() {
+	controller.Register(diagonal)
+	controller.Register(mistyped)
+}
+
+func TestRegisteredOperation(t *testing.T) {
+	op, ok := controller.LookupOperation("diagonal")
+	if !ok {
+		t.Fatal("diagonal is not registered")
+	}
+
+	tests := []struct {
+		name           string
+		op             controller.Operation
+		url            string
+		values         map[string]string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{name: "Func", url: "/diagonal", expectedStatus: http.StatusOK, expectedBody: "1,4\n"},
+		{name: "Param", url: "/diagonal?skip=true", expectedStatus: http.StatusOK, expectedBody: "4\n"},
+		{name: "Form Param", url: "/diagonal", values: map[string]string{"skip": "true"}, expectedStatus: http.StatusOK, expectedBody: "4\n"},
+		{name: "Invalid Param", url: "/diagonal?skip=maybe", expectedStatus: http.StatusBadRequest, expectedBody: "error invalid skip \"maybe\", expected one of true, false\n"},
+		{name: "Invalid Form Param", url: "/diagonal", values: map[string]string{"skip": "maybe"}, expectedStatus: http.StatusBadRequest, expectedBody: "error invalid skip \"maybe\", expected one of true, false\n"},
+		{name: "Wrong Result Type", op: mistyped, url: "/mistyped", expectedStatus: http.StatusInternalServerError, expectedBody: "error operation mistyped returned a string for a matrix output\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			op := op
+			if tt.op.Name != "" {
+				op = tt.op
+			}
+			rr := httptest.NewRecorder()
+			op.ServeHTTP(rr, formRequest(tt.url, tt.values, [2]string{"file", "1,2\n3,4\n"}))
+			if rr.Code != tt.expectedStatus {
+				t.Errorf("status = %d, want %d", rr.Code, tt.expectedStatus)
+			}
+			if rr.Body.String() != tt.expectedBody {
+				t.Errorf("body = %q, want %q", rr.Body.String(), tt.expectedBody)
+			}
+		})
+	}
+}
+
+func TestRegisterInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		op   controller.Operation
+	}{
+		{name: "Duplicate", op: diagonal},
+		{name: "No Name", op: controller.Operation{Inputs: []string{"file"}, Output: controller.OutputScalar, Func: diagonal.Func}},
+		{name: "No Input", op: controller.Operation{Name: "none", Output: controller.OutputScalar, Func: diagonal.Func}},
+		{name: "No Func", op: controller.Operation{Name: "none", Inputs: []string{"file"}, Output: controller.OutputScalar}},
+		{name: "Unknown Output", op: controller.Operation{Name: "none", Inputs: []string{"file"}, Output: "xml", Func: diagonal.Func}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Error("Register did not panic")
+				}
+			}()
+			controller.Register(tt.op)
+		})
+	}
+}
+
+func TestOpenAPI(t *testing.T) {
+	rr := httptest.NewRecorder()
+	controller.OpenAPIHandler(controller.Operations())(rr, httptest.NewRequest("GET", "/openapi.json", nil))
+
+	var document struct {
+		Paths map[string]struct {
+			Post struct {
+				Parameters []struct{ Name string }
+			}
+		}
+	}
+	if err := json.NewDecoder(rr.Body).Decode(&document); err != nil {
+		t.Fatal(err)
+	}
+	for _, op := range controller.Operations() {
+		if _, ok := document.Paths["/v1/"+op.Name]; !ok {
+			t.Errorf("no path for %s", op.Name)
+		}
+	}
+
+	var params []string
+	for _, param := range document.Paths["/v1/matmul"].Post.Parameters {
+		params = append(params, param.Name)
+	}
+	if want := []string{"schema", "domain", "matrix_a", "matrix_b"}; !slices.Equal(params, want) {
+		t.Errorf("matmul parameters = %v, want %v", params, want)
+	}
+}
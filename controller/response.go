@@ -0,0 +1,215 @@
+package controller
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"league/main/matrix"
+	"maps"
+	"net/http"
+)
+
+// flushBytes is how much output is buffered before it is pushed to the client.
+const flushBytes = 64 << 10
+
+// streamWriter writes the response through a buffer and flushes it to the
+// client every flushBytes, so large responses start arriving immediately and
+// are never assembled in memory.
+type streamWriter struct {
+	w          http.ResponseWriter
+	buffered   *bufio.Writer
+	controller *http.ResponseController
+	unflushed  int
+	written    bool
+	midLine    bool // the output written so far does not end a line
+}
+
+func newStreamWriter(w http.ResponseWriter) *streamWriter {
+	return &streamWriter{
+		w:          w,
+		buffered:   bufio.NewWriterSize(w, flushBytes),
+		controller: http.NewResponseController(w),
+	}
+}
+
+func (sw *streamWriter) Write(p []byte) (int, error) {
+	n, err := sw.buffered.Write(p)
+	sw.unflushed += n
+	if n > 0 {
+		sw.written = true
+		sw.midLine = p[n-1] != '\n'
+	}
+	if err == nil && sw.unflushed >= flushBytes {
+		err = sw.Flush()
+	}
+	return n, err
+}
+
+// WriteRow writes one CSV line.
+func (sw *streamWriter) WriteRow(row []string) error {
+	for j, cell := range row {
+		if j > 0 {
+			sw.buffered.WriteByte(',')
+		}
+		sw.buffered.WriteString(cell)
+		sw.unflushed += len(cell) + 1
+	}
+	sw.buffered.WriteByte('\n')
+	sw.written = true
+	sw.midLine = false
+
+	if sw.unflushed >= flushBytes {
+		return sw.Flush()
+	}
+	return nil
+}
+
+// Error reports err to the client. While nothing has been written it is
+// answered by writeError with a proper status, afterwards it can only be
+// appended to the 200 partial output as a last "error ..." line of its own,
+// the writer being told of the failure.
+func (sw *streamWriter) Error(err error) {
+	if !sw.written {
+		writeError(sw.w, err)
+		return
+	}
+	sw.Flush()
+	fail(sw.w, &OperationError{Status: http.StatusOK, Message: err.Error()})
+	if sw.midLine {
+		sw.buffered.WriteByte('\n')
+	}
+	fmt.Fprintf(sw.buffered, "error %s", err.Error())
+}
+
+// Flush sends the buffered output to the client.
+func (sw *streamWriter) Flush() error {
+	sw.unflushed = 0
+	if err := sw.buffered.Flush(); err != nil {
+		return err
+	}
+
+	// Not every ResponseWriter can flush, the data still goes out when the
+	// handler returns.
+	if err := sw.controller.Flush(); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		return err
+	}
+	return nil
+}
+
+// failer is implemented by the writers that need to know an operation
+// failed. Most failures are answered 200 with an "error ..." body, which
+// valid data may start with as well.
+type failer interface {
+	Fail(err error)
+}
+
+// fail tells w that the operation failed with err, if w wants to know.
+func fail(w http.ResponseWriter, err error) {
+	if f, ok := w.(failer); ok {
+		f.Fail(err)
+	}
+}
+
+// responseRecorder keeps a response in memory, to answer it later or more
+// than once.
+type responseRecorder struct {
+	header http.Header
+	code   int
+	body   bytes.Buffer
+	err    error
+}
+
+func newResponseRecorder() *responseRecorder {
+	return &responseRecorder{header: http.Header{}}
+}
+
+func (rr *responseRecorder) Header() http.Header {
+	return rr.header
+}
+
+func (rr *responseRecorder) WriteHeader(code int) {
+	if rr.code == 0 {
+		rr.code = code
+	}
+}
+
+func (rr *responseRecorder) Write(p []byte) (int, error) {
+	rr.WriteHeader(http.StatusOK)
+	return rr.body.Write(p)
+}
+
+// Fail records the failure of the operation.
+func (rr *responseRecorder) Fail(err error) {
+	if rr.err == nil {
+		rr.err = err
+	}
+}
+
+// failed reports whether the operation failed, by its status or as told by
+// Fail.
+func (rr *responseRecorder) failed() bool {
+	return rr.err != nil || rr.status() >= http.StatusBadRequest
+}
+
+// status returns the recorded status code, 200 when nothing was written.
+func (rr *responseRecorder) status() int {
+	if rr.code == 0 {
+		return http.StatusOK
+	}
+	return rr.code
+}
+
+// replay answers the recorded response, and its failure.
+func (rr *responseRecorder) replay(w http.ResponseWriter) {
+	if rr.err != nil {
+		fail(w, rr.err)
+	}
+	maps.Copy(w.Header(), rr.header)
+	w.WriteHeader(rr.status())
+	w.Write(rr.body.Bytes())
+}
+
+// writeRows streams the rows to the client as CSV.
+func writeRows(w http.ResponseWriter, rows [][]string) {
+	sw := newStreamWriter(w)
+	for _, row := range rows {
+		if err := sw.WriteRow(row); err != nil {
+			return
+		}
+	}
+	sw.Flush()
+}
+
+// writeError reports err to the client. Uploads over the size limit answer
+// 413, matrices over Limits 422, computations cancelled by the client or a
+// server shutdown 503 and computations past their deadline 504; any other
+// error keeps the historical 200 "error ..." body.
+func writeError(w http.ResponseWriter, err error) {
+	var tooLarge *http.MaxBytesError
+	var limit *matrix.LimitError
+	switch {
+	case errors.As(err, &tooLarge):
+		httpError(w, fmt.Sprintf("request body larger than %d bytes", tooLarge.Limit), http.StatusRequestEntityTooLarge)
+	case errors.As(err, &limit):
+		httpError(w, limit.Error(), http.StatusUnprocessableEntity)
+	case errors.Is(err, context.Canceled):
+		httpError(w, "request cancelled", http.StatusServiceUnavailable)
+	case errors.Is(err, context.DeadlineExceeded):
+		httpError(w, "operation timed out", http.StatusGatewayTimeout)
+	default:
+		httpError(w, err.Error(), http.StatusOK)
+	}
+}
+
+// httpError answers "error <message>" with status, and tells w the operation
+// failed. A 200 keeps the historical body, without http.Error's newline.
+func httpError(w http.ResponseWriter, message string, status int) {
+	fail(w, &OperationError{Status: status, Message: message})
+	if status == http.StatusOK {
+		w.Write([]byte("error " + message))
+		return
+	}
+	http.Error(w, "error "+message, status)
+}
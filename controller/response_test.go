@@ -0,0 +1,38 @@
+package controller_test
+
+import (
+	"bytes"
+	"fmt"
+	"mime/multipart"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"league/main/controller"
+)
+
+func TestLargeResponsesAreStreamed(t *testing.T) {
+	var content strings.Builder
+	for i := 0; i < 20000; i++ {
+		fmt.Fprintf(&content, "%d,%d,%d\n", i, i+1, i+2)
+	}
+
+	form := new(bytes.Buffer)
+	writer := multipart.NewWriter(form)
+	fileWriter, _ := writer.CreateFormFile("file", "test.csv")
+	fileWriter.Write([]byte(content.String()))
+	writer.Close()
+
+	req := httptest.NewRequest("POST", "/echo", form)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+
+	rr := httptest.NewRecorder()
+	controller.EchoHandler(rr, req)
+
+	if rr.Body.String() != content.String() {
+		t.Errorf("echoed body differs from the upload")
+	}
+	if !rr.Flushed {
+		t.Errorf("expected the response to be flushed while writing")
+	}
+}
@@ -0,0 +1,155 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// APIVersion prefixes the path of every route.
+const APIVersion = "/v1"
+
+// deprecation is the Deprecation header of the unversioned paths, the date
+// they were deprecated as a structured field date (RFC 9745).
+const deprecation = "@1792368000"
+
+// methods are the methods tried to find the ones a path allows.
+var methods = []string{http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete}
+
+// Route is a method and a versioned path the router serves.
+type Route struct {
+	Method string `json:"method"`
+	Path   string `json:"path"`
+}
+
+// Router serves the routes under APIVersion, answering 405 with the allowed
+// methods to any other method, 404 with a JSON body to an unknown path and
+// OPTIONS with the methods of a path, or every route for APIVersion itself.
+// The unversioned paths remain as deprecated aliases, with a Deprecation
+// header and a Link to their successor.
+//
+// The handlers see the unversioned path in both cases, so that a result is
+// cached and an idempotent request replayed whichever path it came from.
+type Router struct {
+	mux    *http.ServeMux
+	routes []Route
+}
+
+// NewRouter returns a router with no routes.
+func NewRouter() *Router {
+	rt := &Router{mux: http.NewServeMux()}
+	rt.mux.HandleFunc("OPTIONS "+APIVersion+"/{path...}", rt.options)
+	rt.mux.HandleFunc("OPTIONS "+APIVersion, rt.options)
+	return rt
+}
+
+// Handle registers the handler for a "METHOD /path" pattern, the path
+// being unversioned and possibly holding wildcards as in http.ServeMux.
+func (rt *Router) Handle(pattern string, handler http.Handler) {
+	method, path, ok := strings.Cut(pattern, " ")
+	if !ok || method == "" || !strings.HasPrefix(path, "/") {
+		panic(fmt.Sprintf("controller: invalid route %q, expected METHOD /path", pattern))
+	}
+	rt.routes = append(rt.routes, Route{Method: method, Path: APIVersion + path})
+
+	rt.mux.Handle(method+" "+APIVersion+path, unversioned(APIVersion, handler))
+	rt.mux.Handle(pattern, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Deprecation", deprecation)
+		w.Header().Set("Link", fmt.Sprintf("<%s%s>; rel=\"successor-version\"", APIVersion, r.URL.EscapedPath()))
+		handler.ServeHTTP(w, r)
+	}))
+}
+
+// HandleFunc registers the handler function for a "METHOD /path" pattern.
+func (rt *Router) HandleFunc(pattern string, handler http.HandlerFunc) {
+	rt.Handle(pattern, handler)
+}
+
+func (rt *Router) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if _, pattern := rt.mux.Handler(r); pattern != "" {
+		rt.mux.ServeHTTP(w, r)
+		return
+	}
+
+	allowed := rt.allowed(r.URL.Path)
+	if len(allowed) == 0 {
+		writeRouteError(w, http.StatusNotFound, fmt.Sprintf("no route for %s", r.URL.Path))
+		return
+	}
+	w.Header().Set("Allow", strings.Join(allowed, ", "))
+	writeRouteError(w, http.StatusMethodNotAllowed, fmt.Sprintf("method %s not allowed for %s", r.Method, r.URL.Path))
+}
+
+// allowed returns the methods with a route for path, and OPTIONS when a
+// versioned path has any.
+func (rt *Router) allowed(path string) []string {
+	var allowed []string
+	for _, method := range methods {
+		r := &http.Request{Method: method, URL: &url.URL{Path: path}}
+		if _, pattern := rt.mux.Handler(r); pattern != "" {
+			allowed = append(allowed, method)
+		}
+	}
+	if len(allowed) > 0 && strings.HasPrefix(path, APIVersion+"/") {
+		allowed = append(allowed, http.MethodOptions)
+	}
+	return allowed
+}
+
+// options answers the methods of a path in Allow, and the routes as JSON
+// for APIVersion.
+func (rt *Router) options(w http.ResponseWriter, r *http.Request) {
+	if path := strings.TrimSuffix(r.URL.Path, "/"); path == APIVersion {
+		w.Header().Set("Allow", http.MethodOptions)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(struct {
+			Version string  `json:"version"`
+			Routes  []Route `json:"routes"`
+		}{strings.TrimPrefix(APIVersion, "/"), rt.routes})
+		return
+	}
+
+	allowed := rt.allowed(r.URL.Path)
+	if len(allowed) == 0 {
+		writeRouteError(w, http.StatusNotFound, fmt.Sprintf("no route for %s", r.URL.Path))
+		return
+	}
+	w.Header().Set("Allow", strings.Join(allowed, ", "))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// writeRouteError answers a request no route serves. Unlike the operations,
+// whose clients expect an "error ..." line, these errors are JSON.
+func writeRouteError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(struct {
+		Error string `json:"error"`
+	}{message})
+}
+
+type basePathKey struct{}
+
+// unversioned serves handler the path without its version prefix, which the
+// handlers find with basePath to answer URLs in the same version.
+func unversioned(prefix string, handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r2 := r.WithContext(context.WithValue(r.Context(), basePathKey{}, prefix))
+		r2.URL = new(url.URL)
+		*r2.URL = *r.URL
+		r2.URL.Path = strings.TrimPrefix(r.URL.Path, prefix)
+		r2.URL.RawPath = strings.TrimPrefix(r.URL.RawPath, prefix)
+		handler.ServeHTTP(w, r2)
+	})
+}
+
+// basePath returns the prefix the request was routed under, "" for an
+// unversioned path.
+func basePath(r *http.Request) string {
+	prefix, _ := r.Context().Value(basePathKey{}).(string)
+	return prefix
+}
@@ -0,0 +1,103 @@
+package controller_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"league/main/controller"
+)
+
+func newRouter() *controller.Router {
+	router := controller.NewRouter()
+	router.HandleFunc("POST /sum", controller.SumHandler)
+	router.HandleFunc("GET /things/{id}", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, r.URL.Path, r.PathValue("id"))
+	})
+	router.HandleFunc("DELETE /things/{id}", func(w http.ResponseWriter, r *http.Request) {})
+	return router
+}
+
+func TestRouter(t *testing.T) {
+	tests := []struct {
+		name                string
+		method              string
+		url                 string
+		expectedStatus      int
+		expectedBody        string
+		expectedAllow       string
+		expectedDeprecation bool
+	}{
+		{name: "Versioned", method: "POST", url: "/v1/sum", expectedStatus: http.StatusOK, expectedBody: "10\n"},
+		{name: "Legacy Alias", method: "POST", url: "/sum", expectedStatus: http.StatusOK, expectedBody: "10\n", expectedDeprecation: true},
+		{name: "Unversioned Path", method: "GET", url: "/v1/things/42", expectedStatus: http.StatusOK, expectedBody: "/things/42 42\n"},
+		{name: "Method Not Allowed", method: "GET", url: "/v1/sum", expectedStatus: http.StatusMethodNotAllowed, expectedBody: `{"error":"method GET not allowed for /v1/sum"}` + "\n", expectedAllow: "POST, OPTIONS"},
+		{name: "Legacy Method Not Allowed", method: "PUT", url: "/things/42", expectedStatus: http.StatusMethodNotAllowed, expectedBody: `{"error":"method PUT not allowed for /things/42"}` + "\n", expectedAllow: "GET, HEAD, DELETE"},
+		{name: "Not Found", method: "POST", url: "/v1/power", expectedStatus: http.StatusNotFound, expectedBody: `{"error":"no route for /v1/power"}` + "\n"},
+		{name: "Options", method: "OPTIONS", url: "/v1/things/42", expectedStatus: http.StatusNoContent, expectedAllow: "GET, HEAD, DELETE, OPTIONS"},
+		{name: "Options Not Found", method: "OPTIONS", url: "/v1/power", expectedStatus: http.StatusNotFound, expectedBody: `{"error":"no route for /v1/power"}` + "\n"},
+	}
+
+	router := newRouter()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := formRequest(tt.url, nil, [2]string{"file", "1,2\n3,4\n"})
+			req.Method = tt.method
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Errorf("status = %d, want %d", rr.Code, tt.expectedStatus)
+			}
+			if rr.Body.String() != tt.expectedBody {
+				t.Errorf("body = %q, want %q", rr.Body.String(), tt.expectedBody)
+			}
+			if allow := rr.Header().Get("Allow"); allow != tt.expectedAllow {
+				t.Errorf("Allow = %q, want %q", allow, tt.expectedAllow)
+			}
+			if deprecated := rr.Header().Get("Deprecation") != ""; deprecated != tt.expectedDeprecation {
+				t.Errorf("Deprecation = %q", rr.Header().Get("Deprecation"))
+			}
+			if tt.expectedDeprecation && rr.Header().Get("Link") != `</v1`+tt.url+`>; rel="successor-version"` {
+				t.Errorf("Link = %q", rr.Header().Get("Link"))
+			}
+		})
+	}
+}
+
+func TestRouterDiscovery(t *testing.T) {
+	rr := httptest.NewRecorder()
+	newRouter().ServeHTTP(rr, httptest.NewRequest("OPTIONS", "/v1", nil))
+
+	var discovery struct {
+		Version string
+		Routes  []controller.Route
+	}
+	if err := json.NewDecoder(rr.Body).Decode(&discovery); err != nil {
+		t.Fatal(err)
+	}
+	var routes []string
+	for _, route := range discovery.Routes {
+		routes = append(routes, route.Method+" "+route.Path)
+	}
+	if got, want := strings.Join(routes, ", "), "POST /v1/sum, GET /v1/things/{id}, DELETE /v1/things/{id}"; discovery.Version != "v1" || got != want {
+		t.Errorf("discovery = %s %s, want v1 %s", discovery.Version, got, want)
+	}
+}
+
+func TestRouterLocation(t *testing.T) {
+	useMatrices(t)
+	router := controller.NewRouter()
+	router.HandleFunc("POST /matrices", controller.MatricesHandler)
+
+	for _, url := range []string{"/v1/matrices", "/matrices"} {
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, formRequest(url, nil, [2]string{"file", "1\n"}))
+		if location := rr.Header().Get("Location"); !strings.HasPrefix(location, url+"/") {
+			t.Errorf("Location = %q, want it under %s", location, url)
+		}
+	}
+}
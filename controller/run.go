@@ -0,0 +1,133 @@
+package controller
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Upload is a file given to an operation run by Run.
+type Upload struct {
+	// Field is the form field the operation reads it from, file for all but
+	// the a and b of /matmul.
+	Field string
+	// Name is the file name, <field>.csv when empty.
+	Name    string
+	Content io.Reader
+}
+
+// OperationError is the failure of an operation run by Run, as it would have
+// been answered to an HTTP client.
+type OperationError struct {
+	Status int
+	// Message is the error answered, without its "error " prefix.
+	Message string
+}
+
+func (e *OperationError) Error() string {
+	return e.Message
+}
+
+// Run runs the operation handler outside of a server, with the params of its
+// query string and the uploads streamed as a multipart body, and writes its
+// output to w. A failure is returned as an *OperationError; when it happens
+// after part of a streamed output was written, that part stays in w.
+func Run(ctx context.Context, handler http.Handler, params url.Values, uploads []Upload, w io.Writer) error {
+	body, pipe := io.Pipe()
+	defer body.Close()
+	form := multipart.NewWriter(pipe)
+	go func() {
+		for _, upload := range uploads {
+			name := upload.Name
+			if name == "" {
+				name = upload.Field + ".csv"
+			}
+			part, err := form.CreateFormFile(upload.Field, name)
+			if err == nil {
+				_, err = io.Copy(part, upload.Content)
+			}
+			if err != nil {
+				pipe.CloseWithError(err)
+				return
+			}
+		}
+		pipe.CloseWithError(form.Close())
+	}()
+
+	r, err := http.NewRequestWithContext(ctx, http.MethodPost, "/?"+params.Encode(), body)
+	if err != nil {
+		return err
+	}
+	r.Header.Set("Content-Type", form.FormDataContentType())
+
+	rw := &runWriter{header: http.Header{}, w: w}
+	handler.ServeHTTP(rw, r)
+	return rw.finish()
+}
+
+// runWriter passes the output of an operation run by Run to w, until the
+// operation tells it failed.
+type runWriter struct {
+	header http.Header
+	code   int
+	w      io.Writer
+	err    error
+	body   bytes.Buffer // the body of a failure the operation did not tell
+}
+
+func (rw *runWriter) Header() http.Header {
+	return rw.header
+}
+
+func (rw *runWriter) WriteHeader(code int) {
+	if rw.code == 0 {
+		rw.code = code
+	}
+}
+
+func (rw *runWriter) Write(p []byte) (int, error) {
+	rw.WriteHeader(http.StatusOK)
+	switch {
+	case rw.err != nil:
+		// The rest of the output is the error.
+		return len(p), nil
+	case rw.code >= http.StatusBadRequest:
+		return rw.body.Write(p)
+	}
+	return rw.w.Write(p)
+}
+
+// Fail records the failure of the operation, the output written before it
+// staying in w.
+func (rw *runWriter) Fail(err error) {
+	if rw.err == nil {
+		rw.err = err
+	}
+}
+
+// Flush pushes the output through w when it can flush, so streamed results
+// arrive as they are computed.
+func (rw *runWriter) Flush() {
+	if flusher, ok := rw.w.(interface{ Flush() error }); ok {
+		flusher.Flush()
+	}
+}
+
+// finish returns the failure of the operation, if any.
+func (rw *runWriter) finish() error {
+	var failure *OperationError
+	switch {
+	case errors.As(rw.err, &failure):
+		return failure
+	case rw.err != nil:
+		return &OperationError{Status: http.StatusOK, Message: rw.err.Error()}
+	case rw.code >= http.StatusBadRequest:
+		return &OperationError{Status: rw.code, Message: strings.TrimSpace(rw.body.String())}
+	}
+	return nil
+}
@@ -0,0 +1,75 @@
+package controller_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"league/main/controller"
+)
+
+func TestRun(t *testing.T) {
+	file := func(content string) []controller.Upload {
+		return []controller.Upload{{Field: "file", Name: "test.csv", Content: strings.NewReader(content)}}
+	}
+
+	tests := []struct {
+		name           string
+		handler        http.HandlerFunc
+		params         url.Values
+		uploads        []controller.Upload
+		expectedOutput string
+		expectedError  string
+		expectedStatus int
+	}{
+		{name: "Sum", handler: controller.SumHandler, uploads: file("1,2\n3,4\n"), expectedOutput: "10\n"},
+		{name: "Rows", handler: controller.InvertHandler, uploads: file("1,2\n3,4\n"), expectedOutput: "1,3\n2,4\n"},
+		{name: "Short Output", handler: controller.EchoHandler, uploads: file("e\n"), expectedOutput: "e\n"},
+		{name: "Output Like An Error", handler: controller.EchoHandler, uploads: file("error x,1\n2,3\n"), expectedOutput: "error x,1\n2,3\n"},
+		{name: "Params", handler: controller.InverseHandler, params: url.Values{"domain": {"gf:7"}}, uploads: file("1,2\n3,4\n"), expectedOutput: "5,1\n5,3\n"},
+		{
+			name:    "Two Uploads",
+			handler: controller.MatMulHandler,
+			uploads: []controller.Upload{
+				{Field: "a", Content: strings.NewReader("1,2\n3,4\n")},
+				{Field: "b", Content: strings.NewReader("1\n1\n")},
+			},
+			expectedOutput: "3\n7\n",
+		},
+		{name: "Error Body", handler: controller.SumHandler, uploads: file("1,2\n3\n"), expectedError: "record on line 2: wrong number of fields", expectedStatus: http.StatusOK},
+		{name: "Error Status", handler: controller.SumHandler, params: url.Values{"schema": {"nope"}}, uploads: file("1\n"), expectedError: `unknown schema "nope"`, expectedStatus: http.StatusBadRequest},
+		{name: "Missing Upload", handler: controller.SumHandler, expectedError: "http: no such file", expectedStatus: http.StatusOK},
+		{
+			name:           "Streamed Error",
+			handler:        controller.FlattenHandler,
+			params:         url.Values{"stream": {"true"}},
+			uploads:        file("1,2\n3,x\n"),
+			expectedOutput: "1,2,3",
+			expectedError:  "invalid number at position [1,1]",
+			expectedStatus: http.StatusOK,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var output strings.Builder
+			err := controller.Run(context.Background(), tt.handler, tt.params, tt.uploads, &output)
+			if output.String() != tt.expectedOutput {
+				t.Errorf("output = %q, want %q", output.String(), tt.expectedOutput)
+			}
+
+			var failure *controller.OperationError
+			switch {
+			case tt.expectedError == "" && err != nil:
+				t.Errorf("Run() error = %v", err)
+			case tt.expectedError != "" && !errors.As(err, &failure):
+				t.Errorf("Run() error = %v, want an OperationError", err)
+			case tt.expectedError != "" && (failure.Message != tt.expectedError || failure.Status != tt.expectedStatus):
+				t.Errorf("Run() error = %d %q, want %d %q", failure.Status, failure.Message, tt.expectedStatus, tt.expectedError)
+			}
+		})
+	}
+}
@@ -0,0 +1,117 @@
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"league/main/matrix"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+)
+
+var schemas = struct {
+	sync.RWMutex
+	byName map[string]*matrix.Schema
+}{byName: map[string]*matrix.Schema{}}
+
+// RegisterSchema stores a schema that operations can reference with schema=<name>.
+func RegisterSchema(name string, schema *matrix.Schema) error {
+	if name == "" {
+		return fmt.Errorf("schema name is required")
+	}
+	if err := schema.Validate(); err != nil {
+		return err
+	}
+
+	schemas.Lock()
+	defer schemas.Unlock()
+	schemas.byName[name] = schema
+	return nil
+}
+
+// SchemaHandler registers a named schema on POST and lists the registered
+// schemas on GET. The POST body is {"name": "...", "schema": {...}}.
+func SchemaHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		schemas.RLock()
+		names := make([]string, 0, len(schemas.byName))
+		for name := range schemas.byName {
+			names = append(names, name)
+		}
+		schemas.RUnlock()
+		sort.Strings(names)
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string][]string{"schemas": names})
+	case http.MethodPost:
+		var body struct {
+			Name   string          `json:"name"`
+			Schema json.RawMessage `json:"schema"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			httpError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		schema, err := matrix.ParseSchema(body.Schema)
+		if err == nil {
+			err = RegisterSchema(body.Name, schema)
+		}
+		if err != nil {
+			httpError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		w.WriteHeader(http.StatusCreated)
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		httpError(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// requestSchema returns the schema attached to the request, if any. The
+// schema parameter is either the name of a registered schema or an inline
+// JSON spec.
+func requestSchema(r *http.Request) (*matrix.Schema, error) {
+	spec := r.FormValue("schema")
+	if spec == "" {
+		return nil, nil
+	}
+
+	if strings.HasPrefix(strings.TrimSpace(spec), "{") {
+		return matrix.ParseSchema([]byte(spec))
+	}
+
+	schemas.RLock()
+	defer schemas.RUnlock()
+	schema, ok := schemas.byName[spec]
+	if !ok {
+		return nil, fmt.Errorf("unknown schema %q", spec)
+	}
+	return schema, nil
+}
+
+// checkSchema rejects records that do not conform to the schema attached to
+// the request and reports whether the request was rejected.
+func checkSchema(r *http.Request, w http.ResponseWriter, records [][]string) bool {
+	schema, err := requestSchema(r)
+	if err != nil {
+		httpError(w, err.Error(), http.StatusBadRequest)
+		return true
+	}
+	if schema == nil {
+		return false
+	}
+
+	issues := schema.Check(records)
+	if len(issues) == 0 {
+		return false
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusUnprocessableEntity)
+	json.NewEncoder(w).Encode(map[string]any{"valid": false, "issues": issues})
+	return true
+}
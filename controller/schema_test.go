@@ -0,0 +1,138 @@
+package controller_test
+
+import (
+	"bytes"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"league/main/controller"
+	"league/main/matrix"
+)
+
+func TestSchemaHandler(t *testing.T) {
+	tests := []struct {
+		name           string
+		method         string
+		body           string
+		expectedStatus int
+	}{
+		{
+			name:           "Register schema",
+			method:         http.MethodPost,
+			body:           `{"name": "pair", "schema": {"cols": 2}}`,
+			expectedStatus: http.StatusCreated,
+		},
+		{
+			name:           "Missing name",
+			method:         http.MethodPost,
+			body:           `{"schema": {"cols": 2}}`,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "Invalid schema",
+			method:         http.MethodPost,
+			body:           `{"name": "bad", "schema": {"default": {"type": "boolean"}}}`,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "List schemas",
+			method:         http.MethodGet,
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "Method not allowed",
+			method:         http.MethodDelete,
+			expectedStatus: http.StatusMethodNotAllowed,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/schemas", strings.NewReader(tt.body))
+			rr := httptest.NewRecorder()
+			controller.SchemaHandler(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Errorf("expected status %v; got %v (%q)", tt.expectedStatus, rr.Code, rr.Body.String())
+			}
+		})
+	}
+}
+
+func TestOperationWithSchema(t *testing.T) {
+	schema, err := matrix.ParseSchema([]byte(`{"default": {"type": "integer", "min": 1}}`))
+	if err != nil {
+		t.Fatalf("Failed to parse schema: %v", err)
+	}
+	if err := controller.RegisterSchema("positive", schema); err != nil {
+		t.Fatalf("Failed to register schema: %v", err)
+	}
+
+	tests := []struct {
+		name           string
+		url            string
+		inlineSchema   string
+		fileContent    string
+		expectedStatus int
+		expected       string
+	}{
+		{
+			name:           "Named schema accepts input",
+			url:            "/sum?schema=positive",
+			fileContent:    "1,2\n3,4\n",
+			expectedStatus: http.StatusOK,
+			expected:       "10\n",
+		},
+		{
+			name:           "Named schema rejects input",
+			url:            "/sum?schema=positive",
+			fileContent:    "1,2\n3,0\n",
+			expectedStatus: http.StatusUnprocessableEntity,
+			expected:       "value 0 at position [1,1] is outside [1,]",
+		},
+		{
+			name:           "Inline schema rejects input",
+			url:            "/sum",
+			inlineSchema:   `{"rows": 1}`,
+			fileContent:    "1,2\n3,4\n",
+			expectedStatus: http.StatusUnprocessableEntity,
+			expected:       "matrix has 2 rows, expected exactly 1",
+		},
+		{
+			name:           "Unknown schema",
+			url:            "/sum?schema=missing",
+			fileContent:    "1,2\n",
+			expectedStatus: http.StatusBadRequest,
+			expected:       `error unknown schema "missing"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			form := new(bytes.Buffer)
+			writer := multipart.NewWriter(form)
+			fileWriter, _ := writer.CreateFormFile("file", "test.csv")
+			fileWriter.Write([]byte(tt.fileContent))
+			if tt.inlineSchema != "" {
+				writer.WriteField("schema", tt.inlineSchema)
+			}
+			writer.Close()
+
+			req := httptest.NewRequest("POST", tt.url, form)
+			req.Header.Set("Content-Type", writer.FormDataContentType())
+
+			rr := httptest.NewRecorder()
+			controller.SumHandler(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Errorf("expected status %v; got %v", tt.expectedStatus, rr.Code)
+			}
+			if !strings.Contains(rr.Body.String(), tt.expected) {
+				t.Errorf("expected body to contain %q; got %q", tt.expected, rr.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,289 @@
+// Package hotfolder processes the matrices dropped in an inbox directory, the
+// way legacy systems hand over their data: every new CSV file goes through a
+// pipeline of operations, its result is written to an outbox and a failed
+// file is moved to an error directory next to a report of the failure.
+package hotfolder
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"league/main/batch"
+	"log/slog"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Options configure a Folder.
+type Options struct {
+	Inbox  string
+	Outbox string
+	// Errors receives the failed files, each with a <name>.error.json report.
+	Errors string
+	// State records the processed files, hashed with the pipeline and its
+	// parameters, so that a file is processed once even across restarts. It
+	// defaults to .league-processed in the outbox.
+	State string
+	// Interval is the time between two polls of the inbox.
+	Interval time.Duration
+	// Settle leaves the files modified more recently for a later poll, as
+	// they may still be written.
+	Settle time.Duration
+	// Workers bounds the files processed at once.
+	Workers int
+}
+
+// Report is the sidecar of a failed file in the error directory.
+type Report struct {
+	File     string    `json:"file"`
+	SHA256   string    `json:"sha256"`
+	Pipeline []string  `json:"pipeline"`
+	Step     string    `json:"step,omitempty"`
+	Error    string    `json:"error"`
+	Failed   time.Time `json:"failed"`
+}
+
+// Folder watches an inbox. It is not safe for concurrent use, and two
+// Folders must not share an inbox.
+type Folder struct {
+	opts      Options
+	pipeline  batch.Pipeline
+	params    url.Values
+	processed map[string]bool
+	state     *os.File
+}
+
+// New creates the directories of opts and loads the processed hashes.
+func New(opts Options, pipeline batch.Pipeline, params url.Values) (*Folder, error) {
+	if opts.State == "" {
+		opts.State = filepath.Join(opts.Outbox, ".league-processed")
+	}
+	for _, dir := range []string{opts.Inbox, opts.Outbox, opts.Errors, filepath.Dir(opts.State)} {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return nil, err
+		}
+	}
+
+	state, err := os.OpenFile(opts.State, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	processed := map[string]bool{}
+	scanner := bufio.NewScanner(state)
+	for scanner.Scan() {
+		if hash := strings.TrimSpace(scanner.Text()); hash != "" {
+			processed[hash] = true
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		state.Close()
+		return nil, err
+	}
+
+	return &Folder{opts: opts, pipeline: pipeline, params: params, processed: processed, state: state}, nil
+}
+
+// Close releases the state file.
+func (f *Folder) Close() error {
+	return f.state.Close()
+}
+
+// Run polls the inbox every interval until the context is done. A failed
+// poll is logged and retried at the next one.
+func (f *Folder) Run(ctx context.Context) {
+	ticker := time.NewTicker(f.opts.Interval)
+	defer ticker.Stop()
+	for {
+		if err := f.Poll(ctx); err != nil && ctx.Err() == nil {
+			slog.Error("polling the inbox", "inbox", f.opts.Inbox, "error", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// inboxFile is a file of the inbox read for processing, so that the content
+// processed is the content hashed. hash is the SHA-256 of the content, key
+// the one recorded in the state, which adds the pipeline and its parameters.
+type inboxFile struct {
+	name    string
+	content []byte
+	hash    string
+	key     string
+}
+
+// skippedError is the report of a file already processed.
+const skippedError = "already processed with the same pipeline and parameters"
+
+// Poll processes the settled files of the inbox once. A file already
+// processed by the same pipeline with the same parameters is moved to the
+// error directory without being processed again. A file interrupted by the
+// context stays in the inbox.
+func (f *Folder) Poll(ctx context.Context) error {
+	files, err := f.settledFiles()
+	if err != nil {
+		return err
+	}
+
+	inputs := make([]batch.Input, 0, len(files))
+	pending := make([]inboxFile, 0, len(files))
+	polled := map[string]bool{}
+	for _, file := range files {
+		switch {
+		case f.processed[file.key]:
+			slog.Warn("skipping a file processed before", "file", file.name, "sha256", file.hash)
+			if err := f.reject(file, batch.Result{Error: skippedError}); err != nil {
+				return err
+			}
+			if err := os.Remove(filepath.Join(f.opts.Inbox, file.name)); err != nil {
+				return err
+			}
+		case polled[file.key]:
+			// Left for the next poll, which skips it.
+		default:
+			polled[file.key] = true
+			content := file.content
+			inputs = append(inputs, batch.Input{Name: file.name, Open: func() (io.ReadCloser, error) {
+				return io.NopCloser(bytes.NewReader(content)), nil
+			}})
+			pending = append(pending, file)
+		}
+	}
+
+	manifest := batch.Run(ctx, f.pipeline, f.params, inputs, f.opts.Workers)
+	for i, result := range manifest.Results {
+		if result.Error != "" && ctx.Err() != nil {
+			continue
+		}
+		if err := f.finish(pending[i], result); err != nil {
+			return err
+		}
+	}
+	return ctx.Err()
+}
+
+// settledFiles reads the CSV files of the inbox not modified for Settle.
+func (f *Folder) settledFiles() ([]inboxFile, error) {
+	entries, err := os.ReadDir(f.opts.Inbox)
+	if err != nil {
+		return nil, err
+	}
+
+	var files []inboxFile
+	for _, entry := range entries {
+		name := entry.Name()
+		if !entry.Type().IsRegular() || !strings.EqualFold(filepath.Ext(name), ".csv") || strings.HasPrefix(name, ".") {
+			continue
+		}
+		info, err := entry.Info()
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if time.Since(info.ModTime()) < f.opts.Settle {
+			continue
+		}
+
+		content, err := os.ReadFile(filepath.Join(f.opts.Inbox, name))
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		hash := sha256.Sum256(content)
+		files = append(files, inboxFile{name: name, content: content, hash: hex.EncodeToString(hash[:]), key: f.key(content)})
+	}
+	return files, nil
+}
+
+// key hashes the content with the pipeline and its parameters, so that the
+// same file is processed again by another pipeline or with other parameters.
+func (f *Folder) key(content []byte) string {
+	h := sha256.New()
+	for _, field := range []string{strings.Join(f.pipeline.Names(), ","), f.params.Encode()} {
+		h.Write([]byte(field))
+		h.Write([]byte{0})
+	}
+	h.Write(content)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// finish writes the result of a file, or moves it to the error directory,
+// then records it as processed and removes it from the inbox. A crash in
+// between leaves the file to be processed again, or skipped by the next poll
+// once recorded.
+func (f *Folder) finish(file inboxFile, result batch.Result) error {
+	if result.Error == "" {
+		if err := writeFile(filepath.Join(f.opts.Outbox, file.name), []byte(result.Output)); err != nil {
+			return err
+		}
+		slog.Info("processed a file", "file", file.name, "sha256", file.hash)
+	} else {
+		if err := f.reject(file, result); err != nil {
+			return err
+		}
+		slog.Warn("failed to process a file", "file", file.name, "sha256", file.hash, "step", result.Step, "error", result.Error)
+	}
+
+	if _, err := f.state.WriteString(file.key + "\n"); err != nil {
+		return err
+	}
+	if err := f.state.Sync(); err != nil {
+		return err
+	}
+	f.processed[file.key] = true
+	return os.Remove(filepath.Join(f.opts.Inbox, file.name))
+}
+
+// reject writes the file to the error directory next to the report of its
+// failure.
+func (f *Folder) reject(file inboxFile, result batch.Result) error {
+	report, err := json.MarshalIndent(Report{
+		File:     file.name,
+		SHA256:   file.hash,
+		Pipeline: f.pipeline.Names(),
+		Step:     result.Step,
+		Error:    result.Error,
+		Failed:   time.Now(),
+	}, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := writeFile(filepath.Join(f.opts.Errors, file.name+".error.json"), append(report, '\n')); err != nil {
+		return err
+	}
+	return writeFile(filepath.Join(f.opts.Errors, file.name), file.content)
+}
+
+// writeFile writes the file through a temporary file renamed over it, so
+// that whoever reads the outbox never sees a partial result.
+func writeFile(name string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(name), ".write-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), name)
+}
@@ -0,0 +1,210 @@
+package hotfolder
+
+import (
+	"context"
+	"encoding/json"
+	"league/main/batch"
+	"league/main/controller"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newFolder(t *testing.T, root string, settle time.Duration) *Folder {
+	t.Helper()
+	return newPipelineFolder(t, root, settle, "invert,flatten", nil)
+}
+
+func newPipelineFolder(t *testing.T, root string, settle time.Duration, operations string, params url.Values) *Folder {
+	t.Helper()
+	pipeline, err := batch.ParsePipeline(operations, controller.Operations())
+	if err != nil {
+		t.Fatal(err)
+	}
+	folder, err := New(Options{
+		Inbox:    filepath.Join(root, "inbox"),
+		Outbox:   filepath.Join(root, "outbox"),
+		Errors:   filepath.Join(root, "errors"),
+		Interval: time.Millisecond,
+		Settle:   settle,
+		Workers:  2,
+	}, pipeline, params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { folder.Close() })
+	return folder
+}
+
+// drop writes the files into the inbox.
+func drop(t *testing.T, root string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(root, "inbox", name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// names returns the files of dir, hidden ones included.
+func names(dir string) string {
+	entries, _ := os.ReadDir(dir)
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	return strings.Join(names, ",")
+}
+
+func TestPoll(t *testing.T) {
+	root := t.TempDir()
+	folder := newFolder(t, root, 0)
+	drop(t, root, map[string]string{"good.csv": "1,2\n3,4\n", "bad.csv": "1,2\n3\n", "notes.txt": "not a matrix"})
+
+	if err := folder.Poll(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := names(filepath.Join(root, "inbox")); got != "notes.txt" {
+		t.Errorf("inbox = %s, want only notes.txt", got)
+	}
+	if result, _ := os.ReadFile(filepath.Join(root, "outbox", "good.csv")); string(result) != "1,3,2,4\n" {
+		t.Errorf("outbox/good.csv = %q", result)
+	}
+	if got := names(filepath.Join(root, "errors")); got != "bad.csv,bad.csv.error.json" {
+		t.Errorf("errors = %s, want bad.csv,bad.csv.error.json", got)
+	}
+
+	var report Report
+	data, _ := os.ReadFile(filepath.Join(root, "errors", "bad.csv.error.json"))
+	if err := json.Unmarshal(data, &report); err != nil {
+		t.Fatal(err)
+	}
+	if report.File != "bad.csv" || report.Step != "invert" || report.Error != "record on line 2: wrong number of fields" || len(report.SHA256) != 64 {
+		t.Errorf("report = %+v", report)
+	}
+}
+
+func TestPollAcrossRestarts(t *testing.T) {
+	root := t.TempDir()
+	first := newFolder(t, root, 0)
+	drop(t, root, map[string]string{"a.csv": "1,2\n"})
+	if err := first.Poll(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	first.Close()
+	os.Remove(filepath.Join(root, "outbox", "a.csv"))
+
+	// The same content, under any name, is not processed again but moved
+	// to the error directory.
+	drop(t, root, map[string]string{"a.csv": "1,2\n", "copy.csv": "1,2\n", "b.csv": "3\n"})
+	folder := newFolder(t, root, 0)
+	if err := folder.Poll(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if got := names(filepath.Join(root, "outbox")); got != ".league-processed,b.csv" {
+		t.Errorf("outbox = %s, want .league-processed,b.csv", got)
+	}
+	if got := names(filepath.Join(root, "inbox")); got != "" {
+		t.Errorf("inbox = %s, want it empty", got)
+	}
+	if got := names(filepath.Join(root, "errors")); got != "a.csv,a.csv.error.json,copy.csv,copy.csv.error.json" {
+		t.Errorf("errors = %s, want the skipped a.csv and copy.csv", got)
+	}
+	var report Report
+	data, _ := os.ReadFile(filepath.Join(root, "errors", "copy.csv.error.json"))
+	if err := json.Unmarshal(data, &report); err != nil {
+		t.Fatal(err)
+	}
+	if report.Error != skippedError {
+		t.Errorf("report error = %q, want %q", report.Error, skippedError)
+	}
+}
+
+func TestPollAnotherPipeline(t *testing.T) {
+	root := t.TempDir()
+	first := newFolder(t, root, 0)
+	drop(t, root, map[string]string{"a.csv": "1,2\n"})
+	if err := first.Poll(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	first.Close()
+
+	tests := []struct {
+		name       string
+		operations string
+		params     url.Values
+		expected   string
+	}{
+		{name: "Other Operations", operations: "invert", expected: "1\n2\n"},
+		{name: "Other Parameters", operations: "invert,flatten", params: url.Values{"domain": {"rational"}}, expected: "1,2\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			drop(t, root, map[string]string{"a.csv": "1,2\n"})
+			folder := newPipelineFolder(t, root, 0, tt.operations, tt.params)
+			if err := folder.Poll(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+			folder.Close()
+			if result, _ := os.ReadFile(filepath.Join(root, "outbox", "a.csv")); string(result) != tt.expected {
+				t.Errorf("outbox/a.csv = %q, want %q", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestPollDuplicates(t *testing.T) {
+	root := t.TempDir()
+	folder := newFolder(t, root, 0)
+	drop(t, root, map[string]string{"a.csv": "1\n", "b.csv": "1\n"})
+
+	folder.Poll(context.Background())
+	if got := names(filepath.Join(root, "outbox")); got != ".league-processed,a.csv" {
+		t.Errorf("outbox = %s, want only a.csv processed", got)
+	}
+	folder.Poll(context.Background())
+	if got := names(filepath.Join(root, "inbox")); got != "" {
+		t.Errorf("inbox = %s, want the duplicate skipped", got)
+	}
+	if got := names(filepath.Join(root, "errors")); got != "b.csv,b.csv.error.json" {
+		t.Errorf("errors = %s, want the duplicate moved there", got)
+	}
+}
+
+func TestPollSettle(t *testing.T) {
+	root := t.TempDir()
+	folder := newFolder(t, root, time.Hour)
+	drop(t, root, map[string]string{"a.csv": "1\n"})
+
+	folder.Poll(context.Background())
+	if got := names(filepath.Join(root, "inbox")); got != "a.csv" {
+		t.Errorf("inbox = %s, want a.csv left while it may be written", got)
+	}
+
+	old := time.Now().Add(-2 * time.Hour)
+	os.Chtimes(filepath.Join(root, "inbox", "a.csv"), old, old)
+	folder.Poll(context.Background())
+	if got := names(filepath.Join(root, "inbox")); got != "" {
+		t.Errorf("inbox = %s, want a.csv processed once settled", got)
+	}
+}
+
+func TestRunCancelled(t *testing.T) {
+	root := t.TempDir()
+	folder := newFolder(t, root, 0)
+	drop(t, root, map[string]string{"a.csv": "1\n"})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	folder.Run(ctx)
+	if got := names(filepath.Join(root, "inbox")); got != "a.csv" {
+		t.Errorf("inbox = %s, want a.csv left for the next run", got)
+	}
+	if got := names(filepath.Join(root, "errors")); got != "" {
+		t.Errorf("errors = %s, want none", got)
+	}
+}
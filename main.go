@@ -1,30 +1,330 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"league/main/batch"
+	"league/main/cli"
+	"league/main/config"
 	"league/main/controller"
+	"league/main/matrix"
+	"league/main/store"
+	"log/slog"
+	"net"
 	"net/http"
+	"os"
+	"os/signal"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
 )
 
 // Run with
 //		go run .
+// or run an operation from the command line, on files or stdin:
+//		go run . sum /path/matrix.csv
+//		go run . inverse -domain gf:7 < /path/matrix.csv
+//		go run . matmul -domain rational /path/a.csv /path/b.csv
+//		go run . batch -operation inverse,flatten -domain gf:7 /path/matrices
+//		go run . watch -operation sum -inbox /path/in -outbox /path/out -errors /path/failed
+// Configure with flags, LEAGUE_* environment variables or a YAML file, flags
+// taking precedence over the environment and the environment over the file:
+//		go run . -config league.yaml -addr :9090 -operations sum,multiply
+//		LEAGUE_WORKERS=4 LEAGUE_LOG_LEVEL=debug go run .
+//		go run . -h
+// SIGINT or SIGTERM stops the server after draining in-flight requests for up
+// to shutdown_timeout; requests still running then are cancelled, and so are
+// the running jobs.
+// Every route is under /v1, the unversioned paths being deprecated aliases;
+// OPTIONS lists the routes:
+//		curl -X OPTIONS "localhost:8080/v1"
 // Send request with:
 //		/echo:
-//		curl -F 'file=@/path/matrix.csv' "localhost:8080/echo"
+//		curl -F 'file=@/path/matrix.csv' "localhost:8080/v1/echo"
 //		/invert:
-//		curl -F 'file=@/path/matrix.csv' "localhost:8080/invert"
+//		curl -F 'file=@/path/matrix.csv' "localhost:8080/v1/invert"
 //		/flatten:
-//		curl -F 'file=@/path/matrix.csv' "localhost:8080/flatten"
+//		curl -F 'file=@/path/matrix.csv' "localhost:8080/v1/flatten"
 //		/sum:
-//		curl -F 'file=@/path/matrix.csv' "localhost:8080/sum"
+//		curl -F 'file=@/path/matrix.csv' "localhost:8080/v1/sum"
 //		/multiply:
-//		curl -F 'file=@/path/matrix.csv' "localhost:8080/multiply"
+//		curl -F 'file=@/path/matrix.csv' "localhost:8080/v1/multiply"
+//		/matmul:
+//		curl -F 'a=@/path/matrix.csv' -F 'b=@/path/matrix.csv' "localhost:8080/v1/matmul?domain=rational"
+//		/determinant:
+//		curl -F 'file=@/path/matrix.csv' "localhost:8080/v1/determinant"
+//		/inverse:
+//		curl -F 'file=@/path/matrix.csv' "localhost:8080/v1/inverse?domain=gf:7"
+//		/stats:
+//		curl -F 'file=@/path/matrix.csv' "localhost:8080/v1/stats"
+//		/sum, /multiply, /flatten and /stats on files larger than memory:
+//		curl -F 'file=@/path/matrix.csv' "localhost:8080/v1/sum?stream=true"
+//		/invert on matrices larger than memory:
+//		curl -F 'file=@/path/matrix.csv' "localhost:8080/v1/invert?external=true"
+//		/validate:
+//		curl -F 'file=@/path/matrix.csv' "localhost:8080/v1/validate"
+//		/schemas:
+//		curl -d '{"name": "square", "schema": {"rows": 3, "cols": 3}}' "localhost:8080/v1/schemas"
+//		curl -F 'file=@/path/matrix.csv' "localhost:8080/v1/sum?schema=square"
+//		/matrices, upload once and reference by ID in any operation:
+//		curl -F 'file=@/path/matrix.csv' "localhost:8080/v1/matrices"
+//		curl -X POST "localhost:8080/v1/sum?matrix=<id>"
+//		curl -X POST "localhost:8080/v1/matmul?matrix_a=<id>&matrix_b=<id>"
+//		curl "localhost:8080/v1/matrices"
+//		curl "localhost:8080/v1/matrices/<id>"
+//		curl -X DELETE "localhost:8080/v1/matrices/<id>"
+//		/jobs, any other operation in the background:
+//		curl -F 'file=@/path/matrix.csv' "localhost:8080/v1/jobs?operation=determinant&domain=rational"
+//		curl "localhost:8080/v1/jobs/<id>"
+//		curl "localhost:8080/v1/jobs/<id>/result"
+//		curl -X DELETE "localhost:8080/v1/jobs/<id>"
+//		/batch, an operation or pipeline on every CSV of a zip, tar or tar.gz:
+//		curl -F 'file=@/path/matrices.zip' "localhost:8080/v1/batch?operation=inverse,flatten&domain=gf:7"
+//		/openapi.json, the description of the enabled operations:
+//		curl "localhost:8080/v1/openapi.json"
+// Results are cached, hits and misses are counted by:
+//		curl "localhost:8080/v1/cache/stats"
+// Any POST can be retried safely with an Idempotency-Key header:
+//		curl -H 'Idempotency-Key: 42' -F 'file=@/path/matrix.csv' "localhost:8080/v1/multiply"
+
+// endpoints are the endpoints the server can serve besides the registered
+// operations, enabled with the operations setting as well.
+var endpoints = []string{"schemas", "jobs", "batch"}
 
 func main() {
-	http.HandleFunc("/echo", controller.EchoHandler)
-	http.HandleFunc("/invert", controller.InvertHandler)
-	http.HandleFunc("/flatten", controller.FlattenHandler)
-	http.HandleFunc("/sum", controller.SumHandler)
-	http.HandleFunc("/multiply", controller.MultiplyHandler)
+	// league <command> runs an operation from the command line; league,
+	// league serve or league -flags starts the server.
+	args := os.Args[1:]
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		if args[0] != "serve" {
+			os.Exit(runCommand(args))
+		}
+		args = args[1:]
+	}
+
+	var names []string
+	for _, op := range controller.Operations() {
+		names = append(names, op.Name)
+	}
+	names = append(names, endpoints...)
+
+	cfg, err := config.Load(args, names)
+	if errors.Is(err, flag.ErrHelp) {
+		os.Exit(0)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(exitInvalidConfig)
+	}
+
+	slog.SetDefault(slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: cfg.LogLevel})))
+	fmt.Fprintf(os.Stderr, "effective configuration:\n%s", cfg)
+
+	matrix.Workers = cfg.Workers
+	matrix.DefaultMulOptions.BlockSize = cfg.MatmulBlockSize
+	matrix.DefaultMulOptions.StrassenThreshold = cfg.MatmulStrassenThreshold
+	controller.ExternalTranspose = matrix.ExternalOptions{
+		MemoryBudget: cfg.ExternalMemoryBudget,
+		TempDir:      cfg.ExternalTempDir,
+	}
+	controller.Limits = matrix.Limits{
+		MaxRows:         cfg.MaxRows,
+		MaxCols:         cfg.MaxCols,
+		MaxCells:        cfg.MaxCells,
+		MaxDigits:       cfg.MaxCellDigits,
+		MaxResultDigits: cfg.MaxResultDigits,
+	}
+
+	switch cfg.MatrixStore {
+	case "memory":
+		controller.Matrices = store.NewMemory()
+	case "filesystem":
+		matrices, err := store.NewFilesystem(cfg.MatrixDir)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "opening the matrix store:", err)
+			os.Exit(exitInvalidConfig)
+		}
+		controller.Matrices = matrices
+	}
+
+	// Results are cached, and POST requests made idempotent, behind the
+	// router: every route goes through both, keyed by its unversioned path.
+	var cache *controller.Cache
+	if cfg.CacheMaxEntries > 0 && cfg.CacheMaxBytes > 0 {
+		// /validate always streams its upload, /schemas changes state and /jobs
+		// and /batch report on several operations.
+		var cached []string
+		for _, op := range cfg.Operations {
+			if op != "validate" && op != "schemas" && op != "jobs" && op != "batch" {
+				cached = append(cached, op)
+			}
+		}
+		cache, err = controller.NewCache(controller.CacheOptions{
+			MaxEntries:   cfg.CacheMaxEntries,
+			MaxBytes:     cfg.CacheMaxBytes,
+			Dir:          cfg.CacheDir,
+			DiskMaxBytes: cfg.CacheDiskMaxBytes,
+		}, cached)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "opening the result cache:", err)
+			os.Exit(exitInvalidConfig)
+		}
+	}
+	var idempotency *controller.Idempotency
+	if cfg.IdempotencyWindow > 0 {
+		idempotency = controller.NewIdempotency(controller.IdempotencyOptions{
+			Window:     cfg.IdempotencyWindow,
+			MaxEntries: cfg.IdempotencyMaxEntries,
+			MaxBytes:   cfg.IdempotencyMaxBytes,
+		})
+	}
+
+	router := controller.NewRouter()
+	route := func(patterns []string, handler http.Handler) {
+		if cache != nil {
+			handler = cache.Handler(handler)
+		}
+		if idempotency != nil {
+			handler = idempotency.Handler(handler)
+		}
+		for _, pattern := range patterns {
+			router.Handle(pattern, handler)
+		}
+	}
+
+	if controller.Matrices != nil {
+		route([]string{"POST /matrices", "GET /matrices", "GET /matrices/{id}", "DELETE /matrices/{id}"}, http.HandlerFunc(controller.MatricesHandler))
+	}
+	var enabled []controller.Operation
+	for _, op := range controller.Operations() {
+		if slices.Contains(cfg.Operations, op.Name) {
+			enabled = append(enabled, op)
+			route([]string{"POST /" + op.Name}, withDeadline(op.ServeHTTP, cfg.Timeout(op.Name)))
+		}
+	}
+	if slices.Contains(cfg.Operations, "schemas") {
+		route([]string{"GET /schemas", "POST /schemas"}, withDeadline(controller.SchemaHandler, cfg.Timeout("schemas")))
+	}
+	route([]string{"GET /openapi.json"}, controller.OpenAPIHandler(enabled))
+
+	// Jobs run every other enabled operation in the background, bounded by
+	// the job timeout instead of the operation deadlines.
+	var jobs *controller.JobQueue
+	if slices.Contains(cfg.Operations, "jobs") {
+		jobOperations := map[string]http.HandlerFunc{}
+		for _, op := range enabled {
+			jobOperations[op.Name] = op.ServeHTTP
+		}
+		jobs = controller.NewJobQueue(controller.JobOptions{
+			Workers:   cfg.JobWorkers,
+			QueueSize: cfg.JobQueueSize,
+			TTL:       cfg.JobTTL,
+			Timeout:   cfg.JobTimeout,
+		}, jobOperations)
+		route([]string{"POST /jobs", "GET /jobs/{id}", "GET /jobs/{id}/result", "DELETE /jobs/{id}"}, withDeadline(jobs.ServeHTTP, cfg.Timeout("jobs")))
+	}
+
+	if slices.Contains(cfg.Operations, "batch") {
+		batches := batch.NewHandler(batch.Options{Workers: cfg.BatchWorkers, MaxBytes: cfg.MaxUploadSize}, enabled)
+		route([]string{"POST /batch"}, withDeadline(batches.ServeHTTP, cfg.Timeout("batch")))
+	}
+	if cache != nil {
+		route([]string{"GET /cache/stats"}, http.HandlerFunc(cache.StatsHandler))
+	}
+
+	// Every request context derives from requests, cancelling it stops the
+	// computations still running when the drain deadline passes.
+	requests, cancelRequests := context.WithCancel(context.Background())
+
+	srv := &http.Server{
+		Addr:         cfg.Addr,
+		Handler:      logRequests(http.MaxBytesHandler(router, cfg.MaxUploadSize)),
+		ReadTimeout:  cfg.ReadTimeout,
+		WriteTimeout: cfg.WriteTimeout,
+		IdleTimeout:  cfg.IdleTimeout,
+		BaseContext:  func(net.Listener) context.Context { return requests },
+	}
+
+	status := serve(srv, cfg.ShutdownTimeout, cancelRequests)
+	if jobs != nil {
+		jobs.Close()
+	}
+	os.Exit(status)
+}
+
+// runCommand runs an operation from the command line, stopped by SIGINT or
+// SIGTERM.
+func runCommand(args []string) int {
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	return cli.Run(ctx, args, os.Stdin, os.Stdout, os.Stderr, controller.Operations())
+}
+
+// Exit statuses of the server.
+const (
+	exitShutdown      = 0 // stopped by a signal after draining every request
+	exitServerError   = 1 // could not listen or failed while serving
+	exitInvalidConfig = 2 // the configuration is invalid
+	exitDrainTimeout  = 3 // stopped by a signal, requests were cancelled at the deadline
+)
+
+// serve runs srv until SIGINT or SIGTERM, then stops accepting connections
+// and lets in-flight requests finish for up to drain before cancelling them.
+// It returns the exit status.
+func serve(srv *http.Server, drain time.Duration, cancelRequests context.CancelFunc) int {
+	signals, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	failed := make(chan error, 1)
+	go func() {
+		slog.Info("listening", "addr", srv.Addr)
+		failed <- srv.ListenAndServe()
+	}()
+
+	select {
+	case err := <-failed:
+		slog.Error("server stopped", "error", err)
+		return exitServerError
+	case <-signals.Done():
+	}
+
+	// A second signal kills the process right away.
+	stop()
+	slog.Info("shutting down, draining requests", "timeout", drain)
+
+	deadline, cancel := context.WithTimeout(context.Background(), drain)
+	defer cancel()
+	if err := srv.Shutdown(deadline); err != nil {
+		slog.Warn("drain deadline exceeded, cancelling requests", "error", err)
+		cancelRequests()
+		srv.Close()
+		return exitDrainTimeout
+	}
+
+	slog.Info("server stopped")
+	return exitShutdown
+}
+
+// withDeadline bounds the computation of every request to timeout, 0 for no
+// deadline. The handlers answer 504 when it passes.
+func withDeadline(next http.HandlerFunc, timeout time.Duration) http.HandlerFunc {
+	if timeout <= 0 {
+		return next
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), timeout)
+		defer cancel()
+		next(w, r.WithContext(ctx))
+	}
+}
 
-	http.ListenAndServe(":8080", nil)
+// logRequests logs every request at debug level.
+func logRequests(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		slog.Debug("request", "method", r.Method, "path", r.URL.Path, "remote", r.RemoteAddr)
+		next.ServeHTTP(w, r)
+	})
 }
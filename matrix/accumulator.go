@@ -0,0 +1,90 @@
+package matrix
+
+import (
+	"math/big"
+)
+
+// intSum is a running sum kept in int64 until it overflows.
+type intSum struct {
+	small int64
+	large *big.Int // set once the sum has been promoted
+}
+
+func (s *intSum) addInt64(cell int64) {
+	if s.large == nil {
+		if next, ok := addInt64(s.small, cell); ok {
+			s.small = next
+			return
+		}
+		s.large = big.NewInt(s.small)
+	}
+	s.large.Add(s.large, big.NewInt(cell))
+}
+
+func (s *intSum) addBig(cell *big.Int) {
+	if s.large == nil {
+		s.large = big.NewInt(s.small)
+	}
+	s.large.Add(s.large, cell)
+}
+
+func (s *intSum) result() *big.Int {
+	if s.large == nil {
+		return big.NewInt(s.small)
+	}
+	return new(big.Int).Set(s.large)
+}
+
+// intProduct is a running product kept in int64 until it overflows. Once a
+// zero has been seen the remaining cells are ignored.
+type intProduct struct {
+	small int64
+	large *big.Int // set once the product has been promoted
+	zero  bool
+}
+
+func newIntProduct() *intProduct {
+	return &intProduct{small: 1}
+}
+
+func (p *intProduct) mulInt64(cell int64) {
+	if p.zero {
+		return
+	}
+	if cell == 0 {
+		p.zero = true
+		return
+	}
+	if p.large == nil {
+		if next, ok := mulInt64(p.small, cell); ok {
+			p.small = next
+			return
+		}
+		p.large = big.NewInt(p.small)
+	}
+	p.large.Mul(p.large, big.NewInt(cell))
+}
+
+func (p *intProduct) mulBig(cell *big.Int) {
+	if p.zero {
+		return
+	}
+	if cell.Sign() == 0 {
+		p.zero = true
+		return
+	}
+	if p.large == nil {
+		p.large = big.NewInt(p.small)
+	}
+	p.large.Mul(p.large, cell)
+}
+
+func (p *intProduct) result() *big.Int {
+	switch {
+	case p.zero:
+		return big.NewInt(0)
+	case p.large == nil:
+		return big.NewInt(p.small)
+	}
+	return new(big.Int).Set(p.large)
+}
@@ -0,0 +1,30 @@
+package matrix
+
+import (
+	"context"
+	"io"
+)
+
+// cancelCheckCells is how many cells the sequential cancellable loops process
+// between two checks of their context.
+const cancelCheckCells = 1 << 10
+
+// contextReader fails with the context's error once the context is done.
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+// ContextReader returns a reader that reads from r until ctx is done and then
+// fails with ctx's error. Wrapping the input of a streaming operation such as
+// SumCSV makes it stop at the next read once ctx is cancelled.
+func ContextReader(ctx context.Context, r io.Reader) io.Reader {
+	return contextReader{ctx: ctx, r: r}
+}
+
+func (cr contextReader) Read(p []byte) (int, error) {
+	if err := cr.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return cr.r.Read(p)
+}
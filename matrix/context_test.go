@@ -0,0 +1,170 @@
+package matrix
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"math/big"
+	"strings"
+	"testing"
+)
+
+func TestOperationsStopWhenCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	records := smallIntegerRecords(4, func(i, j int) int { return i*4 + j + 1 })
+	csv := "1,2\n3,4\n"
+
+	operations := map[string]func() error{
+		"ParseContext": func() error {
+			_, err := ParseContext(ctx, records)
+			return err
+		},
+		"ParseContext parallel": func() error {
+			withWorkers(t, 4)
+			_, err := ParseContext(ctx, largeRecords(300, 300))
+			return err
+		},
+		"InvertMatrixContext": func() error {
+			_, err := InvertMatrixContext(ctx, records)
+			return err
+		},
+		"FlattenMatrixContext": func() error {
+			_, err := FlattenMatrixContext(ctx, records)
+			return err
+		},
+		"SumMatrixContext": func() error {
+			_, err := SumMatrixContext(ctx, records)
+			return err
+		},
+		"StatsMatrixContext": func() error {
+			_, err := StatsMatrixContext(ctx, records)
+			return err
+		},
+		"SumCSV": func() error {
+			_, err := SumCSV(ContextReader(ctx, strings.NewReader(csv)))
+			return err
+		},
+		"ValidateCSV": func() error {
+			_, err := ValidateCSV(ContextReader(ctx, strings.NewReader(csv)))
+			return err
+		},
+		"TransposeCSVContext": func() error {
+			return TransposeCSVContext(ctx, strings.NewReader(csv), io.Discard, ExternalOptions{TempDir: t.TempDir()})
+		},
+	}
+	for _, name := range Domains[:len(Domains)-1] {
+		domain, _ := LookupDomain(name)
+		operations[name+" Sum"] = func() error {
+			_, err := domain.Sum(ctx, records)
+			return err
+		}
+		operations[name+" Product"] = func() error {
+			_, err := domain.Product(ctx, records)
+			return err
+		}
+		operations[name+" MatMul"] = func() error {
+			_, err := domain.MatMul(ctx, records, records)
+			return err
+		}
+		operations[name+" Determinant"] = func() error {
+			_, err := domain.Determinant(ctx, records)
+			return err
+		}
+	}
+	rational, _ := LookupDomain("rational")
+	operations["rational Inverse"] = func() error {
+		_, err := rational.Inverse(ctx, records)
+		return err
+	}
+
+	for name, operation := range operations {
+		t.Run(name, func(t *testing.T) {
+			if err := operation(); !errors.Is(err, context.Canceled) {
+				t.Errorf("error = %v, want %v", err, context.Canceled)
+			}
+		})
+	}
+}
+
+func TestDenseStopsWhenCancelled(t *testing.T) {
+	ring := BigIntRing{}
+	d, err := ParseDense[*big.Int](ring, randomRecords(16, 16, 10, 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	for _, opts := range []MulOptions{{BlockSize: 4, Workers: 4}, {BlockSize: 4, StrassenThreshold: 4}} {
+		if _, err := d.MulContext(ctx, d, opts); !errors.Is(err, context.Canceled) {
+			t.Errorf("MulContext(%+v) error = %v, want %v", opts, err, context.Canceled)
+		}
+	}
+	if _, err := d.DeterminantContext(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("DeterminantContext() error = %v, want %v", err, context.Canceled)
+	}
+	if _, err := d.ProductContext(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("ProductContext() error = %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestMergeBlocksStopsWhenCancelled(t *testing.T) {
+	dir := t.TempDir()
+	var blocks []string
+	for _, columns := range [][][]string{{{"1"}, {"2"}}, {{"3"}, {"4"}}} {
+		block, err := spillBlock(dir, columns)
+		if err != nil {
+			t.Fatal(err)
+		}
+		blocks = append(blocks, block)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := mergeBlocks(ctx, blocks, 2, bufio.NewWriter(io.Discard))
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("mergeBlocks() error = %v, want %v", err, context.Canceled)
+	}
+}
+
+// cancellingRing cancels its context on the first multiplication.
+type cancellingRing struct {
+	Ring[*big.Int]
+	cancel context.CancelFunc
+	muls   int
+}
+
+func (r *cancellingRing) Mul(a, b *big.Int) *big.Int {
+	r.cancel()
+	r.muls++
+	return r.Ring.Mul(a, b)
+}
+
+func TestMulBlockedStopsWithinBand(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ring := &cancellingRing{Ring: BigIntRing{}, cancel: cancel}
+
+	// A single band of rows, 16 tiles of 4x4 products.
+	a, err := ParseDense[*big.Int](ring, randomRecords(4, 16, 10, 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := ParseDense[*big.Int](ring, randomRecords(16, 16, 10, 2))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = a.MulContext(ctx, b, MulOptions{BlockSize: 4, Workers: 1})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("MulContext() error = %v, want %v", err, context.Canceled)
+	}
+	if ring.muls > 4*4*4 {
+		t.Errorf("MulContext() multiplied %d times after being cancelled, want at most one tile", ring.muls)
+	}
+}
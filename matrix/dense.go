@@ -0,0 +1,281 @@
+package matrix
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// ErrSingular is returned when inverting a matrix whose determinant is zero.
+var ErrSingular = errors.New("matrix is singular")
+
+// Dense is a matrix over an arbitrary Ring, stored contiguously in row-major order.
+type Dense[T any] struct {
+	ring       Ring[T]
+	rows, cols int
+	data       []T
+}
+
+// NewDense returns a rows x cols matrix over ring filled with zeros.
+func NewDense[T any](ring Ring[T], rows, cols int) *Dense[T] {
+	d := &Dense[T]{ring: ring, rows: rows, cols: cols, data: make([]T, rows*cols)}
+	for k := range d.data {
+		d.data[k] = ring.Zero()
+	}
+	return d
+}
+
+// Identity returns the n x n identity matrix over ring.
+func Identity[T any](ring Ring[T], n int) *Dense[T] {
+	d := NewDense(ring, n, n)
+	for i := 0; i < n; i++ {
+		d.data[i*n+i] = ring.One()
+	}
+	return d
+}
+
+// ParseDense validates the records and parses every cell with ring.
+func ParseDense[T any](ring Ring[T], records [][]string) (*Dense[T], error) {
+	return ParseDenseContext(context.Background(), ring, records)
+}
+
+// ParseDenseContext is ParseDense checking ctx after every row.
+func ParseDenseContext[T any](ctx context.Context, ring Ring[T], records [][]string) (*Dense[T], error) {
+	if len(records) == 0 {
+		return NewDense(ring, 0, 0), nil
+	}
+
+	d := &Dense[T]{ring: ring, rows: len(records), cols: len(records[0])}
+	d.data = make([]T, d.rows*d.cols)
+	progress := trackProgress(ctx, "parse", d.rows)
+	for i, row := range records {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		progress.set(i)
+		if len(row) != d.cols {
+			return nil, fmt.Errorf("invalid matrix: inconsistent row length at row %d", i)
+		}
+
+		for j, val := range row {
+			cell, err := ring.Parse(val)
+			if err != nil {
+				return nil, fmt.Errorf("invalid number at position [%d,%d] for domain %s", i, j, ring.Name())
+			}
+			d.data[i*d.cols+j] = cell
+		}
+	}
+	progress.set(d.rows)
+
+	return d, nil
+}
+
+// Rows returns the number of rows.
+func (d *Dense[T]) Rows() int {
+	return d.rows
+}
+
+// Cols returns the number of columns.
+func (d *Dense[T]) Cols() int {
+	return d.cols
+}
+
+// At returns the cell at position [i,j].
+func (d *Dense[T]) At(i, j int) T {
+	return d.data[i*d.cols+j]
+}
+
+// Set stores x at position [i,j].
+func (d *Dense[T]) Set(i, j int, x T) {
+	d.data[i*d.cols+j] = x
+}
+
+// Records formats the matrix back into CSV records.
+func (d *Dense[T]) Records() [][]string {
+	records := make([][]string, d.rows)
+	for i := range records {
+		records[i] = make([]string, d.cols)
+		for j := range records[i] {
+			records[i][j] = d.ring.Format(d.data[i*d.cols+j])
+		}
+	}
+	return records
+}
+
+// Sum returns the sum of all cells.
+func (d *Dense[T]) Sum() T {
+	result := d.ring.Zero()
+	for _, cell := range d.data {
+		result = d.ring.Add(result, cell)
+	}
+	return result
+}
+
+// Product returns the product of all cells, stopping early at the first zero.
+func (d *Dense[T]) Product() T {
+	product, _ := d.ProductContext(context.Background())
+	return product
+}
+
+// ProductContext is Product checking ctx every cancelCheckCells cells.
+func (d *Dense[T]) ProductContext(ctx context.Context) (T, error) {
+	result := d.ring.One()
+	progress := trackProgress(ctx, "product", len(d.data))
+	for k, cell := range d.data {
+		if k%cancelCheckCells == 0 {
+			if err := ctx.Err(); err != nil {
+				return d.ring.Zero(), err
+			}
+			progress.set(k)
+		}
+		if d.ring.IsZero(cell) {
+			return d.ring.Zero(), nil
+		}
+		result = d.ring.Mul(result, cell)
+	}
+	progress.set(len(d.data))
+	return result, nil
+}
+
+// Determinant returns the determinant of a square matrix. It uses the
+// fraction-free Bareiss elimination, so it only needs exact division and works
+// over rings such as the integers as well as over fields.
+func (d *Dense[T]) Determinant() (T, error) {
+	return d.DeterminantContext(context.Background())
+}
+
+// DeterminantContext is Determinant checking ctx before every elimination step.
+func (d *Dense[T]) DeterminantContext(ctx context.Context) (T, error) {
+	if d.rows != d.cols {
+		return d.ring.Zero(), fmt.Errorf("invalid matrix: determinant of non-square %dx%d matrix", d.rows, d.cols)
+	}
+
+	n := d.rows
+	if n == 0 {
+		return d.ring.One(), nil
+	}
+
+	work := d.clone()
+	negate := false
+	previous := d.ring.One()
+	progress := trackProgress(ctx, "determinant", n-1)
+	for k := 0; k < n-1; k++ {
+		if err := ctx.Err(); err != nil {
+			return d.ring.Zero(), err
+		}
+		progress.set(k)
+
+		pivot := work.pivotRow(k, k)
+		if pivot == -1 {
+			return d.ring.Zero(), nil
+		}
+		if pivot != k {
+			work.swapRows(pivot, k)
+			negate = !negate
+		}
+
+		for i := k + 1; i < n; i++ {
+			for j := k + 1; j < n; j++ {
+				cell := d.ring.Sub(
+					d.ring.Mul(work.At(i, j), work.At(k, k)),
+					d.ring.Mul(work.At(i, k), work.At(k, j)),
+				)
+				work.Set(i, j, d.ring.Quo(cell, previous))
+			}
+		}
+		previous = work.At(k, k)
+	}
+	progress.set(n - 1)
+
+	det := work.At(n-1, n-1)
+	if negate {
+		det = d.ring.Sub(d.ring.Zero(), det)
+	}
+	return det, nil
+}
+
+// Inverse returns the inverse of a square matrix over a field using
+// Gauss-Jordan elimination.
+func Inverse[T any](field Field[T], d *Dense[T]) (*Dense[T], error) {
+	return InverseContext(context.Background(), field, d)
+}
+
+// InverseContext is Inverse checking ctx before every elimination step.
+func InverseContext[T any](ctx context.Context, field Field[T], d *Dense[T]) (*Dense[T], error) {
+	if d.rows != d.cols {
+		return nil, fmt.Errorf("invalid matrix: inverse of non-square %dx%d matrix", d.rows, d.cols)
+	}
+
+	n := d.rows
+	work := d.clone()
+	inverse := Identity[T](field, n)
+	progress := trackProgress(ctx, "inverse", n)
+	for k := 0; k < n; k++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		progress.set(k)
+
+		pivot := work.pivotRow(k, k)
+		if pivot == -1 {
+			return nil, ErrSingular
+		}
+		work.swapRows(pivot, k)
+		inverse.swapRows(pivot, k)
+
+		scale := field.Inv(work.At(k, k))
+		for j := 0; j < n; j++ {
+			work.Set(k, j, field.Mul(work.At(k, j), scale))
+			inverse.Set(k, j, field.Mul(inverse.At(k, j), scale))
+		}
+
+		for i := 0; i < n; i++ {
+			factor := work.At(i, k)
+			if i == k || field.IsZero(factor) {
+				continue
+			}
+			for j := 0; j < n; j++ {
+				work.Set(i, j, field.Sub(work.At(i, j), field.Mul(factor, work.At(k, j))))
+				inverse.Set(i, j, field.Sub(inverse.At(i, j), field.Mul(factor, inverse.At(k, j))))
+			}
+		}
+	}
+	progress.set(n)
+	return inverse, nil
+}
+
+// pivotRow returns the row at or below row whose cell in column col is the
+// best pivot, or -1 when the column is all zeros.
+func (d *Dense[T]) pivotRow(row, col int) int {
+	best := -1
+	largest := 0.0
+	magnitude, inexact := d.ring.(magnituder[T])
+	for i := row; i < d.rows; i++ {
+		cell := d.At(i, col)
+		if d.ring.IsZero(cell) {
+			continue
+		}
+		if !inexact {
+			return i
+		}
+		if m := magnitude.Magnitude(cell); best == -1 || m > largest {
+			best, largest = i, m
+		}
+	}
+	return best
+}
+
+func (d *Dense[T]) swapRows(a, b int) {
+	if a == b {
+		return
+	}
+	for j := 0; j < d.cols; j++ {
+		d.data[a*d.cols+j], d.data[b*d.cols+j] = d.data[b*d.cols+j], d.data[a*d.cols+j]
+	}
+}
+
+func (d *Dense[T]) clone() *Dense[T] {
+	c := &Dense[T]{ring: d.ring, rows: d.rows, cols: d.cols, data: make([]T, len(d.data))}
+	copy(c.data, d.data)
+	return c
+}
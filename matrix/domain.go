@@ -0,0 +1,208 @@
+package matrix
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// DefaultDomain is the domain used when a client does not pick one.
+const DefaultDomain = "integer"
+
+// Domain runs the generic algorithms over one number system on CSV records,
+// so callers can pick the number system at runtime by name. Every operation
+// stops with ctx's error once ctx is done.
+type Domain interface {
+	Name() string
+	Sum(ctx context.Context, records [][]string) (string, error)
+	Product(ctx context.Context, records [][]string) (string, error)
+	MatMul(ctx context.Context, a, b [][]string) ([][]string, error)
+	Determinant(ctx context.Context, records [][]string) (string, error)
+	Inverse(ctx context.Context, records [][]string) ([][]string, error)
+}
+
+// Domains lists the names accepted by LookupDomain. GF(p) is written gf:<p>.
+// The integer domain computes in int64 and falls back to bigint on overflow.
+var Domains = []string{"integer", "int64", "bigint", "rational", "float64", "complex128", "gf:<p>"}
+
+// LookupDomain returns the domain with the given name, DefaultDomain when
+// name is empty.
+func LookupDomain(name string) (Domain, error) {
+	switch name {
+	case "", "integer":
+		return promotingDomain{
+			fast: domain[int64]{ring: Int64Ring{}},
+			slow: domain[*big.Int]{ring: BigIntRing{}},
+		}, nil
+	case "bigint":
+		return domain[*big.Int]{ring: BigIntRing{}}, nil
+	case "int64":
+		return domain[int64]{ring: Int64Ring{}}, nil
+	case "rational":
+		return domain[*big.Rat]{ring: RationalField{}}, nil
+	case "float64":
+		return domain[float64]{ring: Float64Field{}}, nil
+	case "complex128":
+		return domain[complex128]{ring: Complex128Field{}}, nil
+	}
+
+	if p, ok := strings.CutPrefix(name, "gf:"); ok {
+		prime, err := strconv.ParseUint(p, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid domain %q: %s", name, err.Error())
+		}
+		field, err := NewPrimeField(prime)
+		if err != nil {
+			return nil, fmt.Errorf("invalid domain %q: %s", name, err.Error())
+		}
+		return domain[uint64]{ring: field}, nil
+	}
+
+	return nil, fmt.Errorf("unknown domain %q, expected one of %s", name, strings.Join(Domains, ", "))
+}
+
+type domain[T any] struct {
+	ring Ring[T]
+}
+
+func (dm domain[T]) Name() string {
+	return dm.ring.Name()
+}
+
+func (dm domain[T]) Sum(ctx context.Context, records [][]string) (result string, err error) {
+	defer recoverOverflow(&err)
+
+	d, err := ParseDenseContext(ctx, dm.ring, records)
+	if err != nil {
+		return "", err
+	}
+	return dm.ring.Format(d.Sum()), nil
+}
+
+func (dm domain[T]) Product(ctx context.Context, records [][]string) (result string, err error) {
+	defer recoverOverflow(&err)
+
+	d, err := ParseDenseContext(ctx, dm.ring, records)
+	if err != nil {
+		return "", err
+	}
+
+	product, err := d.ProductContext(ctx)
+	if err != nil {
+		return "", err
+	}
+	return dm.ring.Format(product), nil
+}
+
+func (dm domain[T]) MatMul(ctx context.Context, a, b [][]string) (result [][]string, err error) {
+	defer recoverOverflow(&err)
+
+	left, err := ParseDenseContext(ctx, dm.ring, a)
+	if err != nil {
+		return nil, err
+	}
+	right, err := ParseDenseContext(ctx, dm.ring, b)
+	if err != nil {
+		return nil, err
+	}
+
+	product, err := left.MulContext(ctx, right, DefaultMulOptions)
+	if err != nil {
+		return nil, err
+	}
+	return product.Records(), nil
+}
+
+func (dm domain[T]) Determinant(ctx context.Context, records [][]string) (result string, err error) {
+	defer recoverOverflow(&err)
+
+	d, err := ParseDenseContext(ctx, dm.ring, records)
+	if err != nil {
+		return "", err
+	}
+
+	det, err := d.DeterminantContext(ctx)
+	if err != nil {
+		return "", err
+	}
+	return dm.ring.Format(det), nil
+}
+
+func (dm domain[T]) Inverse(ctx context.Context, records [][]string) (result [][]string, err error) {
+	defer recoverOverflow(&err)
+
+	field, ok := dm.ring.(Field[T])
+	if !ok {
+		return nil, fmt.Errorf("domain %s is not a field, use rational, float64, complex128 or gf:<p>", dm.ring.Name())
+	}
+
+	d, err := ParseDenseContext(ctx, dm.ring, records)
+	if err != nil {
+		return nil, err
+	}
+
+	inverse, err := InverseContext(ctx, field, d)
+	if err != nil {
+		return nil, err
+	}
+	return inverse.Records(), nil
+}
+
+// promotingDomain runs every operation in the fast domain first and reruns it
+// in the slow one when the fast domain fails, which for int64 means a cell or
+// an intermediate result did not fit. Errors are always reported by the slow
+// domain so they do not depend on the fast path. A fast run stopped by its
+// context is not retried.
+type promotingDomain struct {
+	fast, slow Domain
+}
+
+func (pd promotingDomain) Name() string {
+	return "integer"
+}
+
+func (pd promotingDomain) Sum(ctx context.Context, records [][]string) (string, error) {
+	if result, err := pd.fast.Sum(ctx, records); err == nil || ctx.Err() != nil {
+		return result, err
+	}
+	return pd.slow.Sum(ctx, records)
+}
+
+func (pd promotingDomain) Product(ctx context.Context, records [][]string) (string, error) {
+	if result, err := pd.fast.Product(ctx, records); err == nil || ctx.Err() != nil {
+		return result, err
+	}
+	return pd.slow.Product(ctx, records)
+}
+
+func (pd promotingDomain) MatMul(ctx context.Context, a, b [][]string) ([][]string, error) {
+	if result, err := pd.fast.MatMul(ctx, a, b); err == nil || ctx.Err() != nil {
+		return result, err
+	}
+	return pd.slow.MatMul(ctx, a, b)
+}
+
+func (pd promotingDomain) Determinant(ctx context.Context, records [][]string) (string, error) {
+	if result, err := pd.fast.Determinant(ctx, records); err == nil || ctx.Err() != nil {
+		return result, err
+	}
+	return pd.slow.Determinant(ctx, records)
+}
+
+func (pd promotingDomain) Inverse(ctx context.Context, records [][]string) ([][]string, error) {
+	return pd.slow.Inverse(ctx, records)
+}
+
+// recoverOverflow turns an ErrOverflow panic from a fixed width ring into an error.
+func recoverOverflow(err *error) {
+	if r := recover(); r != nil {
+		if e, ok := r.(error); ok && errors.Is(e, ErrOverflow) {
+			*err = e
+			return
+		}
+		panic(r)
+	}
+}
@@ -0,0 +1,314 @@
+package matrix
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestLookupDomain(t *testing.T) {
+	tests := []struct {
+		name        string
+		expected    string
+		expectError bool
+	}{
+		{name: "", expected: DefaultDomain},
+		{name: "int64", expected: "int64"},
+		{name: "rational", expected: "rational"},
+		{name: "float64", expected: "float64"},
+		{name: "complex128", expected: "complex128"},
+		{name: "gf:7", expected: "gf:7"},
+		{name: "gf:8", expectError: true},
+		{name: "gf:x", expectError: true},
+		{name: "octonion", expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			domain, err := LookupDomain(tt.name)
+
+			if tt.expectError {
+				if err == nil {
+					t.Errorf("Expected error but got none")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Errorf("Unexpected error: %v", err)
+				return
+			}
+
+			if domain.Name() != tt.expected {
+				t.Errorf("Name() = %v, want %v", domain.Name(), tt.expected)
+			}
+		})
+	}
+}
+
+func TestDomainScalarOperations(t *testing.T) {
+	tests := []struct {
+		domain              string
+		matrix              [][]string
+		expectedSum         string
+		expectedProduct     string
+		expectedDeterminant string
+		expectError         bool
+	}{
+		{
+			domain:              "int64",
+			matrix:              [][]string{{"1", "2"}, {"3", "4"}},
+			expectedSum:         "10",
+			expectedProduct:     "24",
+			expectedDeterminant: "-2",
+		},
+		{
+			domain:              "bigint",
+			matrix:              [][]string{{"2", "0", "1"}, {"1", "3", "2"}, {"1", "1", "1"}},
+			expectedSum:         "12",
+			expectedProduct:     "0",
+			expectedDeterminant: "0",
+		},
+		{
+			domain:              "rational",
+			matrix:              [][]string{{"1/2", "1/3"}, {"1.5", "2"}},
+			expectedSum:         "13/3",
+			expectedProduct:     "1/2",
+			expectedDeterminant: "1/2",
+		},
+		{
+			domain:              "float64",
+			matrix:              [][]string{{"0", "2"}, {"1.5", "4"}},
+			expectedSum:         "7.5",
+			expectedProduct:     "0",
+			expectedDeterminant: "-3",
+		},
+		{
+			domain:              "complex128",
+			matrix:              [][]string{{"1+1i", "2"}, {"0", "1i"}},
+			expectedSum:         "(3+2i)",
+			expectedProduct:     "(0+0i)",
+			expectedDeterminant: "(-1+1i)",
+		},
+		{
+			domain:              "gf:7",
+			matrix:              [][]string{{"3", "5"}, {"6", "-1"}},
+			expectedSum:         "6",
+			expectedProduct:     "1",
+			expectedDeterminant: "2",
+		},
+		{
+			domain:              "int64",
+			matrix:              [][]string{{"0", "2", "1"}, {"1", "1", "1"}, {"2", "1", "3"}},
+			expectedSum:         "12",
+			expectedProduct:     "0",
+			expectedDeterminant: "-3",
+		},
+		{
+			domain:      "int64",
+			matrix:      [][]string{{"9223372036854775807", "1"}},
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.domain, func(t *testing.T) {
+			domain, err := LookupDomain(tt.domain)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			sum, err := domain.Sum(context.Background(), tt.matrix)
+			if tt.expectError {
+				if err == nil {
+					t.Errorf("Expected error but got none")
+				}
+				return
+			}
+			if err != nil || sum != tt.expectedSum {
+				t.Errorf("Sum() = %v, %v, want %v", sum, err, tt.expectedSum)
+			}
+
+			product, err := domain.Product(context.Background(), tt.matrix)
+			if err != nil || product != tt.expectedProduct {
+				t.Errorf("Product() = %v, %v, want %v", product, err, tt.expectedProduct)
+			}
+
+			det, err := domain.Determinant(context.Background(), tt.matrix)
+			if err != nil || det != tt.expectedDeterminant {
+				t.Errorf("Determinant() = %v, %v, want %v", det, err, tt.expectedDeterminant)
+			}
+		})
+	}
+}
+
+func TestIntegerDomainPromotion(t *testing.T) {
+	integer, err := LookupDomain("integer")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	big := [][]string{{"4294967296", "1"}, {"1", "4294967296"}}
+
+	det, err := integer.Determinant(context.Background(), big)
+	if err != nil || det != "18446744073709551615" {
+		t.Errorf("Determinant() = %v, %v, want 18446744073709551615", det, err)
+	}
+
+	product, err := integer.MatMul(context.Background(), big, big)
+	expected := [][]string{{"18446744073709551617", "8589934592"}, {"8589934592", "18446744073709551617"}}
+	if err != nil || !reflect.DeepEqual(product, expected) {
+		t.Errorf("MatMul() = %v, %v, want %v", product, err, expected)
+	}
+
+	if _, err := integer.Sum(context.Background(), [][]string{{"1", "x"}}); err == nil {
+		t.Errorf("Expected error but got none")
+	}
+}
+
+func TestDomainMatMul(t *testing.T) {
+	tests := []struct {
+		name        string
+		domain      string
+		a           [][]string
+		b           [][]string
+		expected    [][]string
+		expectError bool
+	}{
+		{
+			name:     "2x3 by 3x1",
+			domain:   "bigint",
+			a:        [][]string{{"1", "2", "3"}, {"4", "5", "6"}},
+			b:        [][]string{{"1"}, {"0"}, {"-1"}},
+			expected: [][]string{{"-2"}, {"-2"}},
+		},
+		{
+			name:     "Modular",
+			domain:   "gf:5",
+			a:        [][]string{{"2", "3"}},
+			b:        [][]string{{"4"}, {"4"}},
+			expected: [][]string{{"0"}},
+		},
+		{
+			name:        "Dimension mismatch",
+			domain:      "bigint",
+			a:           [][]string{{"1", "2"}},
+			b:           [][]string{{"1", "2"}},
+			expectError: true,
+		},
+		{
+			name:        "Invalid number for domain",
+			domain:      "int64",
+			a:           [][]string{{"1/2"}},
+			b:           [][]string{{"1"}},
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			domain, err := LookupDomain(tt.domain)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			result, err := domain.MatMul(context.Background(), tt.a, tt.b)
+
+			if tt.expectError {
+				if err == nil {
+					t.Errorf("Expected error but got none")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Errorf("Unexpected error: %v", err)
+				return
+			}
+
+			if !reflect.DeepEqual(result, tt.expected) {
+				t.Errorf("MatMul() = %v, want %v", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestDomainInverse(t *testing.T) {
+	tests := []struct {
+		name        string
+		domain      string
+		matrix      [][]string
+		expected    [][]string
+		expectError bool
+	}{
+		{
+			name:     "Rational",
+			domain:   "rational",
+			matrix:   [][]string{{"4", "7"}, {"2", "6"}},
+			expected: [][]string{{"3/5", "-7/10"}, {"-1/5", "2/5"}},
+		},
+		{
+			name:     "Rational needs pivoting",
+			domain:   "rational",
+			matrix:   [][]string{{"0", "2"}, {"1", "0"}},
+			expected: [][]string{{"0", "1"}, {"1/2", "0"}},
+		},
+		{
+			name:     "Float",
+			domain:   "float64",
+			matrix:   [][]string{{"2", "0"}, {"0", "4"}},
+			expected: [][]string{{"0.5", "0"}, {"0", "0.25"}},
+		},
+		{
+			name:     "Prime field",
+			domain:   "gf:7",
+			matrix:   [][]string{{"3", "0"}, {"0", "2"}},
+			expected: [][]string{{"5", "0"}, {"0", "4"}},
+		},
+		{
+			name:        "Singular",
+			domain:      "rational",
+			matrix:      [][]string{{"1", "2"}, {"2", "4"}},
+			expectError: true,
+		},
+		{
+			name:        "Not a field",
+			domain:      "bigint",
+			matrix:      [][]string{{"1"}},
+			expectError: true,
+		},
+		{
+			name:        "Not square",
+			domain:      "rational",
+			matrix:      [][]string{{"1", "2"}},
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			domain, err := LookupDomain(tt.domain)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			result, err := domain.Inverse(context.Background(), tt.matrix)
+
+			if tt.expectError {
+				if err == nil {
+					t.Errorf("Expected error but got none")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Errorf("Unexpected error: %v", err)
+				return
+			}
+
+			if !reflect.DeepEqual(result, tt.expected) {
+				t.Errorf("Inverse() = %v, want %v", result, tt.expected)
+			}
+		})
+	}
+}
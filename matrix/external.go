@@ -0,0 +1,232 @@
+package matrix
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+const (
+	// DefaultMemoryBudget is the memory budget of TransposeCSV when none is set.
+	DefaultMemoryBudget = 64 << 20
+
+	// maxOpenBlocks bounds the spilled blocks merged at once so a small budget
+	// on a huge input does not run out of file descriptors.
+	maxOpenBlocks = 256
+)
+
+// ExternalOptions configures TransposeCSV.
+type ExternalOptions struct {
+	// MemoryBudget is the approximate number of bytes of cells held in memory
+	// before a block of rows is spilled to disk, DefaultMemoryBudget when zero.
+	MemoryBudget int64
+	// TempDir is where the spilled blocks are written, os.TempDir() when empty.
+	TempDir string
+	// Limits bound the input as it is read.
+	Limits Limits
+}
+
+// TransposeCSV is the external-memory variant of InvertMatrix. It reads r row
+// by row, spills the transpose of each block of rows that fills the memory
+// budget to a temporary file and merges the blocks into w, so the matrix can
+// be far larger than memory. Every cell is validated before anything is
+// written to w.
+func TransposeCSV(r io.Reader, w io.Writer, opts ExternalOptions) error {
+	return TransposeCSVContext(context.Background(), r, w, opts)
+}
+
+// TransposeCSVContext is TransposeCSV stopping with ctx's error once ctx is
+// done, while reading r as well as while merging the spilled blocks.
+func TransposeCSVContext(ctx context.Context, r io.Reader, w io.Writer, opts ExternalOptions) error {
+	r = ContextReader(ctx, r)
+	budget := opts.MemoryBudget
+	if budget <= 0 {
+		budget = DefaultMemoryBudget
+	}
+
+	var dir string
+	defer func() {
+		if dir != "" {
+			os.RemoveAll(dir)
+		}
+	}()
+
+	var blocks []string
+	var columns [][]string
+	var size int64
+	spill := func() error {
+		if dir == "" {
+			var err error
+			if dir, err = os.MkdirTemp(opts.TempDir, "transpose-"); err != nil {
+				return err
+			}
+		}
+
+		block, err := spillBlock(dir, columns)
+		if err != nil {
+			return err
+		}
+		blocks = append(blocks, block)
+		for j := range columns {
+			columns[j] = columns[j][:0]
+		}
+		size = 0
+		return nil
+	}
+
+	reader := csv.NewReader(r)
+	reader.ReuseRecord = true
+
+	rows := 0
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		if columns == nil {
+			columns = make([][]string, len(record))
+		}
+		if err := opts.Limits.checkRecord(rows, record, (rows+1)*len(columns)); err != nil {
+			return err
+		}
+		for j, val := range record {
+			if err := checkCell(val, rows, j); err != nil {
+				return err
+			}
+			// The record is reused by the reader, the cell must be copied.
+			cell := strings.Clone(val)
+			columns[j] = append(columns[j], cell)
+			size += int64(len(cell)) + 16
+		}
+		rows++
+
+		if size >= budget {
+			if err := spill(); err != nil {
+				return err
+			}
+		}
+	}
+
+	// The whole matrix fit in the budget, nothing to merge.
+	if len(blocks) == 0 {
+		return writeColumns(w, columns)
+	}
+
+	if len(columns) > 0 && len(columns[0]) > 0 {
+		if err := spill(); err != nil {
+			return err
+		}
+	}
+
+	for len(blocks) > maxOpenBlocks {
+		var merged []string
+		for start := 0; start < len(blocks); start += maxOpenBlocks {
+			end := min(start+maxOpenBlocks, len(blocks))
+			block, err := mergeToFile(ctx, dir, blocks[start:end], len(columns))
+			if err != nil {
+				return err
+			}
+			merged = append(merged, block)
+		}
+		blocks = merged
+	}
+
+	buffered := bufio.NewWriter(w)
+	if err := mergeBlocks(ctx, blocks, len(columns), buffered); err != nil {
+		return err
+	}
+	return buffered.Flush()
+}
+
+// writeColumns writes every column as one CSV line.
+func writeColumns(w io.Writer, columns [][]string) error {
+	buffered := bufio.NewWriter(w)
+	for _, column := range columns {
+		for i, cell := range column {
+			if i > 0 {
+				buffered.WriteByte(',')
+			}
+			buffered.WriteString(cell)
+		}
+		buffered.WriteByte('\n')
+	}
+	return buffered.Flush()
+}
+
+// spillBlock writes the transposed block to a new file in dir.
+func spillBlock(dir string, columns [][]string) (string, error) {
+	file, err := os.CreateTemp(dir, "block-")
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	if err := writeColumns(file, columns); err != nil {
+		return "", err
+	}
+	return file.Name(), file.Close()
+}
+
+// mergeToFile merges blocks into a new, wider block in dir and removes them.
+func mergeToFile(ctx context.Context, dir string, blocks []string, lines int) (string, error) {
+	file, err := os.CreateTemp(dir, "block-")
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	buffered := bufio.NewWriter(file)
+	if err := mergeBlocks(ctx, blocks, lines, buffered); err != nil {
+		return "", err
+	}
+	if err := buffered.Flush(); err != nil {
+		return "", err
+	}
+
+	for _, block := range blocks {
+		os.Remove(block)
+	}
+	return file.Name(), file.Close()
+}
+
+// mergeBlocks joins line j of every block, in order, into line j of w.
+func mergeBlocks(ctx context.Context, blocks []string, lines int, w *bufio.Writer) error {
+	readers := make([]*bufio.Reader, len(blocks))
+	for b, block := range blocks {
+		file, err := os.Open(block)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		readers[b] = bufio.NewReader(ContextReader(ctx, file))
+	}
+
+	for j := 0; j < lines; j++ {
+		for b, reader := range readers {
+			if b > 0 {
+				w.WriteByte(',')
+			}
+
+			// Lines longer than the reader buffer come back in pieces.
+			line, err := reader.ReadSlice('\n')
+			for err == bufio.ErrBufferFull {
+				w.Write(line)
+				line, err = reader.ReadSlice('\n')
+			}
+			if err != nil {
+				return fmt.Errorf("reading spilled block %d: %w", b, err)
+			}
+			w.Write(line[:len(line)-1])
+		}
+		w.WriteByte('\n')
+	}
+	return nil
+}
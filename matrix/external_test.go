@@ -0,0 +1,116 @@
+package matrix
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestTransposeCSV(t *testing.T) {
+	tests := []struct {
+		name        string
+		input       string
+		budget      int64
+		expected    string
+		expectError bool
+	}{
+		{
+			name:     "Empty matrix",
+			input:    "",
+			expected: "",
+		},
+		{
+			name:     "Fits in memory",
+			input:    "1,2,3\n4,5,6\n",
+			expected: "1,4\n2,5\n3,6\n",
+		},
+		{
+			name:     "Spills every row",
+			input:    "1,2,3\n4,5,6\n7,8,9\n",
+			budget:   1,
+			expected: "1,4,7\n2,5,8\n3,6,9\n",
+		},
+		{
+			name:     "Spills partial blocks",
+			input:    "1,2\n3,4\n5,6\n7,8\n9,10\n",
+			budget:   40,
+			expected: "1,3,5,7,9\n2,4,6,8,10\n",
+		},
+		{
+			name:     "Keeps the cells as written",
+			input:    "007,+1\n99999999999999999999,-0\n",
+			budget:   1,
+			expected: "007,99999999999999999999\n+1,-0\n",
+		},
+		{
+			name:        "Invalid number",
+			input:       "1,2\n3,x\n",
+			budget:      1,
+			expectError: true,
+		},
+		{
+			name:        "Ragged rows",
+			input:       "1,2\n3\n",
+			budget:      1,
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			var out bytes.Buffer
+			err := TransposeCSV(strings.NewReader(tt.input), &out, ExternalOptions{MemoryBudget: tt.budget, TempDir: dir})
+
+			if tt.expectError {
+				if err == nil {
+					t.Errorf("Expected error but got none")
+				}
+				if out.Len() != 0 {
+					t.Errorf("Expected no output on error, got %q", out.String())
+				}
+			} else if err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			} else if out.String() != tt.expected {
+				t.Errorf("TransposeCSV() = %q, want %q", out.String(), tt.expected)
+			}
+
+			if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+				t.Errorf("Expected temp dir to be cleaned up, found %d entries", len(entries))
+			}
+		})
+	}
+}
+
+func TestTransposeCSVMatchesInvertMatrix(t *testing.T) {
+	// Enough rows to need a second round of merging with a tiny budget.
+	rows, cols := maxOpenBlocks*2+3, 7
+	records := make([][]string, rows)
+	var input strings.Builder
+	for i := range records {
+		records[i] = make([]string, cols)
+		for j := range records[i] {
+			records[i][j] = fmt.Sprint(i*cols + j)
+		}
+		input.WriteString(strings.Join(records[i], ",") + "\n")
+	}
+
+	inverted, err := InvertMatrix(records)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	var expected strings.Builder
+	for _, row := range inverted {
+		expected.WriteString(strings.Join(row, ",") + "\n")
+	}
+
+	var out bytes.Buffer
+	if err := TransposeCSV(strings.NewReader(input.String()), &out, ExternalOptions{MemoryBudget: 1, TempDir: t.TempDir()}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if out.String() != expected.String() {
+		t.Errorf("TransposeCSV() differs from InvertMatrix()")
+	}
+}
@@ -0,0 +1,127 @@
+package matrix
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Limits bounds the matrices accepted for computation so that a single
+// request cannot exhaust memory or CPU. A zero field means no limit.
+type Limits struct {
+	MaxRows  int
+	MaxCols  int
+	MaxCells int
+	// MaxDigits bounds the length of a cell, not counting its sign.
+	MaxDigits int
+	// MaxResultDigits bounds the estimated number of digits of the product
+	// of all cells.
+	MaxResultDigits int
+}
+
+// LimitError reports a matrix exceeding one of the Limits.
+type LimitError struct {
+	// What is exceeded, such as "rows" or "digits at position [2,3]".
+	What  string
+	Value int
+	Max   int
+}
+
+func (e *LimitError) Error() string {
+	return fmt.Sprintf("too many %s: %d, the limit is %d", e.What, e.Value, e.Max)
+}
+
+// exceeds returns a *LimitError if value is over max and max is set.
+func exceeds(what string, value, max int) error {
+	if max > 0 && value > max {
+		return &LimitError{What: what, Value: value, Max: max}
+	}
+	return nil
+}
+
+// Check returns a *LimitError if the records exceed the dimension or digit
+// limits. It only looks at the text, the records do not need to be valid.
+func (l Limits) Check(records [][]string) error {
+	if err := exceeds("rows", len(records), l.MaxRows); err != nil {
+		return err
+	}
+
+	cells := 0
+	for i, row := range records {
+		cells += len(row)
+		if err := l.checkRecord(i, row, cells); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkRecord returns a *LimitError if row i, the last of the cells read so
+// far, exceeds the limits. The streaming operations call it on every record
+// as it is read, before parsing its cells.
+func (l Limits) checkRecord(i int, row []string, cells int) error {
+	if err := exceeds("rows", i+1, l.MaxRows); err != nil {
+		return err
+	}
+	if err := exceeds("columns", len(row), l.MaxCols); err != nil {
+		return err
+	}
+	if err := exceeds("cells", cells, l.MaxCells); err != nil {
+		return err
+	}
+
+	if l.MaxDigits <= 0 {
+		return nil
+	}
+	for j, val := range row {
+		digits := len(strings.TrimLeft(val, "+-"))
+		if err := exceeds(fmt.Sprintf("digits at position [%d,%d]", i, j), digits, l.MaxDigits); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CheckProduct returns a *LimitError if the product of all cells could have
+// more than MaxResultDigits digits. A product has at most as many digits as
+// its factors together, so the estimate is their total length; it is 1 as
+// soon as a cell is zero.
+func (l Limits) CheckProduct(records [][]string) error {
+	if l.MaxResultDigits <= 0 {
+		return nil
+	}
+
+	digits := 0
+	for _, row := range records {
+		for _, val := range row {
+			magnitude := strings.TrimLeft(strings.TrimLeft(val, "+-"), "0")
+			if magnitude == "" {
+				return nil
+			}
+			digits += len(magnitude)
+		}
+	}
+	return exceeds("digits in the product", digits, l.MaxResultDigits)
+}
+
+// productDigits is the estimate of CheckProduct made as the factors of a
+// streamed product arrive. Unlike CheckProduct it cannot know that a later
+// cell is zero, so it fails as soon as the factors read so far are too long.
+type productDigits struct {
+	digits int
+	zero   bool
+}
+
+// add counts a factor and returns a *LimitError once the product could have
+// more than MaxResultDigits digits.
+func (p *productDigits) add(val string, l Limits) error {
+	if l.MaxResultDigits <= 0 || p.zero {
+		return nil
+	}
+	magnitude := strings.TrimLeft(strings.TrimLeft(val, "+-"), "0")
+	if magnitude == "" {
+		p.zero = true
+		return nil
+	}
+	p.digits += len(magnitude)
+	return exceeds("digits in the product", p.digits, l.MaxResultDigits)
+}
@@ -0,0 +1,94 @@
+package matrix
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestLimitsCheck(t *testing.T) {
+	records := [][]string{{"1", "-22", "+333"}, {"4444", "5", "6"}}
+	tests := []struct {
+		name     string
+		limits   Limits
+		expected string
+	}{
+		{name: "No Limits", limits: Limits{}},
+		{name: "At The Limits", limits: Limits{MaxRows: 2, MaxCols: 3, MaxCells: 6, MaxDigits: 4}},
+		{name: "Rows", limits: Limits{MaxRows: 1}, expected: "too many rows: 2, the limit is 1"},
+		{name: "Columns", limits: Limits{MaxCols: 2}, expected: "too many columns: 3, the limit is 2"},
+		{name: "Cells", limits: Limits{MaxCells: 5}, expected: "too many cells: 6, the limit is 5"},
+		{name: "Digits", limits: Limits{MaxDigits: 3}, expected: "too many digits at position [1,0]: 4, the limit is 3"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.limits.Check(records)
+			if tt.expected == "" {
+				if err != nil {
+					t.Errorf("Check() error = %v", err)
+				}
+				return
+			}
+
+			var limit *LimitError
+			if !errors.As(err, &limit) || err.Error() != tt.expected {
+				t.Errorf("Check() error = %v, want %q", err, tt.expected)
+			}
+		})
+	}
+}
+
+func TestLimitsCheckProduct(t *testing.T) {
+	tests := []struct {
+		name        string
+		records     [][]string
+		expectError bool
+	}{
+		{name: "Small", records: [][]string{{"12", "-34"}, {"+56", "78"}}},
+		{name: "Leading Zeros", records: [][]string{{"000012", "-0034"}, {"56", "78"}}},
+		{name: "Too Large", records: [][]string{{"12", "34"}, {"56", "789"}}, expectError: true},
+		{name: "Zero Cell", records: [][]string{{"123456789", "-00"}}},
+	}
+
+	limits := Limits{MaxResultDigits: 8}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := limits.CheckProduct(tt.records)
+			if tt.expectError != (err != nil) {
+				t.Errorf("CheckProduct() error = %v, expectError %v", err, tt.expectError)
+			}
+		})
+	}
+}
+
+func TestLimitsStreamed(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{name: "Within Limits", input: "0,123456789\n1,2\n"},
+		{name: "Rows", input: "1\n2\n3\n", expected: "too many rows: 3, the limit is 2"},
+		{name: "Digits", input: "1,-1234567890\n", expected: "too many digits at position [0,1]: 10, the limit is 9"},
+		{name: "Product", input: "12345,6789\n", expected: "too many digits in the product: 9, the limit is 8"},
+	}
+
+	limits := Limits{MaxRows: 2, MaxDigits: 9, MaxResultDigits: 8}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := limits.ProductCSV(strings.NewReader(tt.input))
+			if tt.expected == "" {
+				if err != nil {
+					t.Errorf("ProductCSV() error = %v", err)
+				}
+				return
+			}
+
+			var limit *LimitError
+			if !errors.As(err, &limit) || err.Error() != tt.expected {
+				t.Errorf("ProductCSV() error = %v, want %q", err, tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,262 @@
+package matrix
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+)
+
+// MulOptions tunes the matrix product of Dense.Mul.
+type MulOptions struct {
+	// BlockSize is the side of the tiles the kernel works on.
+	BlockSize int
+	// StrassenThreshold is the dimension from which the Strassen-Winograd
+	// recursion is used, 0 to never use it. It is only applied to exact rings
+	// that cannot overflow: floating point rounding and int64 overflow of the
+	// intermediate sums would make the result depend on the algorithm.
+	StrassenThreshold int
+	// Workers bounds the goroutines computing output tiles, the package
+	// Workers when zero.
+	Workers int
+}
+
+// DefaultMulOptions are the options used by Dense.Mul and /matmul, which the
+// server sets from its configuration.
+var DefaultMulOptions = MulOptions{BlockSize: 64, StrassenThreshold: 512}
+
+// Mul returns the matrix product d x other using DefaultMulOptions.
+func (d *Dense[T]) Mul(other *Dense[T]) (*Dense[T], error) {
+	return d.MulWith(other, DefaultMulOptions)
+}
+
+// MulWith returns the matrix product d x other. The result is bit-identical
+// to the naive triple loop whichever path is taken: the kernel adds the
+// products for a cell in the same order, and Strassen-Winograd, which does
+// not, is only used where the order cannot matter.
+func (d *Dense[T]) MulWith(other *Dense[T], opts MulOptions) (*Dense[T], error) {
+	return d.MulContext(context.Background(), other, opts)
+}
+
+// MulContext is MulWith checking ctx before every band of output rows and
+// every Strassen-Winograd sub-product.
+func (d *Dense[T]) MulContext(ctx context.Context, other *Dense[T], opts MulOptions) (*Dense[T], error) {
+	if d.cols != other.rows {
+		return nil, fmt.Errorf("invalid matrix: cannot multiply %dx%d by %dx%d", d.rows, d.cols, other.rows, other.cols)
+	}
+
+	if opts.BlockSize <= 0 {
+		opts.BlockSize = DefaultMulOptions.BlockSize
+	}
+	if opts.Workers <= 0 {
+		opts.Workers = Workers
+	}
+	if !strassenSafe[T](d.ring) {
+		opts.StrassenThreshold = 0
+	}
+
+	return d.mul(ctx, other, opts)
+}
+
+// strassenSafe reports whether the Strassen-Winograd recursion gives exactly
+// the same result as the naive product over ring.
+func strassenSafe[T any](ring Ring[T]) bool {
+	if _, inexact := ring.(magnituder[T]); inexact {
+		return false
+	}
+	_, fixedWidth := any(ring).(Int64Ring)
+	return !fixedWidth
+}
+
+func (d *Dense[T]) mul(ctx context.Context, other *Dense[T], opts MulOptions) (*Dense[T], error) {
+	threshold := opts.StrassenThreshold
+	if threshold > 0 && d.rows >= threshold && d.cols >= threshold && other.cols >= threshold {
+		return d.mulStrassen(ctx, other, opts)
+	}
+
+	result := NewDense(d.ring, d.rows, other.cols)
+	if err := d.mulBlocked(ctx, other, result, opts); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// mulNaive is the reference triple loop the other algorithms must match.
+func (d *Dense[T]) mulNaive(other *Dense[T]) *Dense[T] {
+	result := NewDense(d.ring, d.rows, other.cols)
+	for i := 0; i < d.rows; i++ {
+		for k := 0; k < d.cols; k++ {
+			a := d.data[i*d.cols+k]
+			if d.ring.IsZero(a) {
+				continue
+			}
+			for j := 0; j < other.cols; j++ {
+				product := d.ring.Mul(a, other.data[k*other.cols+j])
+				result.data[i*result.cols+j] = d.ring.Add(result.data[i*result.cols+j], product)
+			}
+		}
+	}
+	return result
+}
+
+// multiplyAdder is implemented by rings whose elements can be updated in
+// place. The kernel uses it to add a product into a result cell without
+// allocating two new elements per step.
+type multiplyAdder[T any] interface {
+	// multiplyAdd sets acc to acc + a*b using scratch as temporary storage and
+	// returns it.
+	multiplyAdd(acc, a, b, scratch T) T
+}
+
+func (BigIntRing) multiplyAdd(acc, a, b, scratch *big.Int) *big.Int {
+	return acc.Add(acc, scratch.Mul(a, b))
+}
+
+// mulBlocked adds d x other to result tile by tile, result's cells must not
+// be shared with any other matrix. Bands of output rows are
+// computed in parallel, and within a band the k tiles are visited in order so
+// every cell accumulates its products in the same order as mulNaive. An
+// ErrOverflow panic in a worker is returned as an error. Cancellation and
+// the failure of another band are checked once per tile.
+func (d *Dense[T]) mulBlocked(ctx context.Context, other *Dense[T], result *Dense[T], opts MulOptions) error {
+	block := opts.BlockSize
+	inPlace, _ := d.ring.(multiplyAdder[T])
+	progress := trackProgress(ctx, "matmul", d.rows)
+	return forEachChunk(d.rows, block, opts.Workers, func(bi, iEnd int, stop func() bool) (err error) {
+		defer recoverOverflow(&err)
+		scratch := d.ring.Zero()
+		for bj := 0; bj < other.cols; bj += block {
+			jEnd := min(bj+block, other.cols)
+			for bk := 0; bk < d.cols; bk += block {
+				if stop() {
+					return nil
+				}
+				if err := ctx.Err(); err != nil {
+					return err
+				}
+				kEnd := min(bk+block, d.cols)
+				for i := bi; i < iEnd; i++ {
+					for k := bk; k < kEnd; k++ {
+						a := d.data[i*d.cols+k]
+						if d.ring.IsZero(a) {
+							continue
+						}
+						row := result.data[i*result.cols : (i+1)*result.cols]
+						if inPlace != nil {
+							for j := bj; j < jEnd; j++ {
+								row[j] = inPlace.multiplyAdd(row[j], a, other.data[k*other.cols+j], scratch)
+							}
+							continue
+						}
+						for j := bj; j < jEnd; j++ {
+							row[j] = d.ring.Add(row[j], d.ring.Mul(a, other.data[k*other.cols+j]))
+						}
+					}
+				}
+			}
+		}
+		progress.add(iEnd - bi)
+		return nil
+	})
+}
+
+// mulStrassen computes the product with the Strassen-Winograd recursion, 7
+// half-size products instead of 8, padding odd dimensions with a row or
+// column of zeros. Each half-size product goes back through mul, so it
+// recurses while all three dimensions are at least opts.StrassenThreshold
+// and falls back to mulBlocked below it.
+func (d *Dense[T]) mulStrassen(ctx context.Context, other *Dense[T], opts MulOptions) (*Dense[T], error) {
+	n, m, p := d.rows, d.cols, other.cols
+	a := d.padded(n+n%2, m+m%2)
+	b := other.padded(m+m%2, p+p%2)
+	hn, hm, hp := a.rows/2, a.cols/2, b.cols/2
+
+	a11, a12, a21, a22 := a.slice(0, 0, hn, hm), a.slice(0, hm, hn, hm), a.slice(hn, 0, hn, hm), a.slice(hn, hm, hn, hm)
+	b11, b12, b21, b22 := b.slice(0, 0, hm, hp), b.slice(0, hp, hm, hp), b.slice(hm, 0, hm, hp), b.slice(hm, hp, hm, hp)
+
+	s1 := a21.add(a22)
+	s2 := s1.sub(a11)
+	s3 := a11.sub(a21)
+	s4 := a12.sub(s2)
+	t1 := b12.sub(b11)
+	t2 := b22.sub(t1)
+	t3 := b22.sub(b12)
+	t4 := t2.sub(b21)
+
+	factors := [7][2]*Dense[T]{{a11, b11}, {a12, b21}, {s4, b22}, {a22, t4}, {s1, t1}, {s2, t2}, {s3, t3}}
+	// Progress counts the half-size products, not the rows of each of them.
+	progress := trackProgress(ctx, "matmul", len(factors))
+	quiet := WithProgress(ctx, nil)
+	var products [7]*Dense[T]
+	for k, f := range factors {
+		product, err := f[0].mul(quiet, f[1], opts)
+		if err != nil {
+			return nil, err
+		}
+		products[k] = product
+		progress.add(1)
+	}
+	p1, p2, p3, p4, p5, p6, p7 := products[0], products[1], products[2], products[3], products[4], products[5], products[6]
+
+	u2 := p1.add(p6)
+	u3 := u2.add(p7)
+	c11 := p1.add(p2)
+	c12 := u2.add(p5).add(p3)
+	c21 := u3.sub(p4)
+	c22 := u3.add(p5)
+
+	result := NewDense(d.ring, n, p)
+	for i := 0; i < n; i++ {
+		for j := 0; j < p; j++ {
+			var quadrant *Dense[T]
+			switch {
+			case i < hn && j < hp:
+				quadrant = c11
+			case i < hn:
+				quadrant = c12
+			case j < hp:
+				quadrant = c21
+			default:
+				quadrant = c22
+			}
+			result.data[i*p+j] = quadrant.At(i%hn, j%hp)
+		}
+	}
+	return result, nil
+}
+
+// padded returns a rows x cols copy of d, filled up with zeros.
+func (d *Dense[T]) padded(rows, cols int) *Dense[T] {
+	if rows == d.rows && cols == d.cols {
+		return d
+	}
+	p := NewDense(d.ring, rows, cols)
+	for i := 0; i < d.rows; i++ {
+		copy(p.data[i*cols:i*cols+d.cols], d.data[i*d.cols:(i+1)*d.cols])
+	}
+	return p
+}
+
+// slice copies the rows x cols block of d starting at [i,j].
+func (d *Dense[T]) slice(i, j, rows, cols int) *Dense[T] {
+	s := &Dense[T]{ring: d.ring, rows: rows, cols: cols, data: make([]T, rows*cols)}
+	for r := 0; r < rows; r++ {
+		copy(s.data[r*cols:(r+1)*cols], d.data[(i+r)*d.cols+j:(i+r)*d.cols+j+cols])
+	}
+	return s
+}
+
+func (d *Dense[T]) add(other *Dense[T]) *Dense[T] {
+	sum := &Dense[T]{ring: d.ring, rows: d.rows, cols: d.cols, data: make([]T, len(d.data))}
+	for k := range d.data {
+		sum.data[k] = d.ring.Add(d.data[k], other.data[k])
+	}
+	return sum
+}
+
+func (d *Dense[T]) sub(other *Dense[T]) *Dense[T] {
+	difference := &Dense[T]{ring: d.ring, rows: d.rows, cols: d.cols, data: make([]T, len(d.data))}
+	for k := range d.data {
+		difference.data[k] = d.ring.Sub(d.data[k], other.data[k])
+	}
+	return difference
+}
@@ -0,0 +1,134 @@
+package matrix
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+// randomRecords returns a rows x cols matrix of random signed integers of up
+// to the given number of digits, about a tenth of them zero.
+func randomRecords(rows, cols, digits int, seed int64) [][]string {
+	random := rand.New(rand.NewSource(seed))
+	limit := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(digits)), nil)
+	records := make([][]string, rows)
+	for i := range records {
+		records[i] = make([]string, cols)
+		for j := range records[i] {
+			cell := new(big.Int)
+			if random.Intn(10) > 0 {
+				cell.Rand(random, limit)
+				if random.Intn(2) == 0 {
+					cell.Neg(cell)
+				}
+			}
+			records[i][j] = cell.String()
+		}
+	}
+	return records
+}
+
+// checkMulMatchesNaive multiplies a rows x inner by an inner x cols matrix
+// with opts and compares the result with mulNaive.
+func checkMulMatchesNaive[T any](t *testing.T, ring Ring[T], rows, inner, cols, digits int, opts MulOptions) {
+	t.Helper()
+	a, err := ParseDense(ring, randomRecords(rows, inner, digits, 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := ParseDense(ring, randomRecords(inner, cols, digits, 2))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := a.MulWith(b, opts)
+	if err != nil {
+		t.Fatalf("MulWith() error = %v", err)
+	}
+	if expected := a.mulNaive(b); !reflect.DeepEqual(got.Records(), expected.Records()) {
+		t.Errorf("MulWith() differs from the naive product")
+	}
+}
+
+func TestMulMatchesNaive(t *testing.T) {
+	gf, err := NewPrimeField(1_000_000_007)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	shapes := [][3]int{{1, 1, 1}, {3, 5, 2}, {8, 8, 8}, {13, 9, 11}, {17, 16, 15}}
+	options := []MulOptions{
+		{},
+		{BlockSize: 1, Workers: 1},
+		{BlockSize: 3, Workers: 4},
+		{BlockSize: 4, StrassenThreshold: 4, Workers: 4},
+		{BlockSize: 2, StrassenThreshold: 2, Workers: 1},
+	}
+
+	for _, shape := range shapes {
+		for _, opts := range options {
+			name := fmt.Sprintf("%dx%dx%d %+v", shape[0], shape[1], shape[2], opts)
+			t.Run(name, func(t *testing.T) {
+				checkMulMatchesNaive[*big.Int](t, BigIntRing{}, shape[0], shape[1], shape[2], 40, opts)
+				checkMulMatchesNaive[*big.Rat](t, RationalField{}, shape[0], shape[1], shape[2], 5, opts)
+				checkMulMatchesNaive[uint64](t, gf, shape[0], shape[1], shape[2], 12, opts)
+				checkMulMatchesNaive[int64](t, Int64Ring{}, shape[0], shape[1], shape[2], 4, opts)
+				checkMulMatchesNaive[float64](t, Float64Field{}, shape[0], shape[1], shape[2], 17, opts)
+			})
+		}
+	}
+}
+
+func TestMulOverflow(t *testing.T) {
+	records := [][]string{{"9223372036854775807", "1"}, {"1", "1"}}
+	a, err := ParseDense[int64](Int64Ring{}, records)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := a.MulWith(a, MulOptions{BlockSize: 1, Workers: 4}); !errors.Is(err, ErrOverflow) {
+		t.Errorf("MulWith() error = %v, want %v", err, ErrOverflow)
+	}
+}
+
+func TestStrassenSafe(t *testing.T) {
+	if !strassenSafe[*big.Int](BigIntRing{}) || !strassenSafe[*big.Rat](RationalField{}) {
+		t.Errorf("strassenSafe() = false for an exact ring")
+	}
+	if strassenSafe[int64](Int64Ring{}) || strassenSafe[float64](Float64Field{}) || strassenSafe[complex128](Complex128Field{}) {
+		t.Errorf("strassenSafe() = true for a ring that can overflow or round")
+	}
+}
+
+func benchmarkMul(b *testing.B, n int, opts MulOptions) {
+	ring := BigIntRing{}
+	x, _ := ParseDense[*big.Int](ring, randomRecords(n, n, 30, 1))
+	y, _ := ParseDense[*big.Int](ring, randomRecords(n, n, 30, 2))
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		x.MulWith(y, opts)
+	}
+}
+
+func BenchmarkMulNaive256(b *testing.B) {
+	ring := BigIntRing{}
+	x, _ := ParseDense[*big.Int](ring, randomRecords(256, 256, 30, 1))
+	y, _ := ParseDense[*big.Int](ring, randomRecords(256, 256, 30, 2))
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		x.mulNaive(y)
+	}
+}
+
+func BenchmarkMulBlocked256(b *testing.B) {
+	benchmarkMul(b, 256, MulOptions{BlockSize: 64})
+}
+
+func BenchmarkMulStrassen256(b *testing.B) {
+	benchmarkMul(b, 256, MulOptions{BlockSize: 64, StrassenThreshold: 128})
+}
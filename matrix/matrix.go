@@ -0,0 +1,288 @@
+package matrix
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// Matrix is an integer matrix that has been parsed and validated once. The
+// cells are stored contiguously in row-major order, as int64 while every
+// cell fits and promoted to big.Int otherwise.
+type Matrix struct {
+	rows, cols int
+	small      []int64   // cells while every cell fits in an int64
+	data       []big.Int // cells once the matrix has been promoted
+}
+
+// New returns a rows x cols matrix filled with zeros.
+func New(rows, cols int) *Matrix {
+	return &Matrix{rows: rows, cols: cols, small: make([]int64, rows*cols)}
+}
+
+// Parse validates the records and converts them into a Matrix. Every row must
+// have the same length and every cell must be a base 10 integer. Large
+// matrices are parsed in row chunks by Workers goroutines; the error reported
+// is still the one of the first bad cell in row-major order.
+func Parse(records [][]string) (*Matrix, error) {
+	return ParseContext(context.Background(), records)
+}
+
+// ParseContext is Parse checking ctx after every row, it fails with ctx's
+// error once ctx is done.
+func ParseContext(ctx context.Context, records [][]string) (*Matrix, error) {
+	if len(records) == 0 {
+		return New(0, 0), nil
+	}
+
+	if len(records)*len(records[0]) >= parallelMinCells && Workers > 1 {
+		return parseParallel(ctx, records)
+	}
+
+	m := New(len(records), len(records[0]))
+	progress := trackProgress(ctx, "parse", m.rows)
+	for i, row := range records {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		progress.set(i)
+		if len(row) != m.cols {
+			return nil, rowLengthError(i)
+		}
+
+		for j, val := range row {
+			if m.small != nil {
+				integer, err := strconv.ParseInt(val, 10, 64)
+				if err == nil {
+					m.small[i*m.cols+j] = integer
+					continue
+				}
+				if !errors.Is(err, strconv.ErrRange) {
+					return nil, fmt.Errorf("invalid number at position [%d,%d]", i, j)
+				}
+				m.promote()
+			}
+
+			integer, err := parseInteger(val, i, j)
+			if err != nil {
+				return nil, err
+			}
+			m.data[i*m.cols+j].Set(integer)
+		}
+	}
+	progress.set(m.rows)
+
+	return m, nil
+}
+
+// Validate checks the records as Parse does, failing with the same errors,
+// without converting the cells. It suits the operations that answer the
+// cells as they were written.
+func Validate(records [][]string) error {
+	return ValidateContext(context.Background(), records)
+}
+
+// ValidateContext is Validate checking ctx after every row.
+func ValidateContext(ctx context.Context, records [][]string) error {
+	if len(records) == 0 {
+		return nil
+	}
+
+	cols := len(records[0])
+	progress := trackProgress(ctx, "parse", len(records))
+	for i, row := range records {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		progress.set(i)
+		if len(row) != cols {
+			return rowLengthError(i)
+		}
+		for j, val := range row {
+			if err := checkCell(val, i, j); err != nil {
+				return err
+			}
+		}
+	}
+	progress.set(len(records))
+	return nil
+}
+
+func rowLengthError(i int) error {
+	return fmt.Errorf("invalid matrix: inconsistent row length at row %d", i)
+}
+
+// promote moves the cells from int64 to big.Int storage.
+func (m *Matrix) promote() {
+	if m.small == nil {
+		return
+	}
+
+	m.data = make([]big.Int, len(m.small))
+	for k, cell := range m.small {
+		m.data[k].SetInt64(cell)
+	}
+	m.small = nil
+}
+
+// Rows returns the number of rows.
+func (m *Matrix) Rows() int {
+	return m.rows
+}
+
+// Cols returns the number of columns.
+func (m *Matrix) Cols() int {
+	return m.cols
+}
+
+// At returns the cell at position [i,j]. The result must not be modified.
+func (m *Matrix) At(i, j int) *big.Int {
+	if m.small != nil {
+		return big.NewInt(m.small[i*m.cols+j])
+	}
+	return &m.data[i*m.cols+j]
+}
+
+// Set stores a copy of x at position [i,j].
+func (m *Matrix) Set(i, j int, x *big.Int) {
+	if m.small != nil {
+		if x.IsInt64() {
+			m.small[i*m.cols+j] = x.Int64()
+			return
+		}
+		m.promote()
+	}
+	m.data[i*m.cols+j].Set(x)
+}
+
+// Transpose returns a new matrix with rows and columns swapped. The cells are
+// copied in square tiles so reads and writes both stay cache friendly; use T
+// for a view that does not copy at all.
+func (m *Matrix) Transpose() *Matrix {
+	transposed := &Matrix{rows: m.cols, cols: m.rows}
+	if m.small != nil {
+		transposed.small = make([]int64, len(m.small))
+	} else {
+		transposed.data = make([]big.Int, len(m.data))
+	}
+
+	for bi := 0; bi < m.rows; bi += transposeBlock {
+		for bj := 0; bj < m.cols; bj += transposeBlock {
+			for i := bi; i < min(bi+transposeBlock, m.rows); i++ {
+				for j := bj; j < min(bj+transposeBlock, m.cols); j++ {
+					if m.small != nil {
+						transposed.small[j*m.rows+i] = m.small[i*m.cols+j]
+					} else {
+						transposed.data[j*m.rows+i].Set(&m.data[i*m.cols+j])
+					}
+				}
+			}
+		}
+	}
+	return transposed
+}
+
+// Flatten returns a single row matrix with the cells in row-major order.
+func (m *Matrix) Flatten() *Matrix {
+	flattened := &Matrix{rows: 1, cols: m.rows * m.cols}
+	if m.small != nil {
+		flattened.small = append([]int64(nil), m.small...)
+		return flattened
+	}
+
+	flattened.data = make([]big.Int, len(m.data))
+	for k := range m.data {
+		flattened.data[k].Set(&m.data[k])
+	}
+	return flattened
+}
+
+// Sum returns the sum of all cells, 0 for an empty matrix. Small matrices are
+// summed in int64 until the running sum overflows, large ones in chunks by
+// Workers goroutines.
+func (m *Matrix) Sum() *big.Int {
+	if len(m.small)+len(m.data) >= parallelMinCells && Workers > 1 {
+		return m.sumParallel()
+	}
+
+	var sum intSum
+	for _, cell := range m.small {
+		sum.addInt64(cell)
+	}
+	for k := range m.data {
+		sum.addBig(&m.data[k])
+	}
+	return sum.result()
+}
+
+// Product returns the product of all cells, 1 for an empty matrix. Runs of
+// small cells are multiplied in int64 and the resulting factors are combined
+// with a parallel product tree.
+func (m *Matrix) Product() *big.Int {
+	product, _ := m.ProductContext(context.Background())
+	return product
+}
+
+// ProductContext is Product for long computations, it stops early with ctx's
+// error once ctx is done.
+func (m *Matrix) ProductContext(ctx context.Context) (*big.Int, error) {
+	// If multiplying by zero, return early
+	for _, cell := range m.small {
+		if cell == 0 {
+			return big.NewInt(0), nil
+		}
+	}
+	for k := range m.data {
+		if m.data[k].Sign() == 0 {
+			return big.NewInt(0), nil
+		}
+	}
+
+	if m.small != nil {
+		return productTree(ctx, packInt64Factors(m.small), Workers)
+	}
+
+	factors := make([]*big.Int, len(m.data))
+	for k := range m.data {
+		factors[k] = &m.data[k]
+	}
+	return productTree(ctx, factors, Workers)
+}
+
+// text formats the k-th cell in row-major order.
+func (m *Matrix) text(k int) string {
+	if m.small != nil {
+		return strconv.FormatInt(m.small[k], 10)
+	}
+	return m.data[k].Text(10)
+}
+
+// Records formats the matrix back into CSV records.
+func (m *Matrix) Records() [][]string {
+	records := make([][]string, m.rows)
+	for i := range records {
+		records[i] = make([]string, m.cols)
+		for j := range records[i] {
+			records[i][j] = m.text(i*m.cols + j)
+		}
+	}
+	return records
+}
+
+// String formats the matrix as CSV, one line per row.
+func (m *Matrix) String() string {
+	var builder strings.Builder
+	for i := 0; i < m.rows; i++ {
+		for j := 0; j < m.cols; j++ {
+			if j > 0 {
+				builder.WriteByte(',')
+			}
+			builder.WriteString(m.text(i*m.cols + j))
+		}
+		builder.WriteByte('\n')
+	}
+	return builder.String()
+}
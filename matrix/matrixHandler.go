@@ -1,173 +1,121 @@
 package matrix
 
 import (
+	"context"
 	"fmt"
 	"math/big"
 	"strings"
 )
 
-func InvertMatrix(matrix [][]string) ([][]string, error) {
-	if len(matrix) == 0 {
-		return nil, nil
+// parseInteger parses the cell at position [i,j] as a base 10 integer.
+func parseInteger(val string, i, j int) (*big.Int, error) {
+	integer, ok := new(big.Int).SetString(val, 10)
+	if !ok {
+		return nil, fmt.Errorf("invalid number at position [%d,%d]", i, j)
 	}
+	return integer, nil
+}
 
-	rows := len(matrix)
-	cols := len(matrix[0])
+func InvertMatrix(matrix [][]string) ([][]string, error) {
+	return InvertMatrixContext(context.Background(), matrix)
+}
 
-	if rows == 1 && cols == 1 {
-		return [][]string{{matrix[0][0]}}, nil
+// InvertMatrixContext is InvertMatrix stopping with ctx's error once ctx is done.
+// The cells are validated and returned as they were written.
+func InvertMatrixContext(ctx context.Context, matrix [][]string) ([][]string, error) {
+	if len(matrix) == 0 {
+		return nil, nil
 	}
 
-	inverted := make([][]string, cols)
-	for i := range inverted {
-		inverted[i] = make([]string, rows)
+	if err := checkRows(matrix); err != nil {
+		return nil, err
 	}
-
-	for j := 0; j < cols; j++ {
-		for i := 0; i < rows; i++ {
-			if len(matrix[i]) != cols {
-				return nil, fmt.Errorf("invalid matrix: inconsistent rows and columns")
-			}
-			// Validate number
-			_, ok := new(big.Int).SetString(matrix[i][j], 10)
-			if !ok {
-				return nil, fmt.Errorf("invalid number at position [%d,%d]", i, j)
-			}
-
-			inverted[j][i] = matrix[i][j]
-		}
+	if err := ValidateContext(ctx, matrix); err != nil {
+		return nil, err
 	}
 
-	return inverted, nil
+	return Records(Cells(matrix).T()), nil
 }
 
 func FlattenMatrix(matrix [][]string) (string, error) {
+	return FlattenMatrixContext(context.Background(), matrix)
+}
+
+// FlattenMatrixContext is FlattenMatrix stopping with ctx's error once ctx is done.
+// The cells are validated and returned as they were written.
+func FlattenMatrixContext(ctx context.Context, matrix [][]string) (string, error) {
 	if len(matrix) == 0 {
 		return "", nil
 	}
 
-	rows := len(matrix)
-	if rows == 0 {
-		return "", nil
+	if err := checkRows(matrix); err != nil {
+		return "", err
 	}
-
-	cols := len(matrix[0])
-
-	if rows == 1 && cols == 1 {
-		return matrix[0][0] + "\n", nil
+	if err := ValidateContext(ctx, matrix); err != nil {
+		return "", err
 	}
 
-	// Calculate exact capacity needed
-	// Formula: sum of lengths of all strings + (rows*cols - 1) commas + 1 newline
-	totalCap := 0
+	var flattened strings.Builder
+	WriteFlat(&flattened, Cells(matrix))
+	return flattened.String(), nil
+}
+
+// checkRows returns the error InvertMatrix and FlattenMatrix have always
+// returned for rows of different lengths.
+func checkRows(matrix [][]string) error {
 	for _, row := range matrix {
-		if len(row) != cols {
-			return "", fmt.Errorf("invalid matrix: inconsistent rows and columns")
-		}
-		for _, val := range row {
-			totalCap += len(val)
+		if len(row) != len(matrix[0]) {
+			return fmt.Errorf("invalid matrix: inconsistent rows and columns")
 		}
 	}
-	totalCap += (rows*cols - 1) + 1 // Add space for commas and newline
-
-	// Initialize builder with exact capacity
-	var flattenBuilder strings.Builder
-	flattenBuilder.Grow(totalCap)
-
-	// Flatten matrix efficiently
-	for i, row := range matrix {
-		for j, val := range row {
-			// Validate string content
-			if strings.ContainsAny(val, ",\n") {
-				return "", fmt.Errorf("invalid character in matrix at position [%d,%d]: value contains comma or newline", i, j)
-			}
-
-			// Validate number
-			_, ok := new(big.Int).SetString(matrix[i][j], 10)
-			if !ok {
-				return "", fmt.Errorf("invalid number at position [%d,%d]", i, j)
-			}
-
-			flattenBuilder.WriteString(val)
-
-			// Add comma if not the last element
-			if !(i == rows-1 && j == cols-1) {
-				flattenBuilder.WriteByte(',')
-			}
-		}
-	}
-
-	flattenBuilder.WriteByte('\n')
-	return flattenBuilder.String(), nil
+	return nil
 }
 
 func SumMatrix(matrix [][]string) (string, error) {
+	return SumMatrixContext(context.Background(), matrix)
+}
+
+// SumMatrixContext is SumMatrix stopping with ctx's error once ctx is done.
+func SumMatrixContext(ctx context.Context, matrix [][]string) (string, error) {
 	if len(matrix) == 0 {
 		return "0", nil
 	}
 
-	cols := len(matrix[0])
-	if cols == 0 {
+	if len(matrix[0]) == 0 {
 		return "", fmt.Errorf("invalid matrix: empty row found")
 	}
 
-	// Initialize result based on operation
-	result := big.NewInt(0)
-
-	// Process matrix elements
-	for i, row := range matrix {
-		for j, val := range row {
-			if len(row) != cols {
-				return "", fmt.Errorf("invalid matrix: inconsistent row length at row %d", i)
-			}
-
-			// Validate and parse number
-			integer, ok := new(big.Int).SetString(val, 10)
-			if !ok {
-				return "", fmt.Errorf("invalid number at position [%d,%d]", i, j)
-			}
-
-			result.Add(result, integer)
-		}
+	m, err := ParseContext(ctx, matrix)
+	if err != nil {
+		return "", err
 	}
 
-	return result.Text(10), nil
+	return m.Sum().Text(10), nil
 }
 
 func MultiplyMatrix(matrix [][]string) (string, error) {
+	return MultiplyMatrixContext(context.Background(), matrix)
+}
+
+// MultiplyMatrixContext is MultiplyMatrix stopping with ctx's error once ctx
+// is done, so that a cancelled request does not keep computing.
+func MultiplyMatrixContext(ctx context.Context, matrix [][]string) (string, error) {
 	if len(matrix) == 0 {
 		return "0", nil
 	}
 
-	cols := len(matrix[0])
-	if cols == 0 {
+	if len(matrix[0]) == 0 {
 		return "", fmt.Errorf("invalid matrix: empty row found")
 	}
 
-	// Initialize result
-	result := big.NewInt(1)
-
-	// Process matrix elements
-	for i, row := range matrix {
-		for j, val := range row {
-			if len(row) != cols {
-				return "", fmt.Errorf("invalid matrix: inconsistent row length at row %d", i)
-			}
-
-			// Validate and parse number
-			integer, ok := new(big.Int).SetString(val, 10)
-			if !ok {
-				return "", fmt.Errorf("invalid number at position [%d,%d]", i, j)
-			}
-
-			// 	If multiplying by zero, return early
-			if integer.Cmp(big.NewInt(0)) == 0 {
-				return "0", nil
-			}
-
-			result.Mul(result, integer)
-		}
+	m, err := ParseContext(ctx, matrix)
+	if err != nil {
+		return "", err
 	}
 
-	return result.Text(10), nil
+	product, err := m.ProductContext(ctx)
+	if err != nil {
+		return "", err
+	}
+	return product.Text(10), nil
 }
@@ -454,3 +454,24 @@ func TestMultiplyMatrix(t *testing.T) {
 		})
 	}
 }
+
+func TestInvertAndFlattenKeepCells(t *testing.T) {
+	records := [][]string{{"004", "+5"}, {"1", "-0"}}
+
+	inverted, err := InvertMatrix(records)
+	if expected := [][]string{{"004", "1"}, {"+5", "-0"}}; err != nil || !reflect.DeepEqual(inverted, expected) {
+		t.Errorf("InvertMatrix() = %v, %v, want %v", inverted, err, expected)
+	}
+	if flattened, err := FlattenMatrix(records); err != nil || flattened != "004,+5,1,-0\n" {
+		t.Errorf("FlattenMatrix() = %q, %v, want %q", flattened, err, "004,+5,1,-0\n")
+	}
+
+	ragged := [][]string{{"1", "2"}, {"3"}}
+	expected := "invalid matrix: inconsistent rows and columns"
+	if _, err := InvertMatrix(ragged); err == nil || err.Error() != expected {
+		t.Errorf("InvertMatrix() error = %v, want %q", err, expected)
+	}
+	if _, err := FlattenMatrix(ragged); err == nil || err.Error() != expected {
+		t.Errorf("FlattenMatrix() error = %v, want %q", err, expected)
+	}
+}
@@ -0,0 +1,216 @@
+package matrix
+
+import (
+	"context"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name         string
+		input        [][]string
+		expectedRows int
+		expectedCols int
+		expectError  bool
+	}{
+		{
+			name:         "Empty matrix",
+			input:        [][]string{},
+			expectedRows: 0,
+			expectedCols: 0,
+		},
+		{
+			name:         "2x3 matrix",
+			input:        [][]string{{"1", "2", "3"}, {"4", "5", "6"}},
+			expectedRows: 2,
+			expectedCols: 3,
+		},
+		{
+			name:         "Matrix with empty rows",
+			input:        [][]string{{}, {}},
+			expectedRows: 2,
+			expectedCols: 0,
+		},
+		{
+			name:        "Inconsistent rows",
+			input:       [][]string{{"1", "2"}, {"3"}},
+			expectError: true,
+		},
+		{
+			name:        "Invalid number",
+			input:       [][]string{{"1", "x"}},
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := Parse(tt.input)
+
+			if tt.expectError {
+				if err == nil {
+					t.Errorf("Expected error but got none")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Errorf("Unexpected error: %v", err)
+				return
+			}
+
+			if m.Rows() != tt.expectedRows || m.Cols() != tt.expectedCols {
+				t.Errorf("shape = %dx%d, want %dx%d", m.Rows(), m.Cols(), tt.expectedRows, tt.expectedCols)
+			}
+		})
+	}
+}
+
+func TestMatrixMethods(t *testing.T) {
+	m, err := Parse([][]string{{"1", "-2", "3"}, {"004", "5", "99999999999999999999"}})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if got := m.At(1, 0).Text(10); got != "4" {
+		t.Errorf("At(1, 0) = %v, want 4", got)
+	}
+
+	transposed := [][]string{{"1", "4"}, {"-2", "5"}, {"3", "99999999999999999999"}}
+	if got := m.Transpose().Records(); !reflect.DeepEqual(got, transposed) {
+		t.Errorf("Transpose() = %v, want %v", got, transposed)
+	}
+
+	if got := m.Flatten().String(); got != "1,-2,3,4,5,99999999999999999999\n" {
+		t.Errorf("Flatten() = %q", got)
+	}
+
+	if got := m.Sum().Text(10); got != "100000000000000000010" {
+		t.Errorf("Sum() = %v", got)
+	}
+
+	if got := m.Product().Text(10); got != "-11999999999999999999880" {
+		t.Errorf("Product() = %v", got)
+	}
+
+	m.Set(0, 0, m.At(0, 1))
+	if got := m.At(0, 0).Text(10); got != "-2" {
+		t.Errorf("Set(0, 0) = %v, want -2", got)
+	}
+}
+
+func TestMatrixPromotion(t *testing.T) {
+	tests := []struct {
+		name            string
+		input           [][]string
+		expectedSum     string
+		expectedProduct string
+	}{
+		{
+			name:            "Fits in int64",
+			input:           [][]string{{"1", "2"}, {"3", "-4"}},
+			expectedSum:     "2",
+			expectedProduct: "-24",
+		},
+		{
+			name:            "Sum overflows",
+			input:           [][]string{{"9223372036854775807", "9223372036854775807", "-1"}},
+			expectedSum:     "18446744073709551613",
+			expectedProduct: "-85070591730234615847396907784232501249",
+		},
+		{
+			name:            "Negative sum overflows",
+			input:           [][]string{{"-9223372036854775808", "-1"}},
+			expectedSum:     "-9223372036854775809",
+			expectedProduct: "9223372036854775808",
+		},
+		{
+			name:            "Cell does not fit",
+			input:           [][]string{{"1", "9223372036854775808"}, {"-9223372036854775809", "0"}},
+			expectedSum:     "0",
+			expectedProduct: "0",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := Parse(tt.input)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			if got := m.Sum().Text(10); got != tt.expectedSum {
+				t.Errorf("Sum() = %v, want %v", got, tt.expectedSum)
+			}
+			if got := m.Product().Text(10); got != tt.expectedProduct {
+				t.Errorf("Product() = %v, want %v", got, tt.expectedProduct)
+			}
+			if got := m.Transpose().Transpose().Records(); !reflect.DeepEqual(got, tt.input) {
+				t.Errorf("Transpose().Transpose() = %v, want %v", got, tt.input)
+			}
+		})
+	}
+}
+
+// smallIntegerRecords builds the n x n matrix of small integers typical of uploads.
+func smallIntegerRecords(n int, cell func(i, j int) int) [][]string {
+	records := make([][]string, n)
+	for i := range records {
+		records[i] = make([]string, n)
+		for j := range records[i] {
+			records[i][j] = strconv.Itoa(cell(i, j))
+		}
+	}
+	return records
+}
+
+// Compares the int64 fast path against big.Int arithmetic on a 1000x1000 file.
+func BenchmarkSum1000(b *testing.B) {
+	records := smallIntegerRecords(1000, func(i, j int) int { return (i*1000+j)%199 - 99 })
+	bigint, _ := LookupDomain("bigint")
+
+	b.Run("int64 fast path", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			_, _ = SumMatrix(records)
+		}
+	})
+	b.Run("big.Int", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			_, _ = bigint.Sum(context.Background(), records)
+		}
+	})
+}
+
+func BenchmarkMultiply1000(b *testing.B) {
+	records := smallIntegerRecords(1000, func(i, j int) int { return 1 - 2*((i+j)%2) })
+	bigint, _ := LookupDomain("bigint")
+
+	b.Run("int64 fast path", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			_, _ = MultiplyMatrix(records)
+		}
+	})
+	b.Run("big.Int", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			_, _ = bigint.Product(context.Background(), records)
+		}
+	})
+}
+
+func TestValidateMatchesParse(t *testing.T) {
+	cells := []string{"0", "-7", "+7", "007", "99999999999999999999", "-99999999999999999999", "", "+", "-", "1.5", " 1", "1_000", "0x10", "1e3", "--1", "١"}
+	inputs := [][][]string{nil, {{"1", "2"}, {"3"}}, {{"1", "2"}, {"3", "4"}}}
+	for _, cell := range cells {
+		inputs = append(inputs, [][]string{{"1", cell}})
+	}
+
+	for _, input := range inputs {
+		_, parseErr := Parse(input)
+		err := Validate(input)
+		if (err == nil) != (parseErr == nil) || err != nil && err.Error() != parseErr.Error() {
+			t.Errorf("Validate(%q) = %v, Parse() error = %v", input, err, parseErr)
+		}
+	}
+}
@@ -0,0 +1,147 @@
+package matrix
+
+import (
+	"context"
+	"math/big"
+	"sync"
+	"sync/atomic"
+)
+
+// parallelMinCells is the matrix size below which Parse and Sum stay
+// sequential; smaller matrices finish before goroutines pay off.
+const parallelMinCells = 1 << 16
+
+// parallelChunkCells is roughly the number of cells handed to a worker at a time.
+const parallelChunkCells = 1 << 14
+
+// forEachChunk splits [0, n) into chunks of size items and calls fn on them
+// from up to workers goroutines. Once a chunk fails, later chunks are skipped
+// and stop reports true to the ones still running, but every chunk before it
+// still runs, so the returned error is always the one of the earliest failing
+// chunk, exactly as a sequential scan would report it.
+func forEachChunk(n, size, workers int, fn func(start, end int, stop func() bool) error) error {
+	chunks := (n + size - 1) / size
+	errs := make([]error, chunks)
+
+	var next atomic.Int64
+	var failed atomic.Int64
+	failed.Store(int64(chunks))
+
+	var wg sync.WaitGroup
+	for w := 0; w < min(max(workers, 1), chunks); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				c := next.Add(1) - 1
+				if c >= int64(chunks) {
+					return
+				}
+				if c > failed.Load() {
+					continue
+				}
+
+				stop := func() bool { return failed.Load() < c }
+				if err := fn(int(c)*size, min(int(c+1)*size, n), stop); err != nil {
+					errs[c] = err
+					for {
+						current := failed.Load()
+						if c >= current || failed.CompareAndSwap(current, c) {
+							break
+						}
+					}
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// parseParallel is Parse for large matrices: rows are parsed in chunks by
+// Workers goroutines. Cells that do not fit in an int64 are collected per
+// chunk and the matrix is promoted once all chunks are done.
+func parseParallel(ctx context.Context, records [][]string) (*Matrix, error) {
+	m := New(len(records), len(records[0]))
+	chunkRows := max(parallelChunkCells/max(m.cols, 1), 1)
+	large := make([]map[int]*big.Int, (m.rows+chunkRows-1)/chunkRows)
+	progress := trackProgress(ctx, "parse", m.rows)
+
+	err := forEachChunk(m.rows, chunkRows, Workers, func(start, end int, stop func() bool) error {
+		for i := start; i < end; i++ {
+			if stop() {
+				return nil
+			}
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+
+			row := records[i]
+			if len(row) != m.cols {
+				return rowLengthError(i)
+			}
+
+			for j, val := range row {
+				small, integer, err := parseCell(val, i, j)
+				if err != nil {
+					return err
+				}
+				if integer != nil {
+					chunk := start / chunkRows
+					if large[chunk] == nil {
+						large[chunk] = map[int]*big.Int{}
+					}
+					large[chunk][i*m.cols+j] = integer
+					continue
+				}
+				m.small[i*m.cols+j] = small
+			}
+		}
+		progress.add(end - start)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, cells := range large {
+		for k, integer := range cells {
+			m.promote()
+			m.data[k].Set(integer)
+		}
+	}
+	return m, nil
+}
+
+// sumParallel sums the cells in chunks and merges the partial sums.
+func (m *Matrix) sumParallel() *big.Int {
+	n := len(m.small) + len(m.data)
+	partials := make([]intSum, (n+parallelChunkCells-1)/parallelChunkCells)
+
+	forEachChunk(n, parallelChunkCells, Workers, func(start, end int, stop func() bool) error {
+		sum := &partials[start/parallelChunkCells]
+		for _, cell := range m.small[min(start, len(m.small)):min(end, len(m.small))] {
+			sum.addInt64(cell)
+		}
+		for k := min(start, len(m.data)); k < min(end, len(m.data)); k++ {
+			sum.addBig(&m.data[k])
+		}
+		return nil
+	})
+
+	var total intSum
+	for k := range partials {
+		if partials[k].large != nil {
+			total.addBig(partials[k].large)
+		} else {
+			total.addInt64(partials[k].small)
+		}
+	}
+	return total.result()
+}
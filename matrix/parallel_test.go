@@ -0,0 +1,161 @@
+package matrix
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestForEachChunkReportsEarliestError(t *testing.T) {
+	for _, workers := range []int{1, 2, 8} {
+		t.Run(fmt.Sprintf("%d workers", workers), func(t *testing.T) {
+			err := forEachChunk(100, 10, workers, func(start, end int, stop func() bool) error {
+				switch start {
+				case 30:
+					// Fails after a later chunk has already failed.
+					time.Sleep(10 * time.Millisecond)
+					return errors.New("chunk 3")
+				case 70:
+					return errors.New("chunk 7")
+				}
+				return nil
+			})
+
+			if err == nil || err.Error() != "chunk 3" {
+				t.Errorf("forEachChunk() = %v, want chunk 3", err)
+			}
+		})
+	}
+}
+
+func TestForEachChunkSkipsChunksAfterFailure(t *testing.T) {
+	var visited [10]bool
+	forEachChunk(10, 1, 1, func(start, end int, stop func() bool) error {
+		visited[start] = true
+		if start == 2 {
+			return errors.New("chunk 2")
+		}
+		return nil
+	})
+
+	for c, ok := range visited {
+		if ok != (c <= 2) {
+			t.Errorf("chunk %d visited = %v", c, ok)
+		}
+	}
+}
+
+// largeRecords returns a matrix big enough to take the parallel paths.
+func largeRecords(rows, cols int) [][]string {
+	records := make([][]string, rows)
+	for i := range records {
+		records[i] = make([]string, cols)
+		for j := range records[i] {
+			records[i][j] = strconv.Itoa((i*cols+j)%1000 - 500)
+		}
+	}
+	return records
+}
+
+func withWorkers(t testing.TB, workers int) {
+	previous := Workers
+	Workers = workers
+	t.Cleanup(func() { Workers = previous })
+}
+
+func TestParseParallel(t *testing.T) {
+	withWorkers(t, 4)
+
+	tests := []struct {
+		name     string
+		edit     func(records [][]string)
+		expected string
+	}{
+		{
+			name:     "Valid matrix",
+			edit:     func(records [][]string) {},
+			expected: "",
+		},
+		{
+			name: "Earliest invalid cell is reported",
+			edit: func(records [][]string) {
+				records[900][3] = "x"
+				records[5][7] = "y"
+				records[5][9] = "z"
+			},
+			expected: "invalid number at position [5,7]",
+		},
+		{
+			name: "Ragged row before invalid cell",
+			edit: func(records [][]string) {
+				records[700][1] = "x"
+				records[600] = records[600][:10]
+			},
+			expected: "invalid matrix: inconsistent row length at row 600",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			records := largeRecords(1000, 100)
+			tt.edit(records)
+
+			_, err := Parse(records)
+			if tt.expected == "" {
+				if err != nil {
+					t.Errorf("Unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || err.Error() != tt.expected {
+				t.Errorf("Parse() error = %v, want %q", err, tt.expected)
+			}
+		})
+	}
+}
+
+func TestParallelMatchesSequential(t *testing.T) {
+	records := largeRecords(1000, 100)
+	records[10][10] = "99999999999999999999999"
+	records[999][99] = "-99999999999999999999999"
+	records[500][0] = "9223372036854775807"
+
+	withWorkers(t, 1)
+	sequential, err := Parse(records)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	Workers = 4
+	parallel, err := Parse(records)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if sequential.String() != parallel.String() {
+		t.Errorf("parallel Parse() differs from sequential Parse()")
+	}
+
+	Workers = 1
+	expected := sequential.Sum()
+	Workers = 4
+	if got := parallel.Sum(); got.Cmp(expected) != 0 {
+		t.Errorf("parallel Sum() = %v, want %v", got, expected)
+	}
+}
+
+// Sums a matrix of ten million cells sequentially and with all workers.
+func BenchmarkSumMatrix10M(b *testing.B) {
+	records := largeRecords(10000, 1000)
+
+	for _, workers := range []int{1, Workers} {
+		b.Run(fmt.Sprintf("%d workers", workers), func(b *testing.B) {
+			withWorkers(b, workers)
+			for i := 0; i < b.N; i++ {
+				_, _ = SumMatrix(records)
+			}
+		})
+	}
+}
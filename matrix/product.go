@@ -0,0 +1,91 @@
+package matrix
+
+import (
+	"context"
+	"math/big"
+	"runtime"
+	"sync"
+)
+
+// Workers bounds the goroutines used by the parallel algorithms in this
+// package. It defaults to GOMAXPROCS.
+var Workers = runtime.GOMAXPROCS(0)
+
+// productLeafSize is the number of factors below which productTree multiplies
+// sequentially; splitting small products costs more than it saves.
+const productLeafSize = 32
+
+// productTree multiplies the factors as a balanced binary tree. Multiplying
+// operands of similar size lets big.Int use Karatsuba instead of growing one
+// accumulator digit by digit, and independent subtrees run on up to workers
+// goroutines. The factors are not modified. Once ctx is done no further
+// subtree is started and ctx's error is returned.
+func productTree(ctx context.Context, factors []*big.Int, workers int) (*big.Int, error) {
+	if len(factors) == 0 {
+		return big.NewInt(1), nil
+	}
+
+	pool := make(chan struct{}, max(workers-1, 0))
+	progress := trackProgress(ctx, "product", len(factors))
+	return productSubtree(ctx, factors, pool, progress)
+}
+
+func productSubtree(ctx context.Context, factors []*big.Int, pool chan struct{}, progress *progressTracker) (*big.Int, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(factors) <= productLeafSize {
+		result := new(big.Int).Set(factors[0])
+		for _, factor := range factors[1:] {
+			result.Mul(result, factor)
+		}
+		progress.add(len(factors))
+		return result, nil
+	}
+
+	mid := len(factors) / 2
+	var left, right *big.Int
+	var leftErr, err error
+	select {
+	case pool <- struct{}{}:
+		var wg sync.WaitGroup
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-pool }()
+			left, leftErr = productSubtree(ctx, factors[:mid], pool, progress)
+		}()
+		right, err = productSubtree(ctx, factors[mid:], pool, progress)
+		wg.Wait()
+	default:
+		left, leftErr = productSubtree(ctx, factors[:mid], pool, progress)
+		if leftErr == nil {
+			right, err = productSubtree(ctx, factors[mid:], pool, progress)
+		}
+	}
+
+	if leftErr != nil {
+		return nil, leftErr
+	}
+	if err != nil {
+		return nil, err
+	}
+	return left.Mul(left, right), nil
+}
+
+// packInt64Factors multiplies runs of consecutive cells in int64 and returns
+// one factor per run, so small cells do not each become a big.Int.
+func packInt64Factors(cells []int64) []*big.Int {
+	var factors []*big.Int
+	run := int64(1)
+	for _, cell := range cells {
+		next, ok := mulInt64(run, cell)
+		if !ok {
+			factors = append(factors, big.NewInt(run))
+			next = cell
+		}
+		run = next
+	}
+	return append(factors, big.NewInt(run))
+}
@@ -0,0 +1,128 @@
+package matrix
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"math/rand"
+	"runtime"
+	"testing"
+)
+
+// sequentialProduct is the left to right product MultiplyMatrix used to compute.
+func sequentialProduct(factors []*big.Int) *big.Int {
+	result := big.NewInt(1)
+	for _, factor := range factors {
+		result.Mul(result, factor)
+	}
+	return result
+}
+
+// randomFactors returns n random factors of the given number of digits, with random signs.
+func randomFactors(n, digits int) []*big.Int {
+	random := rand.New(rand.NewSource(int64(n)))
+	limit := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(digits)), nil)
+	factors := make([]*big.Int, n)
+	for i := range factors {
+		factors[i] = new(big.Int).Rand(random, limit)
+		factors[i].Add(factors[i], big.NewInt(1))
+		if random.Intn(2) == 0 {
+			factors[i].Neg(factors[i])
+		}
+	}
+	return factors
+}
+
+func TestProductTree(t *testing.T) {
+	for _, n := range []int{0, 1, 2, productLeafSize, productLeafSize + 1, 1000} {
+		for _, workers := range []int{0, 1, 4} {
+			t.Run(fmt.Sprintf("%d factors %d workers", n, workers), func(t *testing.T) {
+				factors := randomFactors(n, 30)
+				original := make([]string, n)
+				for i, factor := range factors {
+					original[i] = factor.String()
+				}
+
+				expected := sequentialProduct(factors)
+				if got, err := productTree(context.Background(), factors, workers); err != nil || got.Cmp(expected) != 0 {
+					t.Errorf("productTree() differs from the sequential product")
+				}
+
+				for i, factor := range factors {
+					if factor.String() != original[i] {
+						t.Fatalf("productTree() modified factor %d", i)
+					}
+				}
+			})
+		}
+	}
+}
+
+func TestProductTreeCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	for _, workers := range []int{1, 4} {
+		if _, err := productTree(ctx, randomFactors(1000, 30), workers); !errors.Is(err, context.Canceled) {
+			t.Errorf("productTree() error = %v, want %v", err, context.Canceled)
+		}
+	}
+
+	records := smallIntegerRecords(10, func(i, j int) int { return i + j + 1 })
+	if _, err := MultiplyMatrixContext(ctx, records); !errors.Is(err, context.Canceled) {
+		t.Errorf("MultiplyMatrixContext() error = %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestPackInt64Factors(t *testing.T) {
+	tests := []struct {
+		name     string
+		cells    []int64
+		expected int
+	}{
+		{name: "No cells", cells: nil, expected: 1},
+		{name: "Fits in one run", cells: []int64{2, 3, -4}, expected: 1},
+		{name: "Overflows into runs", cells: []int64{math.MaxInt64, 2, 3, math.MinInt64}, expected: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			factors := packInt64Factors(tt.cells)
+			if len(factors) != tt.expected {
+				t.Errorf("got %d factors, want %d", len(factors), tt.expected)
+			}
+
+			expected := big.NewInt(1)
+			for _, cell := range tt.cells {
+				expected.Mul(expected, big.NewInt(cell))
+			}
+			if got := sequentialProduct(factors); got.Cmp(expected) != 0 {
+				t.Errorf("product of factors = %v, want %v", got, expected)
+			}
+		})
+	}
+}
+
+// Compares the left to right product with the product tree on one core and on
+// all cores for tens of thousands of large entries.
+func BenchmarkProductTree(b *testing.B) {
+	factors := randomFactors(20000, 50)
+
+	b.Run("sequential", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			sequentialProduct(factors)
+		}
+	})
+	b.Run("tree 1 worker", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			productTree(context.Background(), factors, 1)
+		}
+	})
+	b.Run(fmt.Sprintf("tree %d workers", runtime.GOMAXPROCS(0)), func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			productTree(context.Background(), factors, runtime.GOMAXPROCS(0))
+		}
+	})
+}
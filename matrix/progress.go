@@ -0,0 +1,57 @@
+package matrix
+
+import (
+	"context"
+	"sync/atomic"
+)
+
+// Progress receives how far an operation got: done out of total units of the
+// named stage, such as rows parsed or elimination steps. A long operation
+// reports several stages in turn, "parse" then "matmul" for example. It may
+// be called from several goroutines at once.
+type Progress func(stage string, done, total int)
+
+type progressKey struct{}
+
+// WithProgress returns a context reporting the progress of the operations of
+// this package run with it to progress. A nil progress stops the reporting.
+func WithProgress(ctx context.Context, progress Progress) context.Context {
+	return context.WithValue(ctx, progressKey{}, progress)
+}
+
+// progressTracker counts the units done in one stage. A nil tracker, returned
+// when nobody listens, ignores every update.
+type progressTracker struct {
+	progress Progress
+	stage    string
+	total    int
+	done     atomic.Int64
+}
+
+// trackProgress starts reporting the stage of total units to the Progress of
+// ctx, if any.
+func trackProgress(ctx context.Context, stage string, total int) *progressTracker {
+	progress, _ := ctx.Value(progressKey{}).(Progress)
+	if progress == nil {
+		return nil
+	}
+	progress(stage, 0, total)
+	return &progressTracker{progress: progress, stage: stage, total: total}
+}
+
+// add reports n more units done. It is safe for concurrent use.
+func (t *progressTracker) add(n int) {
+	if t == nil {
+		return
+	}
+	t.progress(t.stage, int(t.done.Add(int64(n))), t.total)
+}
+
+// set reports done units done.
+func (t *progressTracker) set(done int) {
+	if t == nil {
+		return
+	}
+	t.done.Store(int64(done))
+	t.progress(t.stage, done, t.total)
+}
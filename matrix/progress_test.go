@@ -0,0 +1,122 @@
+package matrix
+
+import (
+	"context"
+	"math/big"
+	"slices"
+	"sync"
+	"testing"
+)
+
+// progressRecorder keeps the last report of every stage in the order the
+// stages started.
+type progressRecorder struct {
+	mu     sync.Mutex
+	stages []string
+	last   map[string][2]int
+}
+
+func (pr *progressRecorder) context() context.Context {
+	pr.last = map[string][2]int{}
+	return WithProgress(context.Background(), func(stage string, done, total int) {
+		pr.mu.Lock()
+		defer pr.mu.Unlock()
+		if _, ok := pr.last[stage]; !ok {
+			pr.stages = append(pr.stages, stage)
+		}
+		if previous := pr.last[stage]; done < previous[0] {
+			panic("progress went backwards in stage " + stage)
+		}
+		pr.last[stage] = [2]int{done, total}
+	})
+}
+
+func TestProgress(t *testing.T) {
+	records := randomRecords(40, 40, 5, 1)
+
+	tests := []struct {
+		name      string
+		operation func(ctx context.Context) error
+		stages    []string
+	}{
+		{
+			name: "Parse",
+			operation: func(ctx context.Context) error {
+				_, err := ParseContext(ctx, records)
+				return err
+			},
+			stages: []string{"parse"},
+		},
+		{
+			name: "Parse Parallel",
+			operation: func(ctx context.Context) error {
+				withWorkers(t, 4)
+				_, err := ParseContext(ctx, largeRecords(300, 300))
+				return err
+			},
+			stages: []string{"parse"},
+		},
+		{
+			name: "Product",
+			operation: func(ctx context.Context) error {
+				_, err := MultiplyMatrixContext(ctx, smallIntegerRecords(20, func(i, j int) int { return i*20 + j + 1 }))
+				return err
+			},
+			stages: []string{"parse", "product"},
+		},
+		{
+			name: "Determinant",
+			operation: func(ctx context.Context) error {
+				domain, _ := LookupDomain("bigint")
+				_, err := domain.Determinant(ctx, records)
+				return err
+			},
+			stages: []string{"parse", "determinant"},
+		},
+		{
+			name: "Inverse",
+			operation: func(ctx context.Context) error {
+				domain, _ := LookupDomain("gf:7")
+				_, err := domain.Inverse(ctx, [][]string{{"1", "1", "1"}, {"1", "2", "4"}, {"1", "3", "9"}})
+				return err
+			},
+			stages: []string{"parse", "inverse"},
+		},
+		{
+			name: "MatMul Blocked",
+			operation: func(ctx context.Context) error {
+				d, _ := ParseDense[*big.Int](BigIntRing{}, records)
+				_, err := d.MulContext(ctx, d, MulOptions{BlockSize: 8, Workers: 4})
+				return err
+			},
+			stages: []string{"matmul"},
+		},
+		{
+			name: "MatMul Strassen",
+			operation: func(ctx context.Context) error {
+				d, _ := ParseDense[*big.Int](BigIntRing{}, records)
+				_, err := d.MulContext(ctx, d, MulOptions{BlockSize: 8, StrassenThreshold: 8})
+				return err
+			},
+			stages: []string{"matmul"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var pr progressRecorder
+			if err := tt.operation(pr.context()); err != nil {
+				t.Fatal(err)
+			}
+
+			if !slices.Equal(pr.stages, tt.stages) {
+				t.Errorf("stages = %v, want %v", pr.stages, tt.stages)
+			}
+			for stage, last := range pr.last {
+				if last[0] != last[1] {
+					t.Errorf("stage %s ended at %d of %d", stage, last[0], last[1])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,244 @@
+package matrix
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"math/bits"
+	"math/cmplx"
+	"strconv"
+)
+
+// ErrOverflow is returned when a result does not fit in a fixed width domain.
+var ErrOverflow = errors.New("integer overflow")
+
+// Ring is a number system the generic algorithms can run over. Values are
+// treated as immutable: every operation returns a new value.
+type Ring[T any] interface {
+	Name() string
+	Zero() T
+	One() T
+	Add(a, b T) T
+	Sub(a, b T) T
+	Mul(a, b T) T
+	// Quo returns a / b. It is only used when b divides a exactly.
+	Quo(a, b T) T
+	IsZero(a T) bool
+	Parse(s string) (T, error)
+	Format(a T) string
+}
+
+// Field is a Ring where every non-zero element has a multiplicative inverse.
+type Field[T any] interface {
+	Ring[T]
+	Inv(a T) T
+}
+
+// magnituder is implemented by inexact rings so elimination can pick the
+// numerically largest pivot instead of the first non-zero one.
+type magnituder[T any] interface {
+	Magnitude(a T) float64
+}
+
+// Int64Ring is the ring of int64 values. Operations that overflow panic with
+// ErrOverflow, which the algorithms in this package turn into an error.
+type Int64Ring struct{}
+
+func (Int64Ring) Name() string          { return "int64" }
+func (Int64Ring) Zero() int64           { return 0 }
+func (Int64Ring) One() int64            { return 1 }
+func (Int64Ring) IsZero(a int64) bool   { return a == 0 }
+func (Int64Ring) Format(a int64) string { return strconv.FormatInt(a, 10) }
+
+func (Int64Ring) Add(a, b int64) int64 {
+	c, ok := addInt64(a, b)
+	if !ok {
+		panic(ErrOverflow)
+	}
+	return c
+}
+
+func (Int64Ring) Sub(a, b int64) int64 {
+	c := a - b
+	if (c < a) != (b > 0) {
+		panic(ErrOverflow)
+	}
+	return c
+}
+
+func (Int64Ring) Mul(a, b int64) int64 {
+	c, ok := mulInt64(a, b)
+	if !ok {
+		panic(ErrOverflow)
+	}
+	return c
+}
+
+func (Int64Ring) Quo(a, b int64) int64 {
+	if a == math.MinInt64 && b == -1 {
+		panic(ErrOverflow)
+	}
+	return a / b
+}
+
+func (Int64Ring) Parse(s string) (int64, error) {
+	return strconv.ParseInt(s, 10, 64)
+}
+
+// addInt64 returns a + b and whether the sum fits in an int64.
+func addInt64(a, b int64) (int64, bool) {
+	c := a + b
+	return c, (c > a) == (b > 0)
+}
+
+// mulInt64 returns a * b and whether the product fits in an int64.
+func mulInt64(a, b int64) (int64, bool) {
+	if a == 0 || b == 0 {
+		return 0, true
+	}
+	c := a * b
+	return c, c/b == a && !(b == -1 && a == math.MinInt64)
+}
+
+// BigIntRing is the ring of arbitrary precision integers.
+type BigIntRing struct{}
+
+func (BigIntRing) Name() string               { return "bigint" }
+func (BigIntRing) Zero() *big.Int             { return new(big.Int) }
+func (BigIntRing) One() *big.Int              { return big.NewInt(1) }
+func (BigIntRing) Add(a, b *big.Int) *big.Int { return new(big.Int).Add(a, b) }
+func (BigIntRing) Sub(a, b *big.Int) *big.Int { return new(big.Int).Sub(a, b) }
+func (BigIntRing) Mul(a, b *big.Int) *big.Int { return new(big.Int).Mul(a, b) }
+func (BigIntRing) Quo(a, b *big.Int) *big.Int { return new(big.Int).Quo(a, b) }
+func (BigIntRing) IsZero(a *big.Int) bool     { return a.Sign() == 0 }
+func (BigIntRing) Format(a *big.Int) string   { return a.Text(10) }
+
+func (BigIntRing) Parse(s string) (*big.Int, error) {
+	integer, ok := new(big.Int).SetString(s, 10)
+	if !ok {
+		return nil, fmt.Errorf("invalid integer %q", s)
+	}
+	return integer, nil
+}
+
+// RationalField is the field of arbitrary precision fractions.
+type RationalField struct{}
+
+func (RationalField) Name() string               { return "rational" }
+func (RationalField) Zero() *big.Rat             { return new(big.Rat) }
+func (RationalField) One() *big.Rat              { return big.NewRat(1, 1) }
+func (RationalField) Add(a, b *big.Rat) *big.Rat { return new(big.Rat).Add(a, b) }
+func (RationalField) Sub(a, b *big.Rat) *big.Rat { return new(big.Rat).Sub(a, b) }
+func (RationalField) Mul(a, b *big.Rat) *big.Rat { return new(big.Rat).Mul(a, b) }
+func (RationalField) Quo(a, b *big.Rat) *big.Rat { return new(big.Rat).Quo(a, b) }
+func (RationalField) Inv(a *big.Rat) *big.Rat    { return new(big.Rat).Inv(a) }
+func (RationalField) IsZero(a *big.Rat) bool     { return a.Sign() == 0 }
+func (RationalField) Format(a *big.Rat) string   { return a.RatString() }
+
+func (RationalField) Parse(s string) (*big.Rat, error) {
+	rational, ok := new(big.Rat).SetString(s)
+	if !ok {
+		return nil, fmt.Errorf("invalid rational %q", s)
+	}
+	return rational, nil
+}
+
+// Float64Field is the field of IEEE 754 double precision numbers.
+type Float64Field struct{}
+
+func (Float64Field) Name() string                    { return "float64" }
+func (Float64Field) Zero() float64                   { return 0 }
+func (Float64Field) One() float64                    { return 1 }
+func (Float64Field) Add(a, b float64) float64        { return a + b }
+func (Float64Field) Sub(a, b float64) float64        { return a - b }
+func (Float64Field) Mul(a, b float64) float64        { return a * b }
+func (Float64Field) Quo(a, b float64) float64        { return a / b }
+func (Float64Field) Inv(a float64) float64           { return 1 / a }
+func (Float64Field) IsZero(a float64) bool           { return a == 0 }
+func (Float64Field) Magnitude(a float64) float64     { return math.Abs(a) }
+func (Float64Field) Format(a float64) string         { return strconv.FormatFloat(a, 'g', -1, 64) }
+func (Float64Field) Parse(s string) (float64, error) { return strconv.ParseFloat(s, 64) }
+
+// Complex128Field is the field of double precision complex numbers.
+type Complex128Field struct{}
+
+func (Complex128Field) Name() string                       { return "complex128" }
+func (Complex128Field) Zero() complex128                   { return 0 }
+func (Complex128Field) One() complex128                    { return 1 }
+func (Complex128Field) Add(a, b complex128) complex128     { return a + b }
+func (Complex128Field) Sub(a, b complex128) complex128     { return a - b }
+func (Complex128Field) Mul(a, b complex128) complex128     { return a * b }
+func (Complex128Field) Quo(a, b complex128) complex128     { return a / b }
+func (Complex128Field) Inv(a complex128) complex128        { return 1 / a }
+func (Complex128Field) IsZero(a complex128) bool           { return a == 0 }
+func (Complex128Field) Magnitude(a complex128) float64     { return cmplx.Abs(a) }
+func (Complex128Field) Format(a complex128) string         { return strconv.FormatComplex(a, 'g', -1, 128) }
+func (Complex128Field) Parse(s string) (complex128, error) { return strconv.ParseComplex(s, 128) }
+
+// PrimeField is GF(p), the integers modulo the prime P.
+type PrimeField struct {
+	P uint64
+}
+
+// NewPrimeField returns GF(p) and fails when p is not prime.
+func NewPrimeField(p uint64) (PrimeField, error) {
+	if !new(big.Int).SetUint64(p).ProbablyPrime(20) {
+		return PrimeField{}, fmt.Errorf("%d is not prime", p)
+	}
+	return PrimeField{P: p}, nil
+}
+
+func (f PrimeField) Name() string         { return fmt.Sprintf("gf:%d", f.P) }
+func (f PrimeField) Zero() uint64         { return 0 }
+func (f PrimeField) One() uint64          { return 1 % f.P }
+func (f PrimeField) IsZero(a uint64) bool { return a == 0 }
+func (f PrimeField) Format(a uint64) string {
+	return strconv.FormatUint(a, 10)
+}
+
+func (f PrimeField) Add(a, b uint64) uint64 {
+	sum, carry := bits.Add64(a, b, 0)
+	if carry != 0 || sum >= f.P {
+		sum -= f.P
+	}
+	return sum
+}
+
+func (f PrimeField) Sub(a, b uint64) uint64 {
+	if a >= b {
+		return a - b
+	}
+	return f.P - (b - a)
+}
+
+func (f PrimeField) Mul(a, b uint64) uint64 {
+	hi, lo := bits.Mul64(a, b)
+	_, rem := bits.Div64(hi, lo, f.P)
+	return rem
+}
+
+func (f PrimeField) Quo(a, b uint64) uint64 {
+	return f.Mul(a, f.Inv(b))
+}
+
+// Inv uses Fermat's little theorem: a^(p-2) is the inverse of a modulo p.
+func (f PrimeField) Inv(a uint64) uint64 {
+	result, base := f.One(), a
+	for e := f.P - 2; e > 0; e >>= 1 {
+		if e&1 == 1 {
+			result = f.Mul(result, base)
+		}
+		base = f.Mul(base, base)
+	}
+	return result
+}
+
+// Parse accepts any integer and reduces it modulo P.
+func (f PrimeField) Parse(s string) (uint64, error) {
+	integer, ok := new(big.Int).SetString(s, 10)
+	if !ok {
+		return 0, fmt.Errorf("invalid integer %q", s)
+	}
+	return integer.Mod(integer, new(big.Int).SetUint64(f.P)).Uint64(), nil
+}
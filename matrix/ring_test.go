@@ -0,0 +1,89 @@
+package matrix
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestInt64RingOverflow(t *testing.T) {
+	ring := Int64Ring{}
+	tests := []struct {
+		name           string
+		op             func() int64
+		expected       int64
+		expectOverflow bool
+	}{
+		{name: "Add", op: func() int64 { return ring.Add(2, 3) }, expected: 5},
+		{name: "Add overflow", op: func() int64 { return ring.Add(math.MaxInt64, 1) }, expectOverflow: true},
+		{name: "Add negative overflow", op: func() int64 { return ring.Add(math.MinInt64, -1) }, expectOverflow: true},
+		{name: "Sub", op: func() int64 { return ring.Sub(2, 3) }, expected: -1},
+		{name: "Sub overflow", op: func() int64 { return ring.Sub(math.MinInt64, 1) }, expectOverflow: true},
+		{name: "Mul", op: func() int64 { return ring.Mul(-4, 5) }, expected: -20},
+		{name: "Mul overflow", op: func() int64 { return ring.Mul(math.MaxInt64, 2) }, expectOverflow: true},
+		{name: "Mul min by minus one", op: func() int64 { return ring.Mul(math.MinInt64, -1) }, expectOverflow: true},
+		{name: "Quo min by minus one", op: func() int64 { return ring.Quo(math.MinInt64, -1) }, expectOverflow: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func() {
+				r := recover()
+				if tt.expectOverflow {
+					if err, ok := r.(error); !ok || !errors.Is(err, ErrOverflow) {
+						t.Errorf("Expected overflow panic but got %v", r)
+					}
+					return
+				}
+				if r != nil {
+					t.Errorf("Unexpected panic: %v", r)
+				}
+			}()
+
+			if got := tt.op(); !tt.expectOverflow && got != tt.expected {
+				t.Errorf("got %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestPrimeField(t *testing.T) {
+	if _, err := NewPrimeField(12); err == nil {
+		t.Errorf("Expected error for non-prime modulus")
+	}
+
+	field, err := NewPrimeField(7)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if got := field.Add(5, 4); got != 2 {
+		t.Errorf("Add(5, 4) = %v, want 2", got)
+	}
+	if got := field.Sub(2, 5); got != 4 {
+		t.Errorf("Sub(2, 5) = %v, want 4", got)
+	}
+	if got := field.Mul(5, 4); got != 6 {
+		t.Errorf("Mul(5, 4) = %v, want 6", got)
+	}
+	for a := uint64(1); a < 7; a++ {
+		if got := field.Mul(a, field.Inv(a)); got != 1 {
+			t.Errorf("%v * Inv(%v) = %v, want 1", a, a, got)
+		}
+	}
+	if got, _ := field.Parse("-1"); got != 6 {
+		t.Errorf("Parse(-1) = %v, want 6", got)
+	}
+
+	// Large primes must not overflow in Add and Mul.
+	large, err := NewPrimeField(18446744073709551557)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if got := large.Add(large.P-1, large.P-1); got != large.P-2 {
+		t.Errorf("Add(p-1, p-1) = %v, want p-2", got)
+	}
+	if got := large.Mul(large.P-1, large.P-1); got != 1 {
+		t.Errorf("Mul(p-1, p-1) = %v, want 1", got)
+	}
+}
@@ -0,0 +1,248 @@
+package matrix
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/big"
+)
+
+// Issue kinds reported by Schema.Check.
+const (
+	IssueDimension    = "dimension"
+	IssueTypeMismatch = "type_mismatch"
+	IssueOutOfBounds  = "out_of_bounds"
+	IssueDuplicate    = "duplicate"
+)
+
+// Range is an inclusive bound on a dimension. In JSON it is either a number
+// for an exact size or an object with optional "min" and "max".
+type Range struct {
+	Min *int `json:"min,omitempty"`
+	Max *int `json:"max,omitempty"`
+}
+
+func (rg *Range) UnmarshalJSON(data []byte) error {
+	var exact int
+	if err := json.Unmarshal(data, &exact); err == nil {
+		rg.Min, rg.Max = &exact, &exact
+		return nil
+	}
+
+	type plain Range
+	return json.Unmarshal(data, (*plain)(rg))
+}
+
+func (rg *Range) contains(n int) bool {
+	return (rg.Min == nil || n >= *rg.Min) && (rg.Max == nil || n <= *rg.Max)
+}
+
+func (rg *Range) String() string {
+	switch {
+	case rg.Min != nil && rg.Max != nil && *rg.Min == *rg.Max:
+		return fmt.Sprintf("exactly %d", *rg.Min)
+	case rg.Min != nil && rg.Max != nil:
+		return fmt.Sprintf("between %d and %d", *rg.Min, *rg.Max)
+	case rg.Min != nil:
+		return fmt.Sprintf("at least %d", *rg.Min)
+	case rg.Max != nil:
+		return fmt.Sprintf("at most %d", *rg.Max)
+	}
+	return "any number of"
+}
+
+// ColumnSpec constrains the cells of a column. Type is one of CellInteger,
+// CellDecimal or CellText, Min and Max are inclusive numeric bounds.
+type ColumnSpec struct {
+	Type   string      `json:"type,omitempty"`
+	Min    json.Number `json:"min,omitempty"`
+	Max    json.Number `json:"max,omitempty"`
+	Unique bool        `json:"unique,omitempty"`
+
+	min, max *big.Rat
+}
+
+// Schema describes the matrices an operation accepts. Columns constrains the
+// columns by position, Default applies to every column without its own spec.
+type Schema struct {
+	Rows    *Range       `json:"rows,omitempty"`
+	Cols    *Range       `json:"cols,omitempty"`
+	Columns []ColumnSpec `json:"columns,omitempty"`
+	Default *ColumnSpec  `json:"default,omitempty"`
+}
+
+// ParseSchema decodes and validates a JSON schema spec.
+func ParseSchema(data []byte) (*Schema, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+
+	var schema Schema
+	if err := decoder.Decode(&schema); err != nil {
+		return nil, fmt.Errorf("invalid schema: %s", err.Error())
+	}
+
+	if err := schema.Validate(); err != nil {
+		return nil, err
+	}
+	return &schema, nil
+}
+
+// Validate checks the schema itself and prepares its numeric bounds.
+func (s *Schema) Validate() error {
+	for name, rg := range map[string]*Range{"rows": s.Rows, "cols": s.Cols} {
+		if rg != nil && rg.Min != nil && rg.Max != nil && *rg.Min > *rg.Max {
+			return fmt.Errorf("invalid schema: %s min %d is greater than max %d", name, *rg.Min, *rg.Max)
+		}
+	}
+
+	if s.Default != nil {
+		if err := s.Default.prepare("default"); err != nil {
+			return err
+		}
+	}
+	for j := range s.Columns {
+		if err := s.Columns[j].prepare(fmt.Sprintf("column %d", j)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *ColumnSpec) prepare(name string) error {
+	switch c.Type {
+	case "", CellInteger, CellDecimal, CellText:
+	default:
+		return fmt.Errorf("invalid schema: %s has unknown type %q", name, c.Type)
+	}
+
+	var ok bool
+	if c.Min != "" {
+		if c.min, ok = new(big.Rat).SetString(c.Min.String()); !ok {
+			return fmt.Errorf("invalid schema: %s has invalid min %q", name, c.Min)
+		}
+	}
+	if c.Max != "" {
+		if c.max, ok = new(big.Rat).SetString(c.Max.String()); !ok {
+			return fmt.Errorf("invalid schema: %s has invalid max %q", name, c.Max)
+		}
+	}
+	if c.min != nil && c.max != nil && c.min.Cmp(c.max) > 0 {
+		return fmt.Errorf("invalid schema: %s min %s is greater than max %s", name, c.Min, c.Max)
+	}
+	return nil
+}
+
+// Check returns every cell and dimension of matrix that does not conform to
+// the schema. The schema must have been validated first.
+func (s *Schema) Check(matrix [][]string) []Issue {
+	issues := []Issue{}
+
+	if s.Rows != nil && !s.Rows.contains(len(matrix)) {
+		issues = append(issues, Issue{
+			Kind:    IssueDimension,
+			Row:     -1,
+			Col:     -1,
+			Message: fmt.Sprintf("matrix has %d rows, expected %s", len(matrix), s.Rows),
+		})
+	}
+
+	seen := map[int]map[string]int{}
+	for i, row := range matrix {
+		if s.Cols != nil && !s.Cols.contains(len(row)) {
+			issues = append(issues, Issue{
+				Kind:    IssueDimension,
+				Row:     i,
+				Col:     -1,
+				Message: fmt.Sprintf("row %d has %d columns, expected %s", i, len(row), s.Cols),
+			})
+		}
+
+		for j, val := range row {
+			spec := s.column(j)
+			if spec == nil {
+				continue
+			}
+
+			if issue, ok := spec.check(val, i, j); !ok {
+				issues = append(issues, issue)
+				continue
+			}
+
+			if spec.Unique {
+				if seen[j] == nil {
+					seen[j] = map[string]int{}
+				}
+				key := canonicalCell(val)
+				if first, ok := seen[j][key]; ok {
+					issues = append(issues, Issue{
+						Kind:    IssueDuplicate,
+						Row:     i,
+						Col:     j,
+						Message: fmt.Sprintf("value %q at position [%d,%d] duplicates row %d", val, i, j, first),
+					})
+					continue
+				}
+				seen[j][key] = i
+			}
+		}
+	}
+
+	return issues
+}
+
+func (s *Schema) column(j int) *ColumnSpec {
+	if j < len(s.Columns) {
+		return &s.Columns[j]
+	}
+	return s.Default
+}
+
+func (c *ColumnSpec) check(val string, i, j int) (Issue, bool) {
+	cellType := InferCellType(val)
+
+	switch c.Type {
+	case CellInteger:
+		if cellType != CellInteger {
+			return typeMismatch(val, i, j, c.Type), false
+		}
+	case CellDecimal:
+		if cellType != CellInteger && cellType != CellDecimal {
+			return typeMismatch(val, i, j, c.Type), false
+		}
+	}
+
+	if c.min == nil && c.max == nil {
+		return Issue{}, true
+	}
+
+	number, ok := new(big.Rat).SetString(val)
+	if !ok || (cellType != CellInteger && cellType != CellDecimal) {
+		return typeMismatch(val, i, j, "a number"), false
+	}
+	if (c.min != nil && number.Cmp(c.min) < 0) || (c.max != nil && number.Cmp(c.max) > 0) {
+		return Issue{
+			Kind:    IssueOutOfBounds,
+			Row:     i,
+			Col:     j,
+			Message: fmt.Sprintf("value %s at position [%d,%d] is outside [%s,%s]", val, i, j, c.Min, c.Max),
+		}, false
+	}
+	return Issue{}, true
+}
+
+func typeMismatch(val string, i, j int, expected string) Issue {
+	return Issue{
+		Kind:    IssueTypeMismatch,
+		Row:     i,
+		Col:     j,
+		Message: fmt.Sprintf("value %q at position [%d,%d] is not %s", val, i, j, expected),
+	}
+}
+
+// canonicalCell makes equal numbers with different spellings (007, +7) compare equal.
+func canonicalCell(val string) string {
+	if number, ok := new(big.Rat).SetString(val); ok && InferCellType(val) != CellText {
+		return number.RatString()
+	}
+	return val
+}
@@ -0,0 +1,112 @@
+package matrix
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseSchema(t *testing.T) {
+	tests := []struct {
+		name        string
+		spec        string
+		expectError bool
+	}{
+		{
+			name: "Exact dimensions",
+			spec: `{"rows": 2, "cols": 3}`,
+		},
+		{
+			name: "Ranges and column specs",
+			spec: `{"rows": {"min": 1, "max": 10}, "columns": [{"type": "integer", "min": 0, "max": 1.5, "unique": true}], "default": {"type": "text"}}`,
+		},
+		{
+			name:        "Unknown type",
+			spec:        `{"default": {"type": "boolean"}}`,
+			expectError: true,
+		},
+		{
+			name:        "Min greater than max",
+			spec:        `{"rows": {"min": 5, "max": 1}}`,
+			expectError: true,
+		},
+		{
+			name:        "Column min greater than max",
+			spec:        `{"columns": [{"min": 5, "max": 1}]}`,
+			expectError: true,
+		},
+		{
+			name:        "Unknown field",
+			spec:        `{"row": 2}`,
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseSchema([]byte(tt.spec))
+			if tt.expectError && err == nil {
+				t.Errorf("Expected error but got none")
+			}
+			if !tt.expectError && err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestSchemaCheck(t *testing.T) {
+	tests := []struct {
+		name          string
+		spec          string
+		input         [][]string
+		expectedKinds []string
+	}{
+		{
+			name:          "Conforming matrix",
+			spec:          `{"rows": 2, "cols": {"min": 1, "max": 2}, "default": {"type": "integer", "min": 0}}`,
+			input:         [][]string{{"1", "2"}, {"3", "4"}},
+			expectedKinds: []string{},
+		},
+		{
+			name:          "Wrong dimensions",
+			spec:          `{"rows": 3, "cols": 1}`,
+			input:         [][]string{{"1", "2"}, {"3", "4"}},
+			expectedKinds: []string{IssueDimension, IssueDimension, IssueDimension},
+		},
+		{
+			name:          "Type mismatches",
+			spec:          `{"columns": [{"type": "integer"}, {"type": "decimal"}, {"type": "text"}]}`,
+			input:         [][]string{{"1.5", "2.5", "x"}, {"1", "abc", "3"}},
+			expectedKinds: []string{IssueTypeMismatch, IssueTypeMismatch},
+		},
+		{
+			name:          "Bounds",
+			spec:          `{"default": {"min": -1, "max": 1.5}}`,
+			input:         [][]string{{"-2", "0", "1.5", "2"}},
+			expectedKinds: []string{IssueOutOfBounds, IssueOutOfBounds},
+		},
+		{
+			name:          "Uniqueness compares numbers, not spellings",
+			spec:          `{"columns": [{"unique": true}]}`,
+			input:         [][]string{{"7"}, {"007"}, {"8"}, {"+7"}},
+			expectedKinds: []string{IssueDuplicate, IssueDuplicate},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schema, err := ParseSchema([]byte(tt.spec))
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			kinds := []string{}
+			for _, issue := range schema.Check(tt.input) {
+				kinds = append(kinds, issue.Kind)
+			}
+			if !reflect.DeepEqual(kinds, tt.expectedKinds) {
+				t.Errorf("issue kinds = %v, want %v", kinds, tt.expectedKinds)
+			}
+		})
+	}
+}
@@ -0,0 +1,91 @@
+package matrix
+
+import (
+	"context"
+	"math/big"
+	"strings"
+)
+
+// Stats summarises the cells of an integer matrix. Min, Max and Mean are
+// empty for a matrix without cells.
+type Stats struct {
+	Rows  int    `json:"rows"`
+	Cols  int    `json:"cols"`
+	Count int    `json:"count"`
+	Sum   string `json:"sum"`
+	Min   string `json:"min,omitempty"`
+	Max   string `json:"max,omitempty"`
+	Mean  string `json:"mean,omitempty"`
+}
+
+// statsAccumulator computes Stats one cell at a time.
+type statsAccumulator struct {
+	count    int
+	sum      intSum
+	min, max *big.Int
+	scratch  big.Int
+}
+
+func (a *statsAccumulator) addInt64(cell int64) {
+	a.sum.addInt64(cell)
+	a.observe(a.scratch.SetInt64(cell))
+}
+
+func (a *statsAccumulator) addBig(cell *big.Int) {
+	a.sum.addBig(cell)
+	a.observe(cell)
+}
+
+func (a *statsAccumulator) observe(cell *big.Int) {
+	a.count++
+	if a.min == nil {
+		a.min, a.max = new(big.Int).Set(cell), new(big.Int).Set(cell)
+		return
+	}
+	if cell.Cmp(a.min) < 0 {
+		a.min.Set(cell)
+	}
+	if cell.Cmp(a.max) > 0 {
+		a.max.Set(cell)
+	}
+}
+
+func (a *statsAccumulator) result(rows, cols int) *Stats {
+	sum := a.sum.result()
+	stats := &Stats{Rows: rows, Cols: cols, Count: a.count, Sum: sum.Text(10)}
+	if a.count == 0 {
+		return stats
+	}
+
+	stats.Min = a.min.Text(10)
+	stats.Max = a.max.Text(10)
+	mean := new(big.Rat).SetFrac(sum, big.NewInt(int64(a.count))).FloatString(6)
+	stats.Mean = strings.TrimSuffix(strings.TrimRight(mean, "0"), ".")
+	return stats
+}
+
+// Stats returns the count, sum, minimum, maximum and mean of the cells.
+func (m *Matrix) Stats() *Stats {
+	var stats statsAccumulator
+	for _, cell := range m.small {
+		stats.addInt64(cell)
+	}
+	for k := range m.data {
+		stats.addBig(&m.data[k])
+	}
+	return stats.result(m.rows, m.cols)
+}
+
+func StatsMatrix(matrix [][]string) (*Stats, error) {
+	return StatsMatrixContext(context.Background(), matrix)
+}
+
+// StatsMatrixContext is StatsMatrix stopping with ctx's error once ctx is done.
+func StatsMatrixContext(ctx context.Context, matrix [][]string) (*Stats, error) {
+	m, err := ParseContext(ctx, matrix)
+	if err != nil {
+		return nil, err
+	}
+
+	return m.Stats(), nil
+}
@@ -0,0 +1,205 @@
+package matrix
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"strconv"
+)
+
+// The streaming variants below read the CSV input row by row and only keep a
+// running accumulator, so their memory use does not grow with the input. The
+// Limits methods of the same name bound the input as it is read, since a
+// streamed upload is never checked as a whole.
+
+// scanCSV calls fn with every cell of r in row-major order. Like ReadAll it
+// fails when a row has a different number of fields than the first one, and
+// it fails with a *LimitError when a row exceeds l.
+func scanCSV(r io.Reader, l Limits, fn func(i, j int, val string) error) (rows, cols int, err error) {
+	reader := csv.NewReader(r)
+	reader.ReuseRecord = true
+
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return rows, cols, nil
+		}
+		if err != nil {
+			return rows, cols, err
+		}
+
+		if rows == 0 {
+			cols = len(record)
+		}
+		if err := l.checkRecord(rows, record, (rows+1)*cols); err != nil {
+			return rows, cols, err
+		}
+		for j, val := range record {
+			if err := fn(rows, j, val); err != nil {
+				return rows, cols, err
+			}
+		}
+		rows++
+	}
+}
+
+// checkCell returns the error parseCell would for the cell at position
+// [i,j], without converting it: a base 10 integer is an optional sign
+// followed by digits.
+func checkCell(val string, i, j int) error {
+	digits := val
+	if len(digits) > 0 && (digits[0] == '+' || digits[0] == '-') {
+		digits = digits[1:]
+	}
+	valid := len(digits) > 0
+	for k := 0; k < len(digits) && valid; k++ {
+		valid = digits[k] >= '0' && digits[k] <= '9'
+	}
+	if !valid {
+		return fmt.Errorf("invalid number at position [%d,%d]", i, j)
+	}
+	return nil
+}
+
+// parseCell parses the cell at position [i,j] as an int64 when it fits and
+// as a big.Int otherwise, in which case the returned big.Int is non-nil.
+func parseCell(val string, i, j int) (int64, *big.Int, error) {
+	small, err := strconv.ParseInt(val, 10, 64)
+	if err == nil {
+		return small, nil, nil
+	}
+	if !errors.Is(err, strconv.ErrRange) {
+		return 0, nil, fmt.Errorf("invalid number at position [%d,%d]", i, j)
+	}
+
+	large, err := parseInteger(val, i, j)
+	return 0, large, err
+}
+
+// SumCSV is the streaming variant of SumMatrix.
+func SumCSV(r io.Reader) (string, error) {
+	return Limits{}.SumCSV(r)
+}
+
+// SumCSV is the streaming variant of SumMatrix, failing with a *LimitError
+// once the input exceeds l.
+func (l Limits) SumCSV(r io.Reader) (string, error) {
+	var sum intSum
+	_, _, err := scanCSV(r, l, func(i, j int, val string) error {
+		small, large, err := parseCell(val, i, j)
+		if err != nil {
+			return err
+		}
+		if large != nil {
+			sum.addBig(large)
+		} else {
+			sum.addInt64(small)
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return sum.result().Text(10), nil
+}
+
+// ProductCSV is the streaming variant of MultiplyMatrix. Like MultiplyMatrix
+// it returns 0 for an empty input.
+func ProductCSV(r io.Reader) (string, error) {
+	return Limits{}.ProductCSV(r)
+}
+
+// ProductCSV is the streaming variant of MultiplyMatrix, failing with a
+// *LimitError once the input exceeds l or the factors read so far could
+// make a product of more than l.MaxResultDigits digits.
+func (l Limits) ProductCSV(r io.Reader) (string, error) {
+	product := newIntProduct()
+	var estimate productDigits
+	rows, _, err := scanCSV(r, l, func(i, j int, val string) error {
+		if err := estimate.add(val, l); err != nil {
+			return err
+		}
+		small, large, err := parseCell(val, i, j)
+		if err != nil {
+			return err
+		}
+		if large != nil {
+			product.mulBig(large)
+		} else {
+			product.mulInt64(small)
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	if rows == 0 {
+		return "0", nil
+	}
+	return product.result().Text(10), nil
+}
+
+// FlattenCSV is the streaming variant of FlattenMatrix. Cells are written to
+// w as they were read as soon as they are validated, so on error w holds a
+// partial result.
+func FlattenCSV(r io.Reader, w io.Writer) error {
+	return Limits{}.FlattenCSV(r, w)
+}
+
+// FlattenCSV is the streaming variant of FlattenMatrix, failing with a
+// *LimitError once the input exceeds l.
+func (l Limits) FlattenCSV(r io.Reader, w io.Writer) error {
+	rows, _, err := scanCSV(r, l, func(i, j int, val string) error {
+		if err := checkCell(val, i, j); err != nil {
+			return err
+		}
+
+		if i > 0 || j > 0 {
+			if _, err := io.WriteString(w, ","); err != nil {
+				return err
+			}
+		}
+		_, err := io.WriteString(w, val)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	if rows > 0 {
+		_, err = io.WriteString(w, "\n")
+	}
+	return err
+}
+
+// StatsCSV is the streaming variant of StatsMatrix.
+func StatsCSV(r io.Reader) (*Stats, error) {
+	return Limits{}.StatsCSV(r)
+}
+
+// StatsCSV is the streaming variant of StatsMatrix, failing with a
+// *LimitError once the input exceeds l.
+func (l Limits) StatsCSV(r io.Reader) (*Stats, error) {
+	var stats statsAccumulator
+	rows, cols, err := scanCSV(r, l, func(i, j int, val string) error {
+		small, large, err := parseCell(val, i, j)
+		if err != nil {
+			return err
+		}
+		if large != nil {
+			stats.addBig(large)
+		} else {
+			stats.addInt64(small)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return stats.result(rows, cols), nil
+}
@@ -0,0 +1,160 @@
+package matrix
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestStreamingMatchesInMemory(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{name: "Empty matrix", input: ""},
+		{name: "1x1 matrix", input: "7\n"},
+		{name: "2x3 matrix", input: "1,2,3\n4,5,6\n"},
+		{name: "Negative numbers", input: "-1,-2\n-3,-4\n"},
+		{name: "Matrix with zero", input: "1,0\n3,4\n"},
+		{name: "Overflowing int64", input: "9223372036854775807,2\n99999999999999999999999,-1\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			records := parseRecords(t, tt.input)
+
+			expectedSum, _ := SumMatrix(records)
+			if got, err := SumCSV(strings.NewReader(tt.input)); err != nil || got != expectedSum {
+				t.Errorf("SumCSV() = %v, %v, want %v", got, err, expectedSum)
+			}
+
+			expectedProduct, _ := MultiplyMatrix(records)
+			if got, err := ProductCSV(strings.NewReader(tt.input)); err != nil || got != expectedProduct {
+				t.Errorf("ProductCSV() = %v, %v, want %v", got, err, expectedProduct)
+			}
+
+			expectedFlatten, _ := FlattenMatrix(records)
+			var flattened bytes.Buffer
+			if err := FlattenCSV(strings.NewReader(tt.input), &flattened); err != nil || flattened.String() != expectedFlatten {
+				t.Errorf("FlattenCSV() = %q, %v, want %q", flattened.String(), err, expectedFlatten)
+			}
+
+			expectedStats, _ := StatsMatrix(records)
+			if got, err := StatsCSV(strings.NewReader(tt.input)); err != nil || *got != *expectedStats {
+				t.Errorf("StatsCSV() = %+v, %v, want %+v", got, err, expectedStats)
+			}
+		})
+	}
+}
+
+func TestStreamingErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{name: "Invalid number", input: "1,2\n3,x\n", expected: "invalid number at position [1,1]"},
+		{name: "Ragged rows", input: "1,2\n3\n", expected: "record on line 2: wrong number of fields"},
+		{name: "CSV syntax error", input: "1,\"2\n", expected: "extraneous or missing \" in quoted-field"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			operations := map[string]func() error{
+				"SumCSV":     func() error { _, err := SumCSV(strings.NewReader(tt.input)); return err },
+				"ProductCSV": func() error { _, err := ProductCSV(strings.NewReader(tt.input)); return err },
+				"FlattenCSV": func() error { return FlattenCSV(strings.NewReader(tt.input), io.Discard) },
+				"StatsCSV":   func() error { _, err := StatsCSV(strings.NewReader(tt.input)); return err },
+			}
+
+			for name, operation := range operations {
+				if err := operation(); err == nil || !strings.Contains(err.Error(), tt.expected) {
+					t.Errorf("%s() error = %v, want %q", name, err, tt.expected)
+				}
+			}
+		})
+	}
+}
+
+func TestStatsMatrix(t *testing.T) {
+	stats, err := StatsMatrix([][]string{{"1", "-2"}, {"9", "4"}})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := Stats{Rows: 2, Cols: 2, Count: 4, Sum: "12", Min: "-2", Max: "9", Mean: "3"}
+	if *stats != expected {
+		t.Errorf("StatsMatrix() = %+v, want %+v", *stats, expected)
+	}
+
+	stats, _ = StatsMatrix([][]string{{"1", "2"}, {"2", "2"}, {"2", "2"}})
+	if stats.Mean != "1.833333" {
+		t.Errorf("Mean = %v, want 1.833333", stats.Mean)
+	}
+
+	stats, _ = StatsMatrix([][]string{})
+	if stats.Count != 0 || stats.Sum != "0" || stats.Min != "" || stats.Mean != "" {
+		t.Errorf("StatsMatrix(empty) = %+v", *stats)
+	}
+}
+
+// rowGenerator produces a large CSV file without holding it in memory.
+type rowGenerator struct {
+	rows    int
+	line    []byte
+	pending []byte
+}
+
+func newRowGenerator(rows, cols int) *rowGenerator {
+	line := strings.TrimSuffix(strings.Repeat("12,", cols), ",") + "\n"
+	return &rowGenerator{rows: rows, line: []byte(line)}
+}
+
+func (g *rowGenerator) Read(p []byte) (int, error) {
+	if len(g.pending) == 0 {
+		if g.rows == 0 {
+			return 0, io.EOF
+		}
+		g.rows--
+		g.pending = g.line
+	}
+	n := copy(p, g.pending)
+	g.pending = g.pending[n:]
+	return n, nil
+}
+
+func TestSumCSVLargeInput(t *testing.T) {
+	got, err := SumCSV(newRowGenerator(100000, 100))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if expected := fmt.Sprint(12 * 100000 * 100); got != expected {
+		t.Errorf("SumCSV() = %v, want %v", got, expected)
+	}
+}
+
+// Allocations per row stay constant and none of them outlive the row.
+func BenchmarkSumCSV(b *testing.B) {
+	for _, rows := range []int{1000, 10000} {
+		b.Run(fmt.Sprintf("%d rows", rows), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				_, _ = SumCSV(newRowGenerator(rows, 1000))
+			}
+		})
+	}
+}
+
+func parseRecords(t *testing.T, input string) [][]string {
+	t.Helper()
+
+	records := [][]string{}
+	for _, line := range strings.Split(strings.TrimSuffix(input, "\n"), "\n") {
+		if line != "" {
+			records = append(records, strings.Split(line, ","))
+		}
+	}
+	return records
+}
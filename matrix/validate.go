@@ -0,0 +1,173 @@
+package matrix
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"regexp"
+)
+
+// Cell types inferred by ValidateCSV, ordered from the most to the least specific.
+const (
+	CellEmpty   = "empty"
+	CellInteger = "integer"
+	CellDecimal = "decimal"
+	CellText    = "text"
+)
+
+// Issue kinds reported by ValidateCSV.
+const (
+	IssueCSVSyntax     = "csv_syntax"
+	IssueRaggedRow     = "ragged_row"
+	IssueInvalidNumber = "invalid_number"
+)
+
+// MaxIssues bounds the issues kept in a ValidationReport so validating a huge
+// file does not hold every bad cell in memory. IssueCount keeps counting.
+const MaxIssues = 1000
+
+var decimalPattern = regexp.MustCompile(`^[+-]?(\d+\.?\d*|\.\d+)([eE][+-]?\d+)?$`)
+
+// Issue describes a single problem found in an uploaded matrix. Row and Col
+// are zero based like the positions in the operation errors, Line is the
+// one based line of the CSV input, or 0 when unknown. Col is -1 when the issue
+// covers a whole row and Row is -1 when it covers the whole matrix.
+type Issue struct {
+	Kind    string `json:"kind"`
+	Row     int    `json:"row"`
+	Col     int    `json:"col"`
+	Line    int    `json:"line,omitempty"`
+	Message string `json:"message"`
+}
+
+// Summary describes the shape and the inferred cell types of a matrix.
+type Summary struct {
+	Rows        int            `json:"rows"`
+	Cols        int            `json:"cols"`
+	ColumnTypes []string       `json:"column_types"`
+	CellTypes   map[string]int `json:"cell_types"`
+}
+
+// ValidationReport is the result of ValidateCSV. Truncated is set when more
+// than MaxIssues issues were found.
+type ValidationReport struct {
+	Valid      bool    `json:"valid"`
+	Summary    Summary `json:"summary"`
+	Issues     []Issue `json:"issues"`
+	IssueCount int     `json:"issue_count"`
+	Truncated  bool    `json:"truncated,omitempty"`
+}
+
+func (report *ValidationReport) addIssue(issue Issue) {
+	report.IssueCount++
+	if len(report.Issues) >= MaxIssues {
+		report.Truncated = true
+		return
+	}
+	report.Issues = append(report.Issues, issue)
+}
+
+// ValidateCSV reads the whole CSV input and reports every problem instead of
+// stopping at the first one. It reads row by row, so it can validate inputs
+// larger than memory. Only errors from the underlying reader are returned as
+// error, CSV syntax errors become issues.
+func ValidateCSV(r io.Reader) (*ValidationReport, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.ReuseRecord = true
+
+	report := &ValidationReport{
+		Summary: Summary{CellTypes: map[string]int{}},
+		Issues:  []Issue{},
+	}
+
+	cols := -1
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			report.addIssue(Issue{
+				Kind:    IssueCSVSyntax,
+				Row:     report.Summary.Rows,
+				Col:     -1,
+				Line:    parseErr.Line,
+				Message: parseErr.Error(),
+			})
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		i := report.Summary.Rows
+		line, _ := reader.FieldPos(0)
+		report.Summary.Rows++
+
+		if cols == -1 {
+			cols = len(record)
+			report.Summary.Cols = cols
+		} else if len(record) != cols {
+			report.addIssue(Issue{
+				Kind:    IssueRaggedRow,
+				Row:     i,
+				Col:     -1,
+				Line:    line,
+				Message: fmt.Sprintf("row %d has %d fields, expected %d", i, len(record), cols),
+			})
+		}
+
+		for j, val := range record {
+			cellType := InferCellType(val)
+			report.Summary.CellTypes[cellType]++
+
+			if j < cols {
+				if j >= len(report.Summary.ColumnTypes) {
+					report.Summary.ColumnTypes = append(report.Summary.ColumnTypes, CellEmpty)
+				}
+				report.Summary.ColumnTypes[j] = widenCellType(report.Summary.ColumnTypes[j], cellType)
+			}
+
+			if cellType != CellInteger {
+				report.addIssue(Issue{
+					Kind:    IssueInvalidNumber,
+					Row:     i,
+					Col:     j,
+					Line:    line,
+					Message: fmt.Sprintf("invalid number at position [%d,%d]: %q is %s", i, j, val, cellType),
+				})
+			}
+		}
+	}
+
+	report.Valid = report.IssueCount == 0
+	return report, nil
+}
+
+// InferCellType returns the most specific cell type val can be parsed as.
+func InferCellType(val string) string {
+	if val == "" {
+		return CellEmpty
+	}
+	if _, ok := new(big.Int).SetString(val, 10); ok {
+		return CellInteger
+	}
+	if decimalPattern.MatchString(val) {
+		return CellDecimal
+	}
+	return CellText
+}
+
+// widenCellType returns the least specific of the two cell types, ignoring empty cells.
+func widenCellType(current, next string) string {
+	rank := map[string]int{CellEmpty: 0, CellInteger: 1, CellDecimal: 2, CellText: 3}
+	if rank[next] > rank[current] {
+		return next
+	}
+	return current
+}
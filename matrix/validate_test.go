@@ -0,0 +1,139 @@
+package matrix
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestValidateCSV(t *testing.T) {
+	tests := []struct {
+		name           string
+		input          string
+		expectedValid  bool
+		expectedKinds  []string
+		expectedRows   int
+		expectedCols   int
+		expectedTypes  []string
+		expectedIssues []Issue
+	}{
+		{
+			name:          "Valid matrix",
+			input:         "1,2,3\n4,5,6\n",
+			expectedValid: true,
+			expectedKinds: []string{},
+			expectedRows:  2,
+			expectedCols:  3,
+			expectedTypes: []string{CellInteger, CellInteger, CellInteger},
+		},
+		{
+			name:          "Empty input",
+			input:         "",
+			expectedValid: true,
+			expectedKinds: []string{},
+			expectedRows:  0,
+			expectedCols:  0,
+		},
+		{
+			name:          "Every invalid cell is reported",
+			input:         "1,a,3\n4,5,1.5\n",
+			expectedValid: false,
+			expectedKinds: []string{IssueInvalidNumber, IssueInvalidNumber},
+			expectedRows:  2,
+			expectedCols:  3,
+			expectedTypes: []string{CellInteger, CellText, CellDecimal},
+			expectedIssues: []Issue{
+				{Kind: IssueInvalidNumber, Row: 0, Col: 1, Line: 1, Message: `invalid number at position [0,1]: "a" is text`},
+				{Kind: IssueInvalidNumber, Row: 1, Col: 2, Line: 2, Message: `invalid number at position [1,2]: "1.5" is decimal`},
+			},
+		},
+		{
+			name:          "Ragged rows",
+			input:         "1,2\n3\n4,5,6\n",
+			expectedValid: false,
+			expectedKinds: []string{IssueRaggedRow, IssueRaggedRow},
+			expectedRows:  3,
+			expectedCols:  2,
+			expectedTypes: []string{CellInteger, CellInteger},
+		},
+		{
+			name:          "CSV syntax error does not stop validation",
+			input:         "1,2\n3,\"4\"x\n5,b\n",
+			expectedValid: false,
+			expectedKinds: []string{IssueCSVSyntax, IssueInvalidNumber},
+			expectedRows:  2,
+			expectedCols:  2,
+			expectedTypes: []string{CellInteger, CellText},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report, err := ValidateCSV(strings.NewReader(tt.input))
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			if report.Valid != tt.expectedValid {
+				t.Errorf("Valid = %v, want %v", report.Valid, tt.expectedValid)
+			}
+
+			kinds := []string{}
+			for _, issue := range report.Issues {
+				kinds = append(kinds, issue.Kind)
+			}
+			if !reflect.DeepEqual(kinds, tt.expectedKinds) {
+				t.Errorf("issue kinds = %v, want %v", kinds, tt.expectedKinds)
+			}
+
+			if report.Summary.Rows != tt.expectedRows || report.Summary.Cols != tt.expectedCols {
+				t.Errorf("shape = %dx%d, want %dx%d", report.Summary.Rows, report.Summary.Cols, tt.expectedRows, tt.expectedCols)
+			}
+
+			if !reflect.DeepEqual(report.Summary.ColumnTypes, tt.expectedTypes) {
+				t.Errorf("column types = %v, want %v", report.Summary.ColumnTypes, tt.expectedTypes)
+			}
+
+			if tt.expectedIssues != nil && !reflect.DeepEqual(report.Issues, tt.expectedIssues) {
+				t.Errorf("issues = %+v, want %+v", report.Issues, tt.expectedIssues)
+			}
+		})
+	}
+}
+
+func TestInferCellType(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"", CellEmpty},
+		{"42", CellInteger},
+		{"-42", CellInteger},
+		{"99999999999999999999999", CellInteger},
+		{"1.5", CellDecimal},
+		{"-.5e3", CellDecimal},
+		{"abc", CellText},
+		{" 1 ", CellText},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			if got := InferCellType(tt.input); got != tt.expected {
+				t.Errorf("InferCellType(%q) = %v, want %v", tt.input, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestValidateCSVTruncatesIssues(t *testing.T) {
+	input := strings.Repeat("x\n", MaxIssues+5)
+
+	report, err := ValidateCSV(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if len(report.Issues) != MaxIssues || report.IssueCount != MaxIssues+5 || !report.Truncated {
+		t.Errorf("got %d issues, count %d, truncated %v", len(report.Issues), report.IssueCount, report.Truncated)
+	}
+}
@@ -0,0 +1,178 @@
+package matrix
+
+import (
+	"io"
+	"math/big"
+	"strconv"
+)
+
+// transposeBlock is the side of the square tiles Transpose copies at a time,
+// small enough that a source and a destination tile stay in cache together.
+const transposeBlock = 64
+
+// View is read-only indexed access to a matrix, either a Matrix itself or a
+// lazy view of one such as Transposed.
+type View interface {
+	Rows() int
+	Cols() int
+	At(i, j int) *big.Int
+	// AppendCell appends the base 10 text of the cell at [i,j] to dst.
+	AppendCell(dst []byte, i, j int) []byte
+}
+
+// AppendCell appends the base 10 text of the cell at [i,j] to dst.
+func (m *Matrix) AppendCell(dst []byte, i, j int) []byte {
+	if m.small != nil {
+		return strconv.AppendInt(dst, m.small[i*m.cols+j], 10)
+	}
+	return m.data[i*m.cols+j].Append(dst, 10)
+}
+
+// T returns the transpose of m as a view that shares m's cells.
+func (m *Matrix) T() Transposed {
+	return Transposed{m: m}
+}
+
+// Transposed is a view of a matrix with rows and columns swapped. It does not
+// copy any cells; use Materialize when a standalone copy is needed.
+type Transposed struct {
+	m *Matrix
+}
+
+func (t Transposed) Rows() int {
+	return t.m.cols
+}
+
+func (t Transposed) Cols() int {
+	return t.m.rows
+}
+
+func (t Transposed) At(i, j int) *big.Int {
+	return t.m.At(j, i)
+}
+
+func (t Transposed) AppendCell(dst []byte, i, j int) []byte {
+	return t.m.AppendCell(dst, j, i)
+}
+
+// T returns the original matrix.
+func (t Transposed) T() *Matrix {
+	return t.m
+}
+
+// Materialize copies the view into a new matrix.
+func (t Transposed) Materialize() *Matrix {
+	return t.m.Transpose()
+}
+
+// Cells is a view of CSV records answering their cells as they were written,
+// such as 004 or +5 where a Matrix answers 4 and 5. Transposing or
+// flattening records keeps their text, so they are validated with Parse and
+// then written through Cells. The records must be valid.
+type Cells [][]string
+
+func (c Cells) Rows() int {
+	return len(c)
+}
+
+func (c Cells) Cols() int {
+	if len(c) == 0 {
+		return 0
+	}
+	return len(c[0])
+}
+
+func (c Cells) At(i, j int) *big.Int {
+	cell, _ := new(big.Int).SetString(c[i][j], 10)
+	return cell
+}
+
+func (c Cells) AppendCell(dst []byte, i, j int) []byte {
+	return append(dst, c[i][j]...)
+}
+
+// T returns the transpose of c as a view that shares c's cells.
+func (c Cells) T() View {
+	return transposedCells{c: c}
+}
+
+type transposedCells struct {
+	c Cells
+}
+
+func (t transposedCells) Rows() int {
+	return t.c.Cols()
+}
+
+func (t transposedCells) Cols() int {
+	return t.c.Rows()
+}
+
+func (t transposedCells) At(i, j int) *big.Int {
+	return t.c.At(j, i)
+}
+
+func (t transposedCells) AppendCell(dst []byte, i, j int) []byte {
+	return t.c.AppendCell(dst, j, i)
+}
+
+// WriteCSV writes the view to w one CSV line per row without building the
+// intermediate records.
+func WriteCSV(w io.Writer, v View) error {
+	var line []byte
+	for i := 0; i < v.Rows(); i++ {
+		line = line[:0]
+		for j := 0; j < v.Cols(); j++ {
+			if j > 0 {
+				line = append(line, ',')
+			}
+			line = v.AppendCell(line, i, j)
+		}
+		line = append(line, '\n')
+
+		if _, err := w.Write(line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteFlat writes every cell of the view in row-major order as a single CSV
+// line, like FlattenMatrix. Flattening m.T() gives the column-major order of m.
+func WriteFlat(w io.Writer, v View) error {
+	if v.Rows() == 0 {
+		return nil
+	}
+
+	var cell []byte
+	for i := 0; i < v.Rows(); i++ {
+		for j := 0; j < v.Cols(); j++ {
+			cell = cell[:0]
+			if i > 0 || j > 0 {
+				cell = append(cell, ',')
+			}
+			cell = v.AppendCell(cell, i, j)
+
+			if _, err := w.Write(cell); err != nil {
+				return err
+			}
+		}
+	}
+
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// Records formats the view into CSV records.
+func Records(v View) [][]string {
+	records := make([][]string, v.Rows())
+	var cell []byte
+	for i := range records {
+		records[i] = make([]string, v.Cols())
+		for j := range records[i] {
+			cell = v.AppendCell(cell[:0], i, j)
+			records[i][j] = string(cell)
+		}
+	}
+	return records
+}
@@ -0,0 +1,155 @@
+package matrix
+
+import (
+	"bytes"
+	"io"
+	"math/big"
+	"reflect"
+	"testing"
+)
+
+func TestTransposedView(t *testing.T) {
+	m, err := Parse([][]string{{"1", "2", "3"}, {"4", "5", "99999999999999999999"}})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	view := m.T()
+	if view.Rows() != 3 || view.Cols() != 2 {
+		t.Errorf("shape = %dx%d, want 3x2", view.Rows(), view.Cols())
+	}
+	if got := view.At(2, 1).Text(10); got != "99999999999999999999" {
+		t.Errorf("At(2, 1) = %v", got)
+	}
+	if view.T() != m {
+		t.Errorf("T().T() should return the original matrix")
+	}
+
+	// The view shares the cells of the matrix.
+	m.Set(0, 1, big.NewInt(7))
+	if got := view.At(1, 0).Text(10); got != "7" {
+		t.Errorf("view did not see Set(), At(1, 0) = %v", got)
+	}
+
+	if !reflect.DeepEqual(Records(view), view.Materialize().Records()) {
+		t.Errorf("Records(view) = %v, want %v", Records(view), view.Materialize().Records())
+	}
+}
+
+func TestBlockedTranspose(t *testing.T) {
+	for _, promoted := range []bool{false, true} {
+		records := largeRecords(transposeBlock*2+3, transposeBlock+5)
+		if promoted {
+			records[1][2] = "99999999999999999999"
+		}
+
+		m, err := Parse(records)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		transposed := m.Transpose()
+		for i := 0; i < m.Rows(); i++ {
+			for j := 0; j < m.Cols(); j++ {
+				if transposed.At(j, i).Cmp(m.At(i, j)) != 0 {
+					t.Fatalf("Transpose()[%d][%d] = %v, want %v", j, i, transposed.At(j, i), m.At(i, j))
+				}
+			}
+		}
+	}
+}
+
+func TestWriteViews(t *testing.T) {
+	tests := []struct {
+		name         string
+		input        [][]string
+		expectedCSV  string
+		expectedFlat string
+		expectedCol  string
+	}{
+		{
+			name:         "Empty matrix",
+			input:        [][]string{},
+			expectedCSV:  "",
+			expectedFlat: "",
+			expectedCol:  "",
+		},
+		{
+			name:         "2x3 matrix",
+			input:        [][]string{{"1", "2", "3"}, {"4", "5", "6"}},
+			expectedCSV:  "1,2,3\n4,5,6\n",
+			expectedFlat: "1,2,3,4,5,6\n",
+			expectedCol:  "1,4,2,5,3,6\n",
+		},
+		{
+			name:         "Matrix with empty rows",
+			input:        [][]string{{}, {}},
+			expectedCSV:  "\n\n",
+			expectedFlat: "\n",
+			expectedCol:  "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := Parse(tt.input)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			var out bytes.Buffer
+			WriteCSV(&out, m)
+			if out.String() != tt.expectedCSV {
+				t.Errorf("WriteCSV() = %q, want %q", out.String(), tt.expectedCSV)
+			}
+
+			out.Reset()
+			WriteFlat(&out, m)
+			if expected, _ := FlattenMatrix(tt.input); out.String() != expected || out.String() != tt.expectedFlat {
+				t.Errorf("WriteFlat() = %q, want %q", out.String(), tt.expectedFlat)
+			}
+
+			out.Reset()
+			WriteFlat(&out, m.T())
+			if out.String() != tt.expectedCol {
+				t.Errorf("WriteFlat(T()) = %q, want %q", out.String(), tt.expectedCol)
+			}
+		})
+	}
+}
+
+// Compares flattening in column-major order through InvertMatrix, which
+// builds the transposed [][]string, with flattening the transposed view.
+func BenchmarkColumnMajorFlatten(b *testing.B) {
+	records := largeRecords(1000, 1000)
+	m, _ := Parse(records)
+
+	b.Run("InvertMatrix then FlattenMatrix", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			inverted, _ := InvertMatrix(records)
+			_, _ = FlattenMatrix(inverted)
+		}
+	})
+	b.Run("WriteFlat of T()", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			_ = WriteFlat(io.Discard, m.T())
+		}
+	})
+}
+
+func BenchmarkTranspose(b *testing.B) {
+	m, _ := Parse(largeRecords(2000, 2000))
+
+	b.Run("blocked", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			m.Transpose()
+		}
+	})
+	b.Run("view", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			m.T()
+		}
+	})
+}
@@ -0,0 +1,143 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Filesystem is a Store keeping every matrix in a directory, as the file
+// <id>.csv next to its description <id>.json. The matrices outlive the
+// process.
+type Filesystem struct {
+	dir string
+}
+
+// NewFilesystem returns a Filesystem store in dir, created if needed.
+func NewFilesystem(dir string) (*Filesystem, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &Filesystem{dir: dir}, nil
+}
+
+func (f *Filesystem) path(id, ext string) string {
+	return filepath.Join(f.dir, id+ext)
+}
+
+// Put writes the matrix to a temporary file first, so that a failed upload
+// leaves nothing behind. The description is written last, a matrix without
+// one is not listed.
+func (f *Filesystem) Put(ctx context.Context, name string, r io.Reader) (Info, error) {
+	info := Info{ID: newID(), Name: name, Created: time.Now()}
+
+	tmp, err := os.CreateTemp(f.dir, ".upload-*")
+	if err != nil {
+		return Info{}, err
+	}
+	defer os.Remove(tmp.Name())
+
+	info.Size, err = io.Copy(tmp, contextReader{ctx: ctx, r: r})
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return Info{}, err
+	}
+	if err := os.Rename(tmp.Name(), f.path(info.ID, ".csv")); err != nil {
+		return Info{}, err
+	}
+
+	description, err := json.Marshal(info)
+	if err == nil {
+		err = os.WriteFile(f.path(info.ID, ".json"), description, 0o644)
+	}
+	if err != nil {
+		os.Remove(f.path(info.ID, ".csv"))
+		return Info{}, err
+	}
+	return info, nil
+}
+
+func (f *Filesystem) Open(ctx context.Context, id string) (io.ReadCloser, Info, error) {
+	info, err := f.info(id)
+	if err != nil {
+		return nil, Info{}, err
+	}
+	file, err := os.Open(f.path(id, ".csv"))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, Info{}, ErrNotFound
+	}
+	if err != nil {
+		return nil, Info{}, err
+	}
+	return file, info, nil
+}
+
+// Delete removes the description first, so a matrix is never listed
+// without its content.
+func (f *Filesystem) Delete(ctx context.Context, id string) error {
+	if !validID(id) {
+		return ErrNotFound
+	}
+	err := os.Remove(f.path(id, ".json"))
+	if errors.Is(err, fs.ErrNotExist) {
+		return ErrNotFound
+	}
+	if err != nil {
+		return err
+	}
+	return os.Remove(f.path(id, ".csv"))
+}
+
+func (f *Filesystem) List(ctx context.Context) ([]Info, error) {
+	entries, err := os.ReadDir(f.dir)
+	if err != nil {
+		return nil, err
+	}
+
+	infos := []Info{}
+	for _, entry := range entries {
+		id, ok := strings.CutSuffix(entry.Name(), ".json")
+		if !ok || !validID(id) {
+			continue
+		}
+		info, err := f.info(id)
+		if errors.Is(err, ErrNotFound) {
+			// Deleted meanwhile.
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		infos = append(infos, info)
+	}
+
+	sortInfos(infos)
+	return infos, nil
+}
+
+func (f *Filesystem) info(id string) (Info, error) {
+	if !validID(id) {
+		return Info{}, ErrNotFound
+	}
+	description, err := os.ReadFile(f.path(id, ".json"))
+	if errors.Is(err, fs.ErrNotExist) {
+		return Info{}, ErrNotFound
+	}
+	if err != nil {
+		return Info{}, err
+	}
+
+	var info Info
+	if err := json.Unmarshal(description, &info); err != nil {
+		return Info{}, err
+	}
+	return info, nil
+}
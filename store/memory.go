@@ -0,0 +1,83 @@
+package store
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Memory is a Store keeping the matrices in memory, they are lost when the
+// process exits.
+type Memory struct {
+	mu       sync.RWMutex
+	matrices map[string]memoryMatrix
+}
+
+type memoryMatrix struct {
+	info Info
+	data []byte
+}
+
+// NewMemory returns an empty Memory store.
+func NewMemory() *Memory {
+	return &Memory{matrices: map[string]memoryMatrix{}}
+}
+
+func (m *Memory) Put(ctx context.Context, name string, r io.Reader) (Info, error) {
+	data, err := io.ReadAll(contextReader{ctx: ctx, r: r})
+	if err != nil {
+		return Info{}, err
+	}
+
+	info := Info{ID: newID(), Name: name, Size: int64(len(data)), Created: time.Now()}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.matrices[info.ID] = memoryMatrix{info: info, data: data}
+	return info, nil
+}
+
+func (m *Memory) Open(ctx context.Context, id string) (io.ReadCloser, Info, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	matrix, ok := m.matrices[id]
+	if !ok {
+		return nil, Info{}, ErrNotFound
+	}
+	return io.NopCloser(bytes.NewReader(matrix.data)), matrix.info, nil
+}
+
+func (m *Memory) Delete(ctx context.Context, id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.matrices[id]; !ok {
+		return ErrNotFound
+	}
+	delete(m.matrices, id)
+	return nil
+}
+
+func (m *Memory) List(ctx context.Context) ([]Info, error) {
+	m.mu.RLock()
+	infos := make([]Info, 0, len(m.matrices))
+	for _, matrix := range m.matrices {
+		infos = append(infos, matrix.info)
+	}
+	m.mu.RUnlock()
+
+	sortInfos(infos)
+	return infos, nil
+}
+
+// sortInfos orders the matrices by creation, then by ID.
+func sortInfos(infos []Info) {
+	slices.SortFunc(infos, func(a, b Info) int {
+		if c := a.Created.Compare(b.Created); c != 0 {
+			return c
+		}
+		return strings.Compare(a.ID, b.ID)
+	})
+}
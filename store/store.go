@@ -0,0 +1,68 @@
+// Package store keeps matrices uploaded once on the server, so that any
+// number of operations can then reference them by ID.
+package store
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"io"
+	"time"
+)
+
+// ErrNotFound is returned for an ID that names no stored matrix.
+var ErrNotFound = errors.New("matrix not found")
+
+// Info describes a stored matrix.
+type Info struct {
+	ID string `json:"id"`
+	// Name is the file name the matrix was uploaded with, if any.
+	Name    string    `json:"name,omitempty"`
+	Size    int64     `json:"size"`
+	Created time.Time `json:"created"`
+}
+
+// Store keeps matrices as the CSV they were uploaded as. A stored matrix
+// never changes, a new upload gets a new ID. Implementations are safe for
+// concurrent use.
+type Store interface {
+	// Put stores everything read from r under a new ID.
+	Put(ctx context.Context, name string, r io.Reader) (Info, error)
+	// Open returns the content of a stored matrix, which the caller closes.
+	Open(ctx context.Context, id string) (io.ReadCloser, Info, error)
+	Delete(ctx context.Context, id string) error
+	// List returns the stored matrices, the oldest first.
+	List(ctx context.Context) ([]Info, error)
+}
+
+// newID returns a random ID of 32 hex digits.
+func newID() string {
+	id := make([]byte, 16)
+	rand.Read(id)
+	return hex.EncodeToString(id)
+}
+
+// validID reports whether id could have been returned by newID, so that IDs
+// from clients are safe to use as file names.
+func validID(id string) bool {
+	if len(id) != 32 {
+		return false
+	}
+	_, err := hex.DecodeString(id)
+	return err == nil
+}
+
+// contextReader fails with the context's error once the context is done, so
+// uploads stop when the client goes away.
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (cr contextReader) Read(p []byte) (int, error) {
+	if err := cr.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return cr.r.Read(p)
+}
@@ -0,0 +1,107 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestStores(t *testing.T) {
+	stores := map[string]func(t *testing.T) Store{
+		"Memory": func(t *testing.T) Store { return NewMemory() },
+		"Filesystem": func(t *testing.T) Store {
+			fs, err := NewFilesystem(filepath.Join(t.TempDir(), "matrices"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			return fs
+		},
+	}
+
+	for name, newStore := range stores {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			s := newStore(t)
+
+			first, err := s.Put(ctx, "a.csv", strings.NewReader("1,2\n3,4\n"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			second, err := s.Put(ctx, "", strings.NewReader("5\n"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if first.ID == second.ID || !validID(first.ID) || first.Name != "a.csv" || first.Size != 8 {
+				t.Errorf("Put() = %+v, %+v", first, second)
+			}
+
+			file, info, err := s.Open(ctx, first.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			content, _ := io.ReadAll(file)
+			file.Close()
+			if string(content) != "1,2\n3,4\n" || info.ID != first.ID || info.Size != first.Size {
+				t.Errorf("Open() = %q, %+v", content, info)
+			}
+
+			infos, err := s.List(ctx)
+			if err != nil || len(infos) != 2 || infos[0].ID != first.ID || infos[1].ID != second.ID {
+				t.Errorf("List() = %+v, %v, want %s then %s", infos, err, first.ID, second.ID)
+			}
+
+			if err := s.Delete(ctx, first.ID); err != nil {
+				t.Fatal(err)
+			}
+			for _, id := range []string{first.ID, "nope", "../../etc/passwd"} {
+				if _, _, err := s.Open(ctx, id); !errors.Is(err, ErrNotFound) {
+					t.Errorf("Open(%q) error = %v, want %v", id, err, ErrNotFound)
+				}
+				if err := s.Delete(ctx, id); !errors.Is(err, ErrNotFound) {
+					t.Errorf("Delete(%q) error = %v, want %v", id, err, ErrNotFound)
+				}
+			}
+			if infos, _ := s.List(ctx); len(infos) != 1 || infos[0].ID != second.ID {
+				t.Errorf("List() after Delete = %+v, want only %s", infos, second.ID)
+			}
+		})
+	}
+}
+
+func TestPutCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	dir := t.TempDir()
+	fs, _ := NewFilesystem(dir)
+	for name, s := range map[string]Store{"Memory": NewMemory(), "Filesystem": fs} {
+		if _, err := s.Put(ctx, "", strings.NewReader("1\n")); !errors.Is(err, context.Canceled) {
+			t.Errorf("%s Put() error = %v, want %v", name, err, context.Canceled)
+		}
+		if infos, _ := s.List(context.Background()); len(infos) != 0 {
+			t.Errorf("%s kept a cancelled upload: %+v", name, infos)
+		}
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Errorf("cancelled upload left %d files", len(entries))
+	}
+}
+
+func TestFilesystemPersists(t *testing.T) {
+	dir := t.TempDir()
+	fs, _ := NewFilesystem(dir)
+	info, err := fs.Put(context.Background(), "", strings.NewReader("1\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reopened, _ := NewFilesystem(dir)
+	infos, _ := reopened.List(context.Background())
+	if len(infos) != 1 || infos[0].ID != info.ID || infos[0].Size != info.Size || !infos[0].Created.Equal(info.Created) {
+		t.Errorf("List() after reopening = %+v, want [%+v]", infos, info)
+	}
+}